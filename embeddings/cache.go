@@ -0,0 +1,196 @@
+package embeddings
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// Cache is the interface of the caches of embeddings used by a
+// CachedEmbedder, e.g. the in-memory LRUCache or one backed by Redis.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get a vector from the cache. If the key is not found, return `nil`.
+	Get(ctx context.Context, key string) []float32
+	// Put a vector into the cache.
+	Put(ctx context.Context, key string, vector []float32)
+}
+
+// CachedEmbedder is an Embedder memoizing the embeddings of another Embedder:
+// the texts already embedded are served from the cache, and only the others
+// are passed to the inner embedder.
+type CachedEmbedder struct {
+	inner     Embedder
+	cache     Cache
+	namespace string
+}
+
+var _ Embedder = (*CachedEmbedder)(nil)
+
+// CacheOption is an option of NewCachedEmbedder.
+type CacheOption func(*CachedEmbedder)
+
+// WithCacheNamespace is an option for specifying the namespace of the cache
+// keys, e.g. the model of the inner embedder, for the embedders of different
+// models sharing a cache.
+func WithCacheNamespace(namespace string) CacheOption {
+	return func(e *CachedEmbedder) {
+		e.namespace = namespace
+	}
+}
+
+// NewCachedEmbedder wraps an Embedder and memoizes its embeddings in the
+// cache, keyed by the namespace set with WithCacheNamespace and the text.
+func NewCachedEmbedder(inner Embedder, cache Cache, opts ...CacheOption) *CachedEmbedder {
+	e := &CachedEmbedder{
+		inner: inner,
+		cache: cache,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// EmbedQuery embeds a single text, or returns its cached embedding.
+func (e *CachedEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	key := e.key("query", text)
+	if vector := e.cache.Get(ctx, key); vector != nil {
+		return copyVector(vector), nil
+	}
+
+	vector, err := e.inner.EmbedQuery(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	e.cache.Put(ctx, key, copyVector(vector))
+	return vector, nil
+}
+
+// EmbedDocuments returns a vector for each text, embedding only the texts
+// whose embedding isn't cached.
+func (e *CachedEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	// missing maps the texts to embed to their indices in texts, each text
+	// being embedded once.
+	missing := map[string][]int{}
+	var missingTexts []string
+	for i, text := range texts {
+		if vector := e.cache.Get(ctx, e.key("document", text)); vector != nil {
+			vectors[i] = copyVector(vector)
+			continue
+		}
+		if _, ok := missing[text]; !ok {
+			missingTexts = append(missingTexts, text)
+		}
+		missing[text] = append(missing[text], i)
+	}
+	if len(missingTexts) == 0 {
+		return vectors, nil
+	}
+
+	embedded, err := e.inner.EmbedDocuments(ctx, missingTexts)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedded) != len(missingTexts) {
+		return nil, fmt.Errorf("embedder returned %d vectors for %d texts", len(embedded), len(missingTexts))
+	}
+	for i, text := range missingTexts {
+		e.cache.Put(ctx, e.key("document", text), copyVector(embedded[i]))
+		for j, idx := range missing[text] {
+			if j == 0 {
+				vectors[idx] = embedded[i]
+				continue
+			}
+			vectors[idx] = copyVector(embedded[i])
+		}
+	}
+	return vectors, nil
+}
+
+// key returns the cache key of the embedding of a text, the query and
+// document embeddings of some models being different.
+func (e *CachedEmbedder) key(kind, text string) string {
+	hash := sha256.New()
+	for _, part := range []string{e.namespace, kind, text} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// copyVector returns a copy of a vector, so that the callers can't modify the
+// cached vectors.
+func copyVector(vector []float32) []float32 {
+	return append([]float32(nil), vector...)
+}
+
+// LRUCache is an in-memory Cache holding a bounded number of embeddings,
+// evicting the least recently used ones. It is safe for concurrent use.
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+var _ Cache = (*LRUCache)(nil)
+
+// lruEntry is an element of the order of an LRUCache.
+type lruEntry struct {
+	key    string
+	vector []float32
+}
+
+// NewLRUCache returns an LRUCache holding at most capacity embeddings, or an
+// unbounded number of them if capacity isn't positive.
+func NewLRUCache(capacity int) *LRUCache {
+	return &LRUCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+	}
+}
+
+// Get a vector from the cache. If the key is not found, return `nil`.
+func (c *LRUCache) Get(_ context.Context, key string) []float32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).vector //nolint:forcetypeassert
+}
+
+// Put a vector into the cache, evicting the least recently used one if the
+// cache is full.
+func (c *LRUCache) Put(_ context.Context, key string, vector []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*lruEntry).vector = vector //nolint:forcetypeassert
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, vector: vector})
+	if c.capacity > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key) //nolint:forcetypeassert
+	}
+}
+
+// Len returns the number of embeddings in the cache.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
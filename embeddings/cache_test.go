@@ -0,0 +1,118 @@
+package embeddings
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingEmbedder embeds every text as a vector of its length, counting the
+// texts it embeds.
+type countingEmbedder struct {
+	queries   atomic.Int32
+	documents atomic.Int32
+}
+
+func (e *countingEmbedder) EmbedDocuments(_ context.Context, texts []string) ([][]float32, error) {
+	e.documents.Add(int32(len(texts)))
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text))}
+	}
+	return vectors, nil
+}
+
+func (e *countingEmbedder) EmbedQuery(_ context.Context, text string) ([]float32, error) {
+	e.queries.Add(1)
+	return []float32{float32(len(text))}, nil
+}
+
+func TestCachedEmbedder(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	inner := &countingEmbedder{}
+	e := NewCachedEmbedder(inner, NewLRUCache(10))
+
+	for i := 0; i < 2; i++ {
+		vector, err := e.EmbedQuery(ctx, "tokyo")
+		require.NoError(t, err)
+		assert.Equal(t, []float32{5}, vector)
+		vector[0] = 0 // must not modify the cached vector.
+	}
+	assert.Equal(t, int32(1), inner.queries.Load())
+
+	vectors, err := e.EmbedDocuments(ctx, []string{"tokyo", "kyoto", "tokyo"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{5}, {5}, {5}}, vectors)
+	// The query embedding isn't reused for documents, and a text is
+	// embedded once.
+	assert.Equal(t, int32(2), inner.documents.Load())
+
+	vectors, err = e.EmbedDocuments(ctx, []string{"kyoto", "osaka", "tokyo"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{5}, {5}, {5}}, vectors)
+	assert.Equal(t, int32(3), inner.documents.Load())
+}
+
+func TestCachedEmbedderNamespace(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cache := NewLRUCache(0)
+	inner := &countingEmbedder{}
+	a := NewCachedEmbedder(inner, cache, WithCacheNamespace("model-a"))
+	b := NewCachedEmbedder(inner, cache, WithCacheNamespace("model-b"))
+
+	_, err := a.EmbedQuery(ctx, "tokyo")
+	require.NoError(t, err)
+	_, err = b.EmbedQuery(ctx, "tokyo")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), inner.queries.Load())
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestLRUCache(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cache := NewLRUCache(2)
+	assert.Nil(t, cache.Get(ctx, "a"))
+
+	cache.Put(ctx, "a", []float32{1})
+	cache.Put(ctx, "b", []float32{2})
+	assert.Equal(t, []float32{1}, cache.Get(ctx, "a"))
+	cache.Put(ctx, "c", []float32{3})
+
+	assert.Nil(t, cache.Get(ctx, "b"), "least recently used should have been evicted")
+	assert.Equal(t, []float32{1}, cache.Get(ctx, "a"))
+	assert.Equal(t, []float32{3}, cache.Get(ctx, "c"))
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestCachedEmbedderConcurrency(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	e := NewCachedEmbedder(&countingEmbedder{}, NewLRUCache(5))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			text := strconv.Itoa(i % 8)
+			vector, err := e.EmbedQuery(ctx, text)
+			assert.NoError(t, err)
+			assert.Equal(t, []float32{1}, vector)
+			_, err = e.EmbedDocuments(ctx, []string{text, "tokyo"})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+}
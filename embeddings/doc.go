@@ -8,6 +8,8 @@ The main components of this package are:
     from texts, with optional batching.
   - [NewEmbedder] creates implementations of [Embedder] from provider LLM
     (or Chat) clients.
+  - [NewCachedEmbedder] memoizes the embeddings of an [Embedder] in a
+    [Cache], e.g. an [LRUCache].
 
 See the package example below.
 */
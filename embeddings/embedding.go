@@ -3,6 +3,7 @@ package embeddings
 import (
 	"context"
 	"strings"
+	"sync"
 
 	"github.com/tmc/langchaingo/internal/util"
 )
@@ -49,6 +50,11 @@ type EmbedderImpl struct {
 
 	StripNewLines bool
 	BatchSize     int
+	Normalize     bool
+	// Concurrency is the number of batches embedded at once by
+	// EmbedDocuments. Batches are embedded one after another if it is less
+	// than 2.
+	Concurrency int
 }
 
 // EmbedQuery embeds a single text.
@@ -62,13 +68,26 @@ func (ei *EmbedderImpl) EmbedQuery(ctx context.Context, text string) ([]float32,
 		return nil, err
 	}
 
+	if ei.Normalize {
+		normalize(emb[0])
+	}
 	return emb[0], nil
 }
 
 // EmbedDocuments creates one vector embedding for each of the texts.
 func (ei *EmbedderImpl) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
 	texts = MaybeRemoveNewLines(texts, ei.StripNewLines)
-	return BatchedEmbed(ctx, ei.client, texts, ei.BatchSize)
+	emb, err := ConcurrentBatchedEmbed(ctx, ei.client, texts, ei.BatchSize, ei.Concurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	if ei.Normalize {
+		for _, v := range emb {
+			normalize(v)
+		}
+	}
+	return emb, nil
 }
 
 func MaybeRemoveNewLines(texts []string, removeNewLines bool) []string {
@@ -110,3 +129,70 @@ func BatchedEmbed(ctx context.Context, embedder EmbedderClient, texts []string,
 
 	return emb, nil
 }
+
+// ConcurrentBatchedEmbed creates embeddings for the given input texts like
+// BatchedEmbed, with up to concurrency batches embedded at once. The
+// embeddings are returned in the order of the texts. The first error cancels
+// the batches in flight and is returned.
+func ConcurrentBatchedEmbed(
+	ctx context.Context,
+	embedder EmbedderClient,
+	texts []string,
+	batchSize int,
+	concurrency int,
+) ([][]float32, error) {
+	batchedTexts := BatchTexts(texts, batchSize)
+	if concurrency < 2 || len(batchedTexts) < 2 {
+		return BatchedEmbed(ctx, embedder, texts, batchSize)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+		sem      = make(chan struct{}, concurrency)
+		results  = make([][][]float32, len(batchedTexts))
+	)
+	for i, batch := range batchedTexts {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, batch []string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			emb, err := embedder.CreateEmbedding(ctx, batch)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = emb
+		}(i, batch)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	emb := make([][]float32, 0, len(texts))
+	for _, batchEmbeddings := range results {
+		emb = append(emb, batchEmbeddings...)
+	}
+	return emb, nil
+}
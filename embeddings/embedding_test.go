@@ -1,9 +1,15 @@
 package embeddings
 
 import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBatchTexts(t *testing.T) {
@@ -60,3 +66,98 @@ func TestBatchTexts(t *testing.T) {
 		assert.Equal(t, tc.expected, BatchTexts(tc.texts, tc.batchSize))
 	}
 }
+
+func TestEmbedderNormalization(t *testing.T) {
+	t.Parallel()
+
+	client := EmbedderClientFunc(func(_ context.Context, texts []string) ([][]float32, error) {
+		emb := make([][]float32, 0, len(texts))
+		for _, text := range texts {
+			emb = append(emb, []float32{float32(len(text)), 2, 0})
+		}
+		return emb, nil
+	})
+
+	e, err := NewEmbedder(client, WithNormalization(true))
+	require.NoError(t, err)
+
+	docs, err := e.EmbedDocuments(context.Background(), []string{"foo", "hello world"})
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	for _, v := range docs {
+		assert.InDelta(t, 1.0, getNorm(v), 1e-6)
+	}
+
+	query, err := e.EmbedQuery(context.Background(), "foo")
+	require.NoError(t, err)
+	assert.InDelta(t, 1.0, getNorm(query), 1e-6)
+	assert.InDeltaSlice(t, docs[0], query, 1e-6)
+
+	e, err = NewEmbedder(client)
+	require.NoError(t, err)
+	query, err = e.EmbedQuery(context.Background(), "foo")
+	require.NoError(t, err)
+	assert.Equal(t, []float32{3, 2, 0}, query)
+}
+
+func TestEmbedderConcurrency(t *testing.T) {
+	t.Parallel()
+
+	var inFlight, maxInFlight atomic.Int32
+	// Later batches return first: each batch waits less than the previous.
+	client := EmbedderClientFunc(func(_ context.Context, texts []string) ([][]float32, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		first, err := strconv.Atoi(texts[0])
+		if err != nil {
+			return nil, err
+		}
+		time.Sleep(time.Duration(10-first/2) * 5 * time.Millisecond)
+		emb := make([][]float32, 0, len(texts))
+		for _, text := range texts {
+			i, err := strconv.Atoi(text)
+			if err != nil {
+				return nil, err
+			}
+			emb = append(emb, []float32{float32(i)})
+		}
+		return emb, nil
+	})
+
+	texts := make([]string, 0, 20)
+	want := make([][]float32, 0, 20)
+	for i := 0; i < 20; i++ {
+		texts = append(texts, strconv.Itoa(i))
+		want = append(want, []float32{float32(i)})
+	}
+
+	e, err := NewEmbedder(client, WithBatchSize(2), WithConcurrency(3))
+	require.NoError(t, err)
+	emb, err := e.EmbedDocuments(context.Background(), texts)
+	require.NoError(t, err)
+	assert.Equal(t, want, emb)
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(3))
+	assert.Greater(t, maxInFlight.Load(), int32(1))
+}
+
+func TestEmbedderConcurrencyError(t *testing.T) {
+	t.Parallel()
+
+	errBatch := errors.New("batch failed")
+	client := EmbedderClientFunc(func(ctx context.Context, texts []string) ([][]float32, error) {
+		if texts[0] == "c" {
+			return nil, errBatch
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	_, err := ConcurrentBatchedEmbed(context.Background(), client, []string{"a", "b", "c", "d"}, 1, 4)
+	require.ErrorIs(t, err, errBatch)
+}
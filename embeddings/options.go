@@ -20,3 +20,20 @@ func WithBatchSize(batchSize int) Option {
 		p.BatchSize = batchSize
 	}
 }
+
+// WithNormalization is an option for specifying whether the embeddings of both
+// documents and queries should be L2-normalized to unit length, e.g. for
+// vector stores using cosine distance.
+func WithNormalization(normalize bool) Option {
+	return func(p *EmbedderImpl) {
+		p.Normalize = normalize
+	}
+}
+
+// WithConcurrency is an option for specifying the number of batches embedded
+// at once by EmbedDocuments, for the providers only taking small batches.
+func WithConcurrency(concurrency int) Option {
+	return func(p *EmbedderImpl) {
+		p.Concurrency = concurrency
+	}
+}
@@ -14,6 +14,23 @@ var (
 	ErrAllTextsLenZero = errors.New("all texts have length 0")
 )
 
+// Float64ToFloat32 converts a vector of float64 values, as decoded from the
+// JSON responses of some embedding APIs, to the []float32 vectors used by the
+// embedders and the vector stores. Each value is rounded to the nearest
+// float32: embeddings lose no meaningful precision, their values having far
+// fewer significant digits than a float32 holds. NaN stays NaN, and the
+// values beyond the float32 range become +Inf or -Inf.
+func Float64ToFloat32(v []float64) []float32 {
+	if v == nil {
+		return nil
+	}
+	converted := make([]float32, len(v))
+	for i, value := range v {
+		converted[i] = float32(value)
+	}
+	return converted
+}
+
 func CombineVectors(vectors [][]float32, weights []int) ([]float32, error) {
 	average, err := getAverage(vectors, weights)
 	if err != nil {
@@ -81,3 +98,14 @@ func getNorm(v []float32) float32 {
 
 	return float32(math.Sqrt(float64(sum)))
 }
+
+// normalize scales v in place to unit length. Zero vectors are left as is.
+func normalize(v []float32) {
+	norm := getNorm(v)
+	if norm == 0 {
+		return
+	}
+	for i := 0; i < len(v); i++ {
+		v[i] /= norm
+	}
+}
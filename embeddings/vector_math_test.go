@@ -1,6 +1,7 @@
 package embeddings
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -82,3 +83,19 @@ func TestGetNorm(t *testing.T) {
 		assert.InEpsilon(t, tc.expected, getNorm(tc.vector), 0.0001)
 	}
 }
+
+func TestFloat64ToFloat32(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, Float64ToFloat32(nil))
+	assert.Equal(t, []float32{}, Float64ToFloat32([]float64{}))
+
+	converted := Float64ToFloat32([]float64{0.1, -0.0123456789012345, 1e-40, math.MaxFloat64, math.Inf(-1), math.NaN()})
+	require.Len(t, converted, 6)
+	assert.Equal(t, float32(0.1), converted[0])
+	assert.InDelta(t, -0.0123456789012345, converted[1], 1e-9)
+	assert.InDelta(t, 1e-40, converted[2], 1e-45)
+	assert.True(t, math.IsInf(float64(converted[3]), 1))
+	assert.True(t, math.IsInf(float64(converted[4]), -1))
+	assert.True(t, math.IsNaN(float64(converted[5])))
+}
@@ -0,0 +1,40 @@
+package vertexai
+
+import "github.com/tmc/langchaingo/llms/googleai/palm"
+
+const _defaultStripNewLines = true
+
+// Option is a function type that can be used to modify the embedder.
+type Option func(v *VertexAI)
+
+// WithStripNewLines is an option for specifying the should it strip new lines.
+func WithStripNewLines(stripNewLines bool) Option {
+	return func(v *VertexAI) {
+		v.StripNewLines = stripNewLines
+	}
+}
+
+// WithDocumentTaskType is an option for specifying the task type documents are
+// embedded for. Defaults to palm.TaskTypeRetrievalDocument.
+func WithDocumentTaskType(taskType palm.TaskType) Option {
+	return func(v *VertexAI) {
+		v.DocumentTask = taskType
+	}
+}
+
+// WithQueryTaskType is an option for specifying the task type queries are
+// embedded for. Defaults to palm.TaskTypeRetrievalQuery.
+func WithQueryTaskType(taskType palm.TaskType) Option {
+	return func(v *VertexAI) {
+		v.QueryTask = taskType
+	}
+}
+
+// WithOutputDimensionality is an option for specifying the size of the
+// embeddings, to match the dimension of a vector store, for the models
+// supporting it. It must be positive.
+func WithOutputDimensionality(dimensionality int) Option {
+	return func(v *VertexAI) {
+		v.OutputDimensionality = dimensionality
+	}
+}
@@ -0,0 +1,88 @@
+// Package vertexai provides an embeddings.Embedder backed by the Vertex AI
+// PaLM embedding models of llms/googleai/palm.
+package vertexai
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms/googleai/palm"
+)
+
+// ErrMissingLLM is returned when the embedder is created without an LLM.
+var ErrMissingLLM = errors.New("missing the vertexai LLM")
+
+// embeddingClient is the part of palm.LLM used by the embedder.
+type embeddingClient interface {
+	CreateEmbeddingWithOptions(ctx context.Context, inputTexts []string, options ...palm.EmbeddingOption) ([][]float32, error) //nolint:lll
+}
+
+// VertexAI is an embeddings.Embedder creating embeddings with a palm.LLM.
+// Documents are embedded with the RETRIEVAL_DOCUMENT task type and queries
+// with RETRIEVAL_QUERY, so the vectors fit retrieval from a vector store.
+type VertexAI struct {
+	client embeddingClient
+
+	StripNewLines bool
+	DocumentTask  palm.TaskType
+	QueryTask     palm.TaskType
+	// OutputDimensionality is the size of the embeddings, the model default
+	// if zero.
+	OutputDimensionality int
+}
+
+var _ embeddings.Embedder = &VertexAI{}
+
+// NewVertexAIEmbedder returns an embedder creating embeddings with llm. The
+// LLM splits the texts into batches the embedding models accept.
+func NewVertexAIEmbedder(llm *palm.LLM, opts ...Option) (*VertexAI, error) {
+	if llm == nil {
+		return nil, ErrMissingLLM
+	}
+	return newVertexAI(llm, opts...), nil
+}
+
+func newVertexAI(client embeddingClient, opts ...Option) *VertexAI {
+	v := &VertexAI{
+		client:        client,
+		StripNewLines: _defaultStripNewLines,
+		DocumentTask:  palm.TaskTypeRetrievalDocument,
+		QueryTask:     palm.TaskTypeRetrievalQuery,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// EmbedDocuments creates one vector embedding for each of the texts.
+func (v *VertexAI) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	texts = embeddings.MaybeRemoveNewLines(texts, v.StripNewLines)
+	return v.client.CreateEmbeddingWithOptions(ctx, texts, v.embeddingOptions(v.DocumentTask)...)
+}
+
+// EmbedQuery embeds a single text.
+func (v *VertexAI) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	if v.StripNewLines {
+		text = strings.ReplaceAll(text, "\n", " ")
+	}
+
+	emb, err := v.client.CreateEmbeddingWithOptions(ctx, []string{text}, v.embeddingOptions(v.QueryTask)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return emb[0], nil
+}
+
+// embeddingOptions returns the options of the embedding requests of the task
+// type.
+func (v *VertexAI) embeddingOptions(taskType palm.TaskType) []palm.EmbeddingOption {
+	options := []palm.EmbeddingOption{palm.WithTaskType(taskType)}
+	if v.OutputDimensionality != 0 {
+		options = append(options, palm.WithOutputDimensionality(v.OutputDimensionality))
+	}
+	return options
+}
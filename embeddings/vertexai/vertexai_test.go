@@ -0,0 +1,91 @@
+package vertexai
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms/googleai/palm"
+)
+
+// fakeClient embeds each text as a vector of its length, recording the
+// requests it receives.
+type fakeClient struct {
+	texts      [][]string
+	numOptions []int
+}
+
+func (f *fakeClient) CreateEmbeddingWithOptions(_ context.Context, texts []string, options ...palm.EmbeddingOption) ([][]float32, error) { //nolint:lll
+	f.texts = append(f.texts, texts)
+	f.numOptions = append(f.numOptions, len(options))
+	embeddings := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		embeddings = append(embeddings, []float32{float32(len(text))})
+	}
+	return embeddings, nil
+}
+
+func TestVertexAIEmbedder(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{}
+	v := newVertexAI(client)
+
+	docs, err := v.EmbedDocuments(context.Background(), []string{"hello\nworld", "bye"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{11}, {3}}, docs)
+
+	query, err := v.EmbedQuery(context.Background(), "hi\nthere")
+	require.NoError(t, err)
+	assert.Equal(t, []float32{8}, query)
+
+	assert.Equal(t, [][]string{{"hello world", "bye"}, {"hi there"}}, client.texts)
+}
+
+func TestNewVertexAIEmbedderMissingLLM(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewVertexAIEmbedder(nil)
+	require.ErrorIs(t, err, ErrMissingLLM)
+}
+
+func TestVertexAIEmbeddings(t *testing.T) {
+	t.Parallel()
+
+	if gcpProjectID := os.Getenv("GOOGLE_CLOUD_PROJECT"); gcpProjectID == "" {
+		t.Skip("GOOGLE_CLOUD_PROJECT not set")
+	}
+	llm, err := palm.New()
+	require.NoError(t, err)
+	v, err := NewVertexAIEmbedder(llm)
+	require.NoError(t, err)
+
+	_, err = v.EmbedQuery(context.Background(), "Hello world!")
+	require.NoError(t, err)
+
+	embeddings, err := v.EmbedDocuments(context.Background(), []string{"Hello world", "The world is ending", "good bye"})
+	require.NoError(t, err)
+	assert.Len(t, embeddings, 3)
+}
+
+func TestVertexAIEmbedderOutputDimensionality(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{}
+	v := newVertexAI(client, WithOutputDimensionality(256))
+	assert.Equal(t, 256, v.OutputDimensionality)
+
+	_, err := v.EmbedDocuments(context.Background(), []string{"hello"})
+	require.NoError(t, err)
+	_, err = v.EmbedQuery(context.Background(), "hi")
+	require.NoError(t, err)
+
+	v = newVertexAI(client)
+	_, err = v.EmbedQuery(context.Background(), "hi")
+	require.NoError(t, err)
+
+	// The task type, and the output dimensionality if set.
+	assert.Equal(t, []int{2, 2, 1}, client.numOptions)
+}
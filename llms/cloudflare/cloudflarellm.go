@@ -124,7 +124,7 @@ func (o *LLM) GenerateContent(ctx context.Context, messages []llms.MessageConten
 
 	choices := []*llms.ContentChoice{
 		{
-			Content: res.Result.Response,
+			Content: llms.TrimStopWords(res.Result.Response, opts.StopWords),
 		},
 	}
 
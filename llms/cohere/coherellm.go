@@ -44,7 +44,8 @@ func (o *LLM) GenerateContent(ctx context.Context, messages []llms.MessageConten
 	msg0 := messages[0]
 	part := msg0.Parts[0]
 	result, err := o.client.CreateGeneration(ctx, &cohereclient.GenerationRequest{
-		Prompt: part.(llms.TextContent).Text,
+		Prompt:        part.(llms.TextContent).Text,
+		StopSequences: opts.StopWords,
 	})
 	if err != nil {
 		if o.CallbacksHandler != nil {
@@ -66,6 +66,8 @@ func New(token string, baseURL string, model string, opts ...Option) (*Client, e
 
 type GenerationRequest struct {
 	Prompt string `json:"prompt"`
+	// StopSequences stop the generation, excluded from the text. Optional.
+	StopSequences []string `json:"stop_sequences,omitempty"`
 }
 
 type Generation struct {
@@ -73,8 +75,9 @@ type Generation struct {
 }
 
 type generateRequestPayload struct {
-	Prompt string `json:"prompt"`
-	Model  string `json:"model"`
+	Prompt        string   `json:"prompt"`
+	Model         string   `json:"model"`
+	StopSequences []string `json:"stop_sequences,omitempty"`
 }
 
 type generateResponsePayload struct {
@@ -92,8 +95,9 @@ func (c *Client) CreateGeneration(ctx context.Context, r *GenerationRequest) (*G
 	}
 
 	payload := generateRequestPayload{
-		Prompt: r.Prompt,
-		Model:  c.model,
+		Prompt:        r.Prompt,
+		Model:         c.model,
+		StopSequences: r.StopSequences,
 	}
 
 	payloadBytes, err := json.Marshal(&payload)
@@ -50,15 +50,35 @@ func GetModelContextSize(model string) int {
 
 // CountTokens gets the number of tokens the text contains.
 func CountTokens(model, text string) int {
-	e, err := tiktoken.EncodingForModel(model)
+	return CountTokensBatch(model, []string{text})[0]
+}
+
+// CountTokensBatch gets the number of tokens each of the texts contains, like
+// CountTokens but sharing a single tokenizer across the texts.
+func CountTokensBatch(model string, texts []string) []int {
+	counts := make([]int, len(texts))
+	e, err := encodingForModel(model)
 	if err != nil {
-		e, err = tiktoken.GetEncoding("gpt2")
-		if err != nil {
-			log.Printf("[WARN] Failed to calculate number of tokens for model, falling back to approximate count")
-			return len([]rune(text)) / _tokenApproximation
+		log.Printf("[WARN] Failed to calculate number of tokens for model, falling back to approximate count")
+		for i, text := range texts {
+			counts[i] = len([]rune(text)) / _tokenApproximation
 		}
+		return counts
+	}
+	for i, text := range texts {
+		counts[i] = len(e.Encode(text, nil, nil))
+	}
+	return counts
+}
+
+// encodingForModel returns the tokenizer of the model, falling back to the
+// gpt2 encoding for unknown models.
+func encodingForModel(model string) (*tiktoken.Tiktoken, error) {
+	e, err := tiktoken.EncodingForModel(model)
+	if err != nil {
+		return tiktoken.GetEncoding("gpt2")
 	}
-	return len(e.Encode(text, nil, nil))
+	return e, nil
 }
 
 // CalculateMaxTokens calculates the max number of tokens that could be added to a text.
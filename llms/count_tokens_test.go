@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCountTokens(t *testing.T) {
@@ -12,3 +13,17 @@ func TestCountTokens(t *testing.T) {
 	expectedNumTokens := 4
 	assert.Equal(t, expectedNumTokens, numTokens)
 }
+
+func TestCountTokensBatch(t *testing.T) {
+	t.Parallel()
+
+	texts := []string{"test for counting tokens", "", "Bonjour, comment ça va ?"}
+	for _, model := range []string{"gpt-3.5-turbo", "text-bison"} {
+		counts := CountTokensBatch(model, texts)
+		require.Len(t, counts, len(texts))
+		for i, text := range texts {
+			assert.Equal(t, CountTokens(model, text), counts[i])
+		}
+	}
+	assert.Empty(t, CountTokensBatch("gpt-3.5-turbo", nil))
+}
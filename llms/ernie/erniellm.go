@@ -83,6 +83,7 @@ func (o *LLM) GenerateContent(ctx context.Context, messages []llms.MessageConten
 		Temperature:   opts.Temperature,
 		TopP:          opts.TopP,
 		PenaltyScore:  opts.RepetitionPenalty,
+		Stop:          opts.StopWords,
 		StreamingFunc: opts.StreamingFunc,
 		Stream:        opts.StreamingFunc != nil,
 	})
@@ -60,6 +60,7 @@ type CompletionRequest struct {
 	Temperature   float64                                       `json:"temperature"`
 	TopP          float64                                       `json:"top_p,omitempty"`
 	PenaltyScore  float64                                       `json:"penalty_score,omitempty"`
+	Stop          []string                                      `json:"stop,omitempty"`
 	Stream        bool                                          `json:"stream,omitempty"`
 	UserID        string                                        `json:"user_id,omitempty"`
 	StreamingFunc func(ctx context.Context, chunk []byte) error `json:"-"`
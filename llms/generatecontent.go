@@ -153,6 +153,7 @@ type ContentChoice struct {
 	StopReason string
 
 	// GenerationInfo is arbitrary information the model adds to the response.
+	// Its well-known keys can be read by converting it to a GenerationInfo.
 	GenerationInfo map[string]any
 
 	// FuncCall is non-nil when the model asks to invoke a function/tool.
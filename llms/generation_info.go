@@ -0,0 +1,71 @@
+package llms
+
+// The keys of the generation info reported by the models.
+const (
+	GenerationInfoPromptTokens     = "PromptTokens"
+	GenerationInfoCompletionTokens = "CompletionTokens"
+	GenerationInfoTotalTokens      = "TotalTokens"
+	GenerationInfoFinishReason     = "FinishReason"
+)
+
+// GenerationInfo is the information a model adds to a response choice, as
+// found in ContentChoice.GenerationInfo. Its well-known keys are read with
+// typed accessors.
+type GenerationInfo map[string]any
+
+// NewGenerationInfo returns the generation info for the given token usage and
+// finish reason. The finish reason is left out if empty.
+func NewGenerationInfo(promptTokens, completionTokens int, finishReason string) GenerationInfo {
+	info := GenerationInfo{
+		GenerationInfoPromptTokens:     promptTokens,
+		GenerationInfoCompletionTokens: completionTokens,
+		GenerationInfoTotalTokens:      promptTokens + completionTokens,
+	}
+	if finishReason != "" {
+		info[GenerationInfoFinishReason] = finishReason
+	}
+	return info
+}
+
+// PromptTokens returns the number of tokens of the prompt, or 0 if unknown.
+func (g GenerationInfo) PromptTokens() int {
+	return g.intValue(GenerationInfoPromptTokens)
+}
+
+// CompletionTokens returns the number of generated tokens, or 0 if unknown.
+func (g GenerationInfo) CompletionTokens() int {
+	return g.intValue(GenerationInfoCompletionTokens)
+}
+
+// TotalTokens returns the total number of tokens, or the sum of the prompt and
+// completion tokens if it isn't reported.
+func (g GenerationInfo) TotalTokens() int {
+	if _, ok := g[GenerationInfoTotalTokens]; ok {
+		return g.intValue(GenerationInfoTotalTokens)
+	}
+	return g.PromptTokens() + g.CompletionTokens()
+}
+
+// FinishReason returns the reason the model stopped generating, or "" if
+// unknown.
+func (g GenerationInfo) FinishReason() string {
+	reason, _ := g[GenerationInfoFinishReason].(string)
+	return reason
+}
+
+// intValue returns the value of key as an int, whatever integer type the
+// model reported it with.
+func (g GenerationInfo) intValue(key string) int {
+	switch v := g[key].(type) {
+	case int:
+		return v
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,32 @@
+package llms
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerationInfo(t *testing.T) {
+	t.Parallel()
+
+	info := NewGenerationInfo(3, 4, "STOP")
+	assert.Equal(t, 3, info.PromptTokens())
+	assert.Equal(t, 4, info.CompletionTokens())
+	assert.Equal(t, 7, info.TotalTokens())
+	assert.Equal(t, "STOP", info.FinishReason())
+
+	info = NewGenerationInfo(1, 2, "")
+	assert.NotContains(t, info, GenerationInfoFinishReason)
+	assert.Equal(t, "", info.FinishReason())
+
+	// Provider maps use various integer types.
+	info = GenerationInfo(map[string]any{
+		"PromptTokens":     int32(5),
+		"CompletionTokens": float64(6),
+	})
+	assert.Equal(t, 5, info.PromptTokens())
+	assert.Equal(t, 6, info.CompletionTokens())
+	assert.Equal(t, 11, info.TotalTokens())
+
+	assert.Equal(t, 0, GenerationInfo(nil).PromptTokens())
+}
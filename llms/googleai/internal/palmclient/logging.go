@@ -0,0 +1,101 @@
+package palmclient
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"cloud.google.com/go/aiplatform/apiv1/aiplatformpb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Logger is called with the JSON request and response bodies of every request
+// sent to Vertex AI. The response of a failed request is a JSON object with
+// the error message, and the response of a streaming request is the JSON
+// array of the streamed responses. The request headers, which carry the
+// credentials, are never logged.
+type Logger func(req, resp []byte)
+
+// WithLogger sets a Logger called for every prediction request: the requests
+// of CreateCompletion, CreateChat and CreateEmbedding, retries included. No
+// requests are logged by default.
+func WithLogger(logger Logger) Option {
+	return func(c *PaLMClient) {
+		c.logger = logger
+	}
+}
+
+// logCall logs a unary request and its response or error.
+func (c *PaLMClient) logCall(req, resp proto.Message, err error) {
+	if c.logger == nil {
+		return
+	}
+	if err != nil {
+		c.logger(marshalLog(req), errorLog(err))
+		return
+	}
+	c.logger(marshalLog(req), marshalLog(resp))
+}
+
+// logStream returns stream, logging req and the responses received once the
+// stream ends.
+func (c *PaLMClient) logStream(
+	req *aiplatformpb.StreamingPredictRequest,
+	stream aiplatformpb.PredictionService_ServerStreamingPredictClient,
+) aiplatformpb.PredictionService_ServerStreamingPredictClient {
+	if c.logger == nil {
+		return stream
+	}
+	return &loggedStream{
+		PredictionService_ServerStreamingPredictClient: stream,
+		logger: c.logger,
+		req:    req,
+	}
+}
+
+// loggedStream is a prediction stream logging its request and responses once
+// the stream ends.
+type loggedStream struct {
+	aiplatformpb.PredictionService_ServerStreamingPredictClient
+
+	logger    Logger
+	req       *aiplatformpb.StreamingPredictRequest
+	responses []json.RawMessage
+	done      bool
+}
+
+func (s *loggedStream) Recv() (*aiplatformpb.StreamingPredictResponse, error) {
+	resp, err := s.PredictionService_ServerStreamingPredictClient.Recv()
+	if s.done {
+		return resp, err
+	}
+	switch {
+	case errors.Is(err, io.EOF):
+		s.done = true
+		data, _ := json.Marshal(s.responses)
+		s.logger(marshalLog(s.req), data)
+	case err != nil:
+		s.done = true
+		s.logger(marshalLog(s.req), errorLog(err))
+	default:
+		s.responses = append(s.responses, marshalLog(resp))
+	}
+	return resp, err
+}
+
+// marshalLog returns the JSON encoding of a message, "null" if it can't be
+// encoded.
+func marshalLog(m proto.Message) []byte {
+	data, err := protojson.Marshal(m)
+	if err != nil || len(data) == 0 {
+		return []byte("null")
+	}
+	return data
+}
+
+// errorLog returns the JSON object logged for a failed request.
+func errorLog(err error) []byte {
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return data
+}
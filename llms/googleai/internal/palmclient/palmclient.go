@@ -4,19 +4,29 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
 	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	aiplatform "cloud.google.com/go/aiplatform/apiv1"
 	"cloud.google.com/go/aiplatform/apiv1/aiplatformpb"
+	"github.com/googleapis/gax-go/v2"
 	"github.com/tmc/langchaingo/llms"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
 const (
-	defaultAPIEndpoint = "us-central1-aiplatform.googleapis.com:443"
-	defaultLocation    = "us-central1"
-	defaultPublisher   = "google"
+	defaultLocation  = "us-central1"
+	defaultPublisher = "google"
 )
 
 var (
@@ -39,131 +49,506 @@ const (
 	ChatModelName      = "chat-bison"
 
 	defaultMaxConns = 4
+
+	// defaultEmbeddingBatchSize is the maximum number of instances per
+	// embedding request accepted by the Vertex AI embedding models.
+	defaultEmbeddingBatchSize = 5
+
+	// DefaultMaxRetries is the number of retries of a failed prediction
+	// request unless set with WithMaxRetries.
+	DefaultMaxRetries     = 3
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	maxRetryDelay         = 10 * time.Second
 )
 
+// predictionClient is the subset of the Vertex AI prediction API used by the
+// PaLM client.
+//
+//nolint:lll
+type predictionClient interface {
+	Predict(ctx context.Context, req *aiplatformpb.PredictRequest, opts ...gax.CallOption) (*aiplatformpb.PredictResponse, error)
+	ServerStreamingPredict(ctx context.Context, req *aiplatformpb.StreamingPredictRequest, opts ...gax.CallOption) (aiplatformpb.PredictionService_ServerStreamingPredictClient, error)
+}
+
+// tokenClient is the subset of the Vertex AI LLM utility API used by the PaLM
+// client to count tokens.
+//
+//nolint:lll
+type tokenClient interface {
+	CountTokens(ctx context.Context, req *aiplatformpb.CountTokensRequest, opts ...gax.CallOption) (*aiplatformpb.CountTokensResponse, error)
+}
+
 // PaLMClient represents a Vertex AI based PaLM API client.
 type PaLMClient struct {
-	client    *aiplatform.PredictionClient
+	client    predictionClient
 	projectID string
+	location  string
+	endpoint  string
+	textModel string
+
+	embeddingBatchSize int
+
+	maxRetries     int
+	retryBaseDelay time.Duration
+
+	// labels are the key-value pairs of the metadata of the prediction
+	// requests, sorted by key, set with WithRequestLabels.
+	labels []string
+
+	clientOptions []option.ClientOption
+	httpClient    *http.Client
+	logger        Logger
+
+	// tokens is created on the first CountTokens call, with apiOptions.
+	tokens     tokenClient
+	tokensOnce sync.Once
+	tokensErr  error
+	apiOptions []option.ClientOption
+}
+
+// Option is a function that configures a PaLMClient.
+type Option func(*PaLMClient)
+
+// WithClientOptions passes options to the underlying Google API client.
+func WithClientOptions(opts ...option.ClientOption) Option {
+	return func(c *PaLMClient) {
+		c.clientOptions = append(c.clientOptions, opts...)
+	}
+}
+
+// WithHTTPClient sends all requests with the given HTTP client, through the
+// Vertex AI REST API instead of gRPC. The client is responsible for the
+// authentication of the requests: New fails with ErrInvalidValue if client
+// options, e.g. credentials, are also given, as they would not be used.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *PaLMClient) {
+		c.httpClient = client
+	}
+}
+
+// WithTextModel sets the name of the model used for text completions.
+// Defaults to TextModelName.
+func WithTextModel(model string) Option {
+	return func(c *PaLMClient) {
+		c.textModel = model
+	}
+}
+
+// WithLocation sets the GCP region the requests are sent to. Defaults to
+// "us-central1".
+func WithLocation(location string) Option {
+	return func(c *PaLMClient) {
+		c.location = location
+	}
+}
+
+// WithEndpoint overrides the API endpoint the requests are sent to, e.g. a
+// mock server or a Private Service Connect endpoint. It is either a host:port
+// or a URL, whose scheme is only used by the REST API of WithHTTPClient and
+// defaults to https. Defaults to the regional endpoint of the location.
+func WithEndpoint(endpoint string) Option {
+	return func(c *PaLMClient) {
+		c.endpoint = endpoint
+	}
+}
+
+// WithEmbeddingBatchSize sets the maximum number of inputs sent per embedding
+// request. Larger inputs are split into several requests. Defaults to 5.
+func WithEmbeddingBatchSize(size int) Option {
+	return func(c *PaLMClient) {
+		c.embeddingBatchSize = size
+	}
+}
+
+// WithMaxRetries sets how many times a prediction request failing with a
+// retryable status (resource exhausted or unavailable) is retried, with
+// exponential backoff. Zero disables retries. Defaults to 3.
+func WithMaxRetries(n int) Option {
+	return func(c *PaLMClient) {
+		c.maxRetries = n
+	}
+}
+
+// WithRequestLabels attaches the labels to the metadata of every prediction
+// request: the gRPC metadata, or the HTTP headers with the REST API of
+// WithHTTPClient. The label keys are lowercased. They aren't Vertex AI
+// resource or billing labels, since PredictRequest has no labels field.
+func WithRequestLabels(labels map[string]string) Option {
+	return func(c *PaLMClient) {
+		keys := make([]string, 0, len(labels))
+		for key := range labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		c.labels = make([]string, 0, 2*len(keys)) //nolint:gomnd
+		for _, key := range keys {
+			c.labels = append(c.labels, key, labels[key])
+		}
+	}
 }
 
 // New returns a new Vertex AI based PaLM API client.
-func New(projectID string, opts ...option.ClientOption) (*PaLMClient, error) {
+func New(projectID string, opts ...Option) (*PaLMClient, error) {
+	c := &PaLMClient{
+		projectID:          projectID,
+		location:           defaultLocation,
+		textModel:          TextModelName,
+		embeddingBatchSize: defaultEmbeddingBatchSize,
+		maxRetries:         DefaultMaxRetries,
+		retryBaseDelay:     defaultRetryBaseDelay,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.location == "" {
+		c.location = defaultLocation
+	}
+	if c.embeddingBatchSize <= 0 {
+		c.embeddingBatchSize = defaultEmbeddingBatchSize
+	}
+	if c.maxRetries < 0 {
+		c.maxRetries = 0
+	}
+	if c.httpClient != nil {
+		if len(c.clientOptions) > 0 {
+			return nil, fmt.Errorf("%w: client options can't be combined with an HTTP client, which authenticates the requests",
+				ErrInvalidValue)
+		}
+		rest := newRESTClient(c.httpClient, restBaseURL(c.location, c.endpoint))
+		c.client = rest
+		c.tokens = rest
+		return c, nil
+	}
+
 	numConns := runtime.GOMAXPROCS(0)
 	if numConns > defaultMaxConns {
 		numConns = defaultMaxConns
 	}
 	o := []option.ClientOption{
 		option.WithGRPCConnectionPool(numConns),
-		option.WithEndpoint(defaultAPIEndpoint),
+		option.WithEndpoint(grpcEndpoint(c.location, c.endpoint)),
 	}
-	o = append(o, opts...)
+	o = append(o, c.clientOptions...)
+	c.apiOptions = o
 
 	ctx := context.Background()
 	client, err := aiplatform.NewPredictionClient(ctx, o...)
 	if err != nil {
 		return nil, err
 	}
-	return &PaLMClient{
-		client:    client,
-		projectID: projectID,
-	}, nil
+	c.client = client
+	return c, nil
 }
 
 // ErrEmptyResponse is returned when the OpenAI API returns an empty response.
 var ErrEmptyResponse = errors.New("empty response")
 
+// ErrContentFiltered is returned when the API blocked the response for safety
+// reasons. The error wraps the blocked safety categories.
+var ErrContentFiltered = errors.New("content filtered")
+
 // CompletionRequest is a request to create a completion.
 type CompletionRequest struct {
 	Prompts       []string `json:"prompts"`
 	MaxTokens     int      `json:"max_tokens"`
 	Temperature   float64  `json:"temperature"`
-	TopP          int      `json:"top_p,omitempty"`
+	TopP          float64  `json:"top_p,omitempty"`
 	TopK          int      `json:"top_k,omitempty"`
 	StopSequences []string `json:"stop_sequences"`
+	// Seed makes the sampling deterministic, for the models supporting it;
+	// the others ignore it. Not sent if 0.
+	Seed int `json:"seed,omitempty"`
+
+	// StreamingFunc is a function to be called for each chunk of a streaming response.
+	// Return an error to stop streaming early.
+	StreamingFunc func(ctx context.Context, chunk []byte) error `json:"-"`
 }
 
 // Completion is a completion.
 type Completion struct {
-	Text string `json:"text"`
+	Text             string            `json:"text"`
+	SafetyAttributes *SafetyAttributes `json:"safety_attributes,omitempty"`
 }
 
-// CreateCompletion creates a completion.
-func (c *PaLMClient) CreateCompletion(ctx context.Context, r *CompletionRequest) ([]*Completion, error) {
-	params := map[string]interface{}{
-		"maxOutputTokens": r.MaxTokens,
-		"temperature":     r.Temperature,
-		"top_p":           r.TopP,
-		"top_k":           r.TopK,
-		"stopSequences":   convertArray(r.StopSequences),
+// CompletionResponse is a response to a completion request.
+type CompletionResponse struct {
+	Completions []*Completion `json:"completions"`
+	Usage       TokenUsage    `json:"usage"`
+}
+
+// TokenUsage is the token accounting the API reports for a request.
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// SafetyAttributes are the safety scores attached to a generated candidate.
+type SafetyAttributes struct {
+	Blocked    bool      `json:"blocked"`
+	Categories []string  `json:"categories,omitempty"`
+	Scores     []float64 `json:"scores,omitempty"`
+}
+
+// CreateCompletion creates a completion. If r.StreamingFunc is set, the
+// completion of a single prompt is streamed and the callback is invoked for
+// each chunk as it arrives. When the text model doesn't support streaming, or
+// for several prompts, the callback is invoked once with the full text of
+// each completion instead.
+func (c *PaLMClient) CreateCompletion(ctx context.Context, r *CompletionRequest) (*CompletionResponse, error) {
+	if r.StreamingFunc == nil {
+		return c.createCompletion(ctx, r)
 	}
-	predictions, err := c.batchPredict(ctx, TextModelName, r.Prompts, params)
+	if len(r.Prompts) == 1 {
+		resp, err := c.completionStream(ctx, r)
+		if !errors.Is(err, errStreamingUnsupported) {
+			return resp, err
+		}
+	}
+	resp, err := c.createCompletion(ctx, r)
 	if err != nil {
 		return nil, err
 	}
+	for _, completion := range resp.Completions {
+		if err := r.StreamingFunc(ctx, []byte(completion.Text)); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// CreateCompletionPartial creates the completions of several prompts in one
+// request like CreateCompletion, without streaming, but tolerates prompts that
+// get no completion, e.g. because it was filtered. The returned completions
+// are aligned with r.Prompts and are nil for the prompts that got none, whose
+// indices are returned.
+func (c *PaLMClient) CreateCompletionPartial(ctx context.Context, r *CompletionRequest) (*CompletionResponse, []int, error) { //nolint:lll
+	return c.predictCompletions(ctx, r, true)
+}
+
+// createCompletion creates the completions of a request without streaming.
+func (c *PaLMClient) createCompletion(ctx context.Context, r *CompletionRequest) (*CompletionResponse, error) {
+	resp, _, err := c.predictCompletions(ctx, r, false)
+	return resp, err
+}
+
+// predictCompletions issues the completion prediction request of the prompts.
+// If partial is set, the prompts without a valid prediction get a nil
+// completion and their indices are returned, rather than failing the request.
+func (c *PaLMClient) predictCompletions(ctx context.Context, r *CompletionRequest, partial bool) (*CompletionResponse, []int, error) { //nolint:lll
+	resp, err := c.batchPredict(ctx, c.textModel, contentInstances(r.Prompts), completionParams(r))
+	if err != nil && (!partial || !errors.Is(err, ErrEmptyResponse)) {
+		return nil, nil, err
+	}
 	completions := []*Completion{}
-	for _, p := range predictions {
-		value := p.GetStructValue().AsMap()
-		text, ok := value["content"].(string)
-		if !ok {
-			return nil, fmt.Errorf("%w: %v", ErrMissingValue, "content")
+	if partial {
+		completions = make([]*Completion, len(r.Prompts))
+	}
+	var missing []int
+	for i, p := range resp.GetPredictions() {
+		completion, err := parseCompletion(p)
+		if err != nil {
+			if !partial {
+				return nil, nil, err
+			}
+			missing = append(missing, i)
+			continue
+		}
+		if partial {
+			if i < len(completions) {
+				completions[i] = completion
+			}
+			continue
+		}
+		completions = append(completions, completion)
+	}
+	if partial {
+		for i := len(resp.GetPredictions()); i < len(r.Prompts); i++ {
+			missing = append(missing, i)
 		}
-		completions = append(completions, &Completion{
-			Text: text,
-		})
 	}
-	return completions, nil
+	return &CompletionResponse{
+		Completions: completions,
+		Usage:       parseTokenUsage(resp.GetMetadata()),
+	}, missing, nil
+}
+
+// parseCompletion converts a completion prediction.
+func parseCompletion(p *structpb.Value) (*Completion, error) {
+	value := p.GetStructValue().AsMap()
+	safety := parseSafetyAttributes(value["safetyAttributes"])
+	text, ok := value["content"].(string)
+	if text == "" && safety.blocked() {
+		return nil, safety.filteredError()
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrMissingValue, "content")
+	}
+	return &Completion{
+		Text:             text,
+		SafetyAttributes: safety,
+	}, nil
 }
 
 // EmbeddingRequest is a request to create an embedding.
 type EmbeddingRequest struct {
 	Input []string `json:"input"`
+	// TaskType is the intended downstream application of the embeddings,
+	// e.g. RETRIEVAL_DOCUMENT or RETRIEVAL_QUERY. Optional.
+	TaskType string `json:"task_type,omitempty"`
+	// Title is the title of the embedded documents. Only valid with the
+	// RETRIEVAL_DOCUMENT task type. Optional.
+	Title string `json:"title,omitempty"`
+	// OutputDimensionality is the size of the embeddings, for the models
+	// supporting smaller ones, e.g. text-embedding-004. Optional.
+	OutputDimensionality int `json:"output_dimensionality,omitempty"`
 }
 
-// CreateEmbedding creates embeddings.
+// CreateEmbedding creates embeddings. The inputs are sent in batches of at
+// most the configured embedding batch size, and the results are concatenated
+// in order.
 func (c *PaLMClient) CreateEmbedding(ctx context.Context, r *EmbeddingRequest) ([][]float32, error) {
-	params := map[string]interface{}{}
-	responses, err := c.batchPredict(ctx, embeddingModelName, r.Input, params)
+	predictions, err := c.predictEmbeddings(ctx, r, false)
 	if err != nil {
 		return nil, err
 	}
 
 	embeddings := [][]float32{}
-	for _, res := range responses {
-		value := res.GetStructValue().AsMap()
-		embedding, ok := value["embeddings"].(map[string]interface{})
-		if !ok {
-			return nil, fmt.Errorf("%w: %v", ErrMissingValue, "embeddings")
+	for _, res := range predictions {
+		embedding, err := parseEmbedding(res)
+		if err != nil {
+			return nil, err
 		}
-		values, ok := embedding["values"].([]interface{})
-		if !ok {
-			return nil, fmt.Errorf("%w: %v", ErrMissingValue, "values")
+		embeddings = append(embeddings, embedding)
+	}
+	return embeddings, nil
+}
+
+// CreateEmbeddingPartial creates embeddings like CreateEmbedding, but tolerates
+// inputs that get no embedding. The returned embeddings are aligned with
+// r.Input and are nil for the inputs that got none; those inputs are retried
+// once, and the indices of the ones still missing afterwards are returned.
+func (c *PaLMClient) CreateEmbeddingPartial(ctx context.Context, r *EmbeddingRequest) ([][]float32, []int, error) { //nolint:lll
+	embeddings := make([][]float32, len(r.Input))
+	pending := make([]int, len(r.Input))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	const attempts = 2
+	for attempt := 0; attempt < attempts && len(pending) > 0; attempt++ {
+		input := make([]string, 0, len(pending))
+		for _, idx := range pending {
+			input = append(input, r.Input[idx])
 		}
-		floatValues := []float32{}
-		for _, v := range values {
-			val, ok := v.(float32)
-			if !ok {
-				valF64, ok := v.(float64)
-				if !ok {
-					return nil, fmt.Errorf("%w: %v is not a float64 or float32, it is a %T", ErrInvalidValue, "value", v)
+		req := *r
+		req.Input = input
+		predictions, err := c.predictEmbeddings(ctx, &req, true)
+		if err != nil {
+			return embeddings, pending, err
+		}
+
+		missing := make([]int, 0, len(pending))
+		for i, idx := range pending {
+			if i < len(predictions) {
+				if embedding, err := parseEmbedding(predictions[i]); err == nil {
+					embeddings[idx] = embedding
+					continue
 				}
-				val = float32(valF64)
 			}
-			floatValues = append(floatValues, val)
+			missing = append(missing, idx)
 		}
-		embeddings = append(embeddings, floatValues)
+		pending = missing
 	}
-	return embeddings, nil
+
+	if len(pending) == 0 {
+		return embeddings, nil, nil
+	}
+	return embeddings, pending, nil
+}
+
+// predictEmbeddings issues the embedding prediction requests, one per batch of
+// inputs, and returns the predictions of all of them in order. If partial is
+// set, a batch with missing predictions is padded with nil values so that the
+// predictions stay aligned with the inputs.
+func (c *PaLMClient) predictEmbeddings(ctx context.Context, r *EmbeddingRequest, partial bool) ([]*structpb.Value, error) { //nolint:lll
+	params := map[string]interface{}{}
+	if r.OutputDimensionality > 0 {
+		params["outputDimensionality"] = r.OutputDimensionality
+	}
+	predictions := make([]*structpb.Value, 0, len(r.Input))
+	for start := 0; start < len(r.Input); start += c.embeddingBatchSize {
+		end := start + c.embeddingBatchSize
+		if end > len(r.Input) {
+			end = len(r.Input)
+		}
+		instances := contentInstances(r.Input[start:end])
+		for _, instance := range instances {
+			if r.TaskType != "" {
+				instance["task_type"] = r.TaskType
+			}
+			if r.Title != "" {
+				instance["title"] = r.Title
+			}
+		}
+
+		resp, err := c.batchPredict(ctx, embeddingModelName, instances, params)
+		if err != nil && (!partial || !errors.Is(err, ErrEmptyResponse)) {
+			return nil, err
+		}
+		batch := resp.GetPredictions()
+		if partial {
+			padded := make([]*structpb.Value, len(instances))
+			copy(padded, batch)
+			batch = padded
+		}
+		predictions = append(predictions, batch...)
+	}
+	return predictions, nil
+}
+
+// parseEmbedding converts an embedding prediction.
+func parseEmbedding(res *structpb.Value) ([]float32, error) {
+	value := res.GetStructValue().AsMap()
+	embedding, ok := value["embeddings"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrMissingValue, "embeddings")
+	}
+	values, ok := embedding["values"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrMissingValue, "values")
+	}
+	floatValues := []float32{}
+	for _, v := range values {
+		val, ok := v.(float32)
+		if !ok {
+			valF64, ok := v.(float64)
+			if !ok {
+				return nil, fmt.Errorf("%w: %v is not a float64 or float32, it is a %T", ErrInvalidValue, "value", v)
+			}
+			val = float32(valF64)
+		}
+		floatValues = append(floatValues, val)
+	}
+	return floatValues, nil
 }
 
 // ChatRequest is a request to create an embedding.
 type ChatRequest struct {
 	Context        string         `json:"context"`
 	Messages       []*ChatMessage `json:"messages"`
+	MaxTokens      int            `json:"max_tokens,omitempty"`
 	Temperature    float64        `json:"temperature"`
-	TopP           int            `json:"top_p,omitempty"`
+	TopP           float64        `json:"top_p,omitempty"`
 	TopK           int            `json:"top_k,omitempty"`
+	StopSequences  []string       `json:"stop_sequences,omitempty"`
 	CandidateCount int            `json:"candidate_count,omitempty"`
+	// Seed makes the sampling deterministic, for the models supporting it;
+	// the others ignore it. Not sent if 0.
+	Seed int `json:"seed,omitempty"`
+
+	// StreamingFunc is a function to be called for each chunk of a streaming response.
+	// Return an error to stop streaming early.
+	StreamingFunc func(ctx context.Context, chunk []byte) error `json:"-"`
 }
 
 // ChatMessage is a message in a chat.
@@ -195,16 +580,28 @@ func (m ChatMessage) GetContent() string {
 // ChatResponse is a response to a chat request.
 type ChatResponse struct {
 	Candidates []ChatMessage
+	// SafetyAttributes holds the safety scores of each of the candidates, in
+	// the same order.
+	SafetyAttributes []*SafetyAttributes
+	Usage            TokenUsage
 }
 
-// CreateChat creates chat request.
+// CreateChat creates chat request. If r.StreamingFunc is set, the response is
+// streamed and the callback is invoked for each chunk of the first candidate
+// as it arrives, the other candidates of r.CandidateCount only being returned
+// once complete.
 func (c *PaLMClient) CreateChat(ctx context.Context, r *ChatRequest) (*ChatResponse, error) {
-	responses, err := c.chat(ctx, r)
+	if r.StreamingFunc != nil {
+		return c.chatStream(ctx, r)
+	}
+	resp, err := c.chat(ctx, r)
 	if err != nil {
 		return nil, err
 	}
-	chatResponse := &ChatResponse{}
-	res := responses[0]
+	chatResponse := &ChatResponse{
+		Usage: parseTokenUsage(resp.GetMetadata()),
+	}
+	res := resp.GetPredictions()[0]
 	value := res.GetStructValue().AsMap()
 	candidates, ok := value["candidates"].([]interface{})
 	if !ok {
@@ -228,9 +625,77 @@ func (c *PaLMClient) CreateChat(ctx context.Context, r *ChatRequest) (*ChatRespo
 			Content: content,
 		})
 	}
+	safetyAttributes, _ := value["safetyAttributes"].([]interface{})
+	for i := range chatResponse.Candidates {
+		var attrs *SafetyAttributes
+		if i < len(safetyAttributes) {
+			attrs = parseSafetyAttributes(safetyAttributes[i])
+		}
+		chatResponse.SafetyAttributes = append(chatResponse.SafetyAttributes, attrs)
+	}
+	if len(chatResponse.Candidates) == 0 {
+		// A blocked response has no candidates, only its safety attributes.
+		for _, a := range safetyAttributes {
+			if attrs := parseSafetyAttributes(a); attrs.blocked() {
+				return nil, attrs.filteredError()
+			}
+		}
+	}
 	return chatResponse, nil
 }
 
+// parseTokenUsage extracts the token counts from the metadata of a
+// prediction response.
+func parseTokenUsage(metadata *structpb.Value) TokenUsage {
+	tokenMetadata, _ := metadata.GetStructValue().AsMap()["tokenMetadata"].(map[string]interface{})
+	return TokenUsage{
+		PromptTokens:     totalTokens(tokenMetadata, "inputTokenCount"),
+		CompletionTokens: totalTokens(tokenMetadata, "outputTokenCount"),
+	}
+}
+
+func totalTokens(tokenMetadata map[string]interface{}, key string) int {
+	count, _ := tokenMetadata[key].(map[string]interface{})
+	total, _ := count["totalTokens"].(float64)
+	return int(total)
+}
+
+// blocked reports whether the response was blocked for safety reasons.
+func (a *SafetyAttributes) blocked() bool {
+	return a != nil && a.Blocked
+}
+
+// filteredError returns the ErrContentFiltered error of a blocked response.
+func (a *SafetyAttributes) filteredError() error {
+	if len(a.Categories) == 0 {
+		return ErrContentFiltered
+	}
+	return fmt.Errorf("%w: blocked categories %s", ErrContentFiltered, strings.Join(a.Categories, ", "))
+}
+
+// parseSafetyAttributes converts the safety attributes of a prediction.
+func parseSafetyAttributes(value interface{}) *SafetyAttributes {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	attrs := &SafetyAttributes{}
+	attrs.Blocked, _ = m["blocked"].(bool)
+	categories, _ := m["categories"].([]interface{})
+	for _, c := range categories {
+		if category, ok := c.(string); ok {
+			attrs.Categories = append(attrs.Categories, category)
+		}
+	}
+	scores, _ := m["scores"].([]interface{})
+	for _, s := range scores {
+		if score, ok := s.(float64); ok {
+			attrs.Scores = append(attrs.Scores, score)
+		}
+	}
+	return attrs
+}
+
 func mergeParams(defaultParams, params map[string]interface{}) *structpb.Struct {
 	mergedParams := cloneDefaultParameters()
 	for paramKey, paramValue := range params {
@@ -240,7 +705,13 @@ func mergeParams(defaultParams, params map[string]interface{}) *structpb.Struct
 				mergedParams[paramKey] = value
 			}
 		case int:
+			if value != 0 {
+				mergedParams[paramKey] = value
+			}
 		case int32:
+			if value != 0 {
+				mergedParams[paramKey] = value
+			}
 		case int64:
 			if value != 0 {
 				mergedParams[paramKey] = value
@@ -277,17 +748,29 @@ func convertArray(value []string) interface{} {
 	return newArray
 }
 
-func (c *PaLMClient) batchPredict(ctx context.Context, model string, prompts []string, params map[string]interface{}) ([]*structpb.Value, error) { //nolint:lll
-	mergedParams := mergeParams(defaultParameters, params)
-	instances := []*structpb.Value{}
+// contentInstances returns one prediction instance per prompt.
+func contentInstances(prompts []string) []map[string]interface{} {
+	instances := make([]map[string]interface{}, 0, len(prompts))
 	for _, prompt := range prompts {
-		content, _ := structpb.NewStruct(map[string]interface{}{
+		instances = append(instances, map[string]interface{}{
 			"content": prompt,
 		})
-		instances = append(instances, structpb.NewStructValue(content))
 	}
-	resp, err := c.client.Predict(ctx, &aiplatformpb.PredictRequest{
-		Endpoint:   c.projectLocationPublisherModelPath(c.projectID, "us-central1", "google", model),
+	return instances
+}
+
+func (c *PaLMClient) batchPredict(ctx context.Context, model string, instanceMaps []map[string]interface{}, params map[string]interface{}) (*aiplatformpb.PredictResponse, error) { //nolint:lll
+	mergedParams := mergeParams(defaultParameters, params)
+	instances := []*structpb.Value{}
+	for _, instanceMap := range instanceMaps {
+		instance, err := structpb.NewStruct(instanceMap)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, structpb.NewStructValue(instance))
+	}
+	resp, err := c.predict(ctx, &aiplatformpb.PredictRequest{
+		Endpoint:   c.projectLocationPublisherModelPath(c.projectID, c.location, defaultPublisher, model),
 		Instances:  instances,
 		Parameters: structpb.NewStructValue(mergedParams),
 	})
@@ -297,36 +780,109 @@ func (c *PaLMClient) batchPredict(ctx context.Context, model string, prompts []s
 	if len(resp.GetPredictions()) == 0 {
 		return nil, ErrEmptyResponse
 	}
-	return resp.GetPredictions(), nil
+	return resp, nil
 }
 
-func (c *PaLMClient) chat(ctx context.Context, r *ChatRequest) ([]*structpb.Value, error) {
-	params := map[string]interface{}{
-		"temperature": r.Temperature,
-		"top_p":       r.TopP,
-		"top_k":       r.TopK,
+// CountTokens counts the tokens of text for the text model with the Vertex AI
+// countTokens API.
+func (c *PaLMClient) CountTokens(ctx context.Context, text string) (int, error) {
+	client, err := c.tokenClient()
+	if err != nil {
+		return 0, err
 	}
-	mergedParams := mergeParams(defaultParameters, params)
-	messages := []interface{}{}
-	for _, msg := range r.Messages {
-		msgMap := map[string]interface{}{
-			"author":  msg.Author,
-			"content": msg.Content,
-		}
-		messages = append(messages, msgMap)
+	instance, err := structpb.NewStruct(contentInstances([]string{text})[0])
+	if err != nil {
+		return 0, err
 	}
-	instance, err := structpb.NewStruct(map[string]interface{}{
-		"context":  r.Context,
-		"messages": messages,
+	resp, err := client.CountTokens(ctx, &aiplatformpb.CountTokensRequest{
+		Endpoint:  c.projectLocationPublisherModelPath(c.projectID, c.location, defaultPublisher, c.textModel),
+		Instances: []*structpb.Value{structpb.NewStructValue(instance)},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(resp.GetTotalTokens()), nil
+}
+
+// tokenClient returns the client of the LLM utility API, creating it on first
+// use as most callers never count tokens remotely.
+func (c *PaLMClient) tokenClient() (tokenClient, error) {
+	c.tokensOnce.Do(func() {
+		if c.tokens != nil {
+			return
+		}
+		client, err := aiplatform.NewLlmUtilityClient(context.Background(), c.apiOptions...)
+		if err != nil {
+			c.tokensErr = err
+			return
+		}
+		c.tokens = client
 	})
+	return c.tokens, c.tokensErr
+}
+
+// predict issues a prediction request, retrying it with exponential backoff
+// and jitter while it fails with a retryable status.
+func (c *PaLMClient) predict(ctx context.Context, req *aiplatformpb.PredictRequest) (*aiplatformpb.PredictResponse, error) { //nolint:lll
+	for attempt := 0; ; attempt++ {
+		resp, err := c.client.Predict(c.labeled(ctx), req)
+		c.logCall(req, resp, err)
+		if err == nil || attempt >= c.maxRetries || !isRetryable(err) {
+			return resp, err
+		}
+		timer := time.NewTimer(c.retryDelay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// labeled returns the context of a prediction request, carrying the labels of
+// WithRequestLabels in its outgoing metadata.
+func (c *PaLMClient) labeled(ctx context.Context) context.Context {
+	if len(c.labels) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, c.labels...)
+}
+
+// retryDelay returns the delay before the retry following the given attempt:
+// the base delay doubled for every attempt, capped at maxRetryDelay, of which
+// a random half is waited.
+func (c *PaLMClient) retryDelay(attempt int) time.Duration {
+	delay := maxRetryDelay
+	if attempt < 32 && c.retryBaseDelay<<attempt < maxRetryDelay { //nolint:gomnd
+		delay = c.retryBaseDelay << attempt
+	}
+	half := delay / 2                      //nolint:gomnd
+	jitter := rand.Int63n(int64(half) + 1) //nolint:gosec
+	return half + time.Duration(jitter)
+}
+
+// isRetryable reports whether a failed request may succeed when retried.
+func isRetryable(err error) bool {
+	switch status.Code(err) { //nolint:exhaustive
+	case codes.ResourceExhausted, codes.Unavailable:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *PaLMClient) chat(ctx context.Context, r *ChatRequest) (*aiplatformpb.PredictResponse, error) {
+	mergedParams := mergeParams(defaultParameters, chatParams(r))
+	instance, err := structpb.NewStruct(chatInstance(r))
 	if err != nil {
 		return nil, err
 	}
 	instances := []*structpb.Value{
 		structpb.NewStructValue(instance),
 	}
-	resp, err := c.client.Predict(ctx, &aiplatformpb.PredictRequest{
-		Endpoint:   c.projectLocationPublisherModelPath(c.projectID, "us-central1", "google", ChatModelName),
+	resp, err := c.predict(ctx, &aiplatformpb.PredictRequest{
+		Endpoint:   c.projectLocationPublisherModelPath(c.projectID, c.location, defaultPublisher, ChatModelName),
 		Instances:  instances,
 		Parameters: structpb.NewStructValue(mergedParams),
 	})
@@ -336,7 +892,318 @@ func (c *PaLMClient) chat(ctx context.Context, r *ChatRequest) ([]*structpb.Valu
 	if len(resp.GetPredictions()) == 0 {
 		return nil, ErrEmptyResponse
 	}
-	return resp.GetPredictions(), nil
+	return resp, nil
+}
+
+// chatStream issues a server-streaming chat prediction, invoking
+// r.StreamingFunc for every chunk of the first candidate and returning the
+// candidates assembled from their chunks, by their index in the stream
+// responses, once the stream is exhausted. A response blocked by the safety
+// filters without content returns ErrContentFiltered.
+func (c *PaLMClient) chatStream(ctx context.Context, r *ChatRequest) (*ChatResponse, error) {
+	mergedParams := mergeParams(defaultParameters, chatParams(r))
+	req := &aiplatformpb.StreamingPredictRequest{
+		Endpoint:   c.projectLocationPublisherModelPath(c.projectID, c.location, defaultPublisher, ChatModelName),
+		Inputs:     []*aiplatformpb.Tensor{toTensor(chatInstance(r))},
+		Parameters: toTensor(mergedParams.AsMap()),
+	}
+	stream, err := c.client.ServerStreamingPredict(c.labeled(ctx), req)
+	if err != nil {
+		c.logCall(req, nil, err)
+		return nil, err
+	}
+	stream = c.logStream(req, stream)
+
+	var (
+		candidates []ChatMessage
+		safety     []*SafetyAttributes
+	)
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, output := range resp.GetOutputs() {
+			for i, attrs := range output.GetStructVal()["safetyAttributes"].GetListVal() {
+				for len(safety) <= i {
+					safety = append(safety, nil)
+				}
+				if a := tensorSafetyAttributes(attrs); a != nil {
+					safety[i] = a
+				}
+			}
+			for i, candidate := range output.GetStructVal()["candidates"].GetListVal() {
+				for len(candidates) <= i {
+					candidates = append(candidates, ChatMessage{Author: "bot"})
+				}
+				fields := candidate.GetStructVal()
+				if a := tensorString(fields["author"]); a != "" {
+					candidates[i].Author = a
+				}
+				chunk := tensorString(fields["content"])
+				if chunk == "" {
+					continue
+				}
+				candidates[i].Content += chunk
+				if i > 0 {
+					continue
+				}
+				if err := r.StreamingFunc(ctx, []byte(chunk)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if !hasContent(candidates) {
+		// A blocked response has no content, only its safety attributes.
+		for _, attrs := range safety {
+			if attrs.blocked() {
+				return nil, attrs.filteredError()
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, ErrEmptyResponse
+	}
+
+	chatResponse := &ChatResponse{
+		Candidates:       candidates,
+		SafetyAttributes: make([]*SafetyAttributes, len(candidates)),
+	}
+	copy(chatResponse.SafetyAttributes, safety)
+	return chatResponse, nil
+}
+
+// hasContent reports whether any of the candidates has content.
+func hasContent(candidates []ChatMessage) bool {
+	for _, candidate := range candidates {
+		if candidate.Content != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// completionStream issues a server-streaming prediction of the completion of
+// the single prompt of r, invoking r.StreamingFunc for every chunk and
+// returning the assembled completion once the stream is exhausted. It returns
+// errStreamingUnsupported if the text model rejected the streaming request,
+// and ErrContentFiltered for a completion blocked by the safety filters.
+func (c *PaLMClient) completionStream(ctx context.Context, r *CompletionRequest) (*CompletionResponse, error) {
+	mergedParams := mergeParams(defaultParameters, completionParams(r))
+	req := &aiplatformpb.StreamingPredictRequest{
+		Endpoint:   c.projectLocationPublisherModelPath(c.projectID, c.location, defaultPublisher, c.textModel),
+		Inputs:     []*aiplatformpb.Tensor{toTensor(contentInstances(r.Prompts)[0])},
+		Parameters: toTensor(mergedParams.AsMap()),
+	}
+	stream, err := c.client.ServerStreamingPredict(c.labeled(ctx), req)
+	if err != nil {
+		c.logCall(req, nil, err)
+		return nil, streamError(err)
+	}
+	stream = c.logStream(req, stream)
+
+	var (
+		content strings.Builder
+		safety  *SafetyAttributes
+		found   bool
+	)
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if !found {
+				// The rejection of a streaming request surfaces on its first
+				// response.
+				return nil, streamError(err)
+			}
+			return nil, err
+		}
+		for _, output := range resp.GetOutputs() {
+			if attrs := tensorSafetyAttributes(output.GetStructVal()["safetyAttributes"]); attrs != nil {
+				safety = attrs
+			}
+			chunk := tensorString(output.GetStructVal()["content"])
+			found = true
+			if chunk == "" {
+				continue
+			}
+			content.WriteString(chunk)
+			if err := r.StreamingFunc(ctx, []byte(chunk)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if content.Len() == 0 && safety.blocked() {
+		return nil, safety.filteredError()
+	}
+	if !found {
+		return nil, ErrEmptyResponse
+	}
+
+	return &CompletionResponse{
+		Completions: []*Completion{{Text: content.String(), SafetyAttributes: safety}},
+	}, nil
+}
+
+// errStreamingUnsupported is returned by completionStream when the model
+// doesn't support streaming.
+var errStreamingUnsupported = errors.New("streaming unsupported")
+
+// streamError returns errStreamingUnsupported for the Unimplemented errors the
+// API returns when a model doesn't support streaming, and err otherwise. The
+// invalid requests aren't retried without streaming, failing the same way.
+func streamError(err error) error {
+	if status.Code(err) == codes.Unimplemented {
+		return fmt.Errorf("%w: %w", errStreamingUnsupported, err)
+	}
+	return err
+}
+
+// completionParams returns the request parameters of a completion request.
+func completionParams(r *CompletionRequest) map[string]interface{} {
+	return map[string]interface{}{
+		"maxOutputTokens": r.MaxTokens,
+		"temperature":     r.Temperature,
+		"topP":            r.TopP,
+		"topK":            r.TopK,
+		"stopSequences":   convertArray(r.StopSequences),
+		"seed":            r.Seed,
+	}
+}
+
+// chatParams returns the request parameters of a chat request.
+func chatParams(r *ChatRequest) map[string]interface{} {
+	return map[string]interface{}{
+		"maxOutputTokens": r.MaxTokens,
+		"temperature":     r.Temperature,
+		"topP":            r.TopP,
+		"topK":            r.TopK,
+		"stopSequences":   convertArray(r.StopSequences),
+		"candidateCount":  r.CandidateCount,
+		"seed":            r.Seed,
+	}
+}
+
+// chatInstance returns the prediction instance of a chat request.
+func chatInstance(r *ChatRequest) map[string]interface{} {
+	messages := []interface{}{}
+	for _, msg := range r.Messages {
+		msgMap := map[string]interface{}{
+			"author":  msg.Author,
+			"content": msg.Content,
+		}
+		messages = append(messages, msgMap)
+	}
+	return map[string]interface{}{
+		"context":  r.Context,
+		"messages": messages,
+	}
+}
+
+// toTensor converts a generic value into the tensor representation used by
+// the streaming prediction endpoints.
+func toTensor(value interface{}) *aiplatformpb.Tensor {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		fields := make(map[string]*aiplatformpb.Tensor, len(v))
+		for key, val := range v {
+			fields[key] = toTensor(val)
+		}
+		return &aiplatformpb.Tensor{StructVal: fields}
+	case []interface{}:
+		list := make([]*aiplatformpb.Tensor, 0, len(v))
+		for _, val := range v {
+			list = append(list, toTensor(val))
+		}
+		return &aiplatformpb.Tensor{ListVal: list}
+	case string:
+		return &aiplatformpb.Tensor{Dtype: aiplatformpb.Tensor_STRING, StringVal: []string{v}}
+	case bool:
+		return &aiplatformpb.Tensor{Dtype: aiplatformpb.Tensor_BOOL, BoolVal: []bool{v}}
+	case int:
+		return &aiplatformpb.Tensor{Dtype: aiplatformpb.Tensor_INT64, Int64Val: []int64{int64(v)}}
+	case int32:
+		return &aiplatformpb.Tensor{Dtype: aiplatformpb.Tensor_INT32, IntVal: []int32{v}}
+	case int64:
+		return &aiplatformpb.Tensor{Dtype: aiplatformpb.Tensor_INT64, Int64Val: []int64{v}}
+	case float64:
+		return &aiplatformpb.Tensor{Dtype: aiplatformpb.Tensor_DOUBLE, DoubleVal: []float64{v}}
+	case float32:
+		return &aiplatformpb.Tensor{Dtype: aiplatformpb.Tensor_FLOAT, FloatVal: []float32{v}}
+	default:
+		return &aiplatformpb.Tensor{}
+	}
+}
+
+// tensorString returns the first string value held by a tensor, if any.
+func tensorString(t *aiplatformpb.Tensor) string {
+	if values := t.GetStringVal(); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// tensorSafetyAttributes converts the safety attributes of a streaming
+// prediction, nil if unset.
+func tensorSafetyAttributes(t *aiplatformpb.Tensor) *SafetyAttributes {
+	fields := t.GetStructVal()
+	if fields == nil {
+		return nil
+	}
+	attrs := &SafetyAttributes{
+		Categories: tensorStrings(fields["categories"]),
+		Scores:     tensorFloats(fields["scores"]),
+	}
+	if blocked := fields["blocked"].GetBoolVal(); len(blocked) > 0 {
+		attrs.Blocked = blocked[0]
+	}
+	return attrs
+}
+
+// tensorStrings returns the string values held by a tensor or by the tensors
+// of its list.
+func tensorStrings(t *aiplatformpb.Tensor) []string {
+	values := append([]string(nil), t.GetStringVal()...)
+	for _, v := range t.GetListVal() {
+		values = append(values, v.GetStringVal()...)
+	}
+	return values
+}
+
+// tensorFloats returns the floating point values held by a tensor or by the
+// tensors of its list.
+func tensorFloats(t *aiplatformpb.Tensor) []float64 {
+	var values []float64
+	for _, v := range append([]*aiplatformpb.Tensor{t}, t.GetListVal()...) {
+		values = append(values, v.GetDoubleVal()...)
+		for _, f := range v.GetFloatVal() {
+			values = append(values, float64(f))
+		}
+	}
+	return values
+}
+
+// apiEndpoint returns the regional API endpoint of the given location.
+func apiEndpoint(location string) string {
+	return location + "-aiplatform.googleapis.com:443"
+}
+
+// grpcEndpoint returns the host:port of the gRPC API: the endpoint set with
+// WithEndpoint without its scheme, or the regional endpoint of the location.
+func grpcEndpoint(location, endpoint string) string {
+	if endpoint == "" {
+		return apiEndpoint(location)
+	}
+	if _, host, ok := strings.Cut(endpoint, "://"); ok {
+		return strings.TrimSuffix(host, "/")
+	}
+	return endpoint
 }
 
 func (c *PaLMClient) projectLocationPublisherModelPath(projectID, location, publisher, model string) string {
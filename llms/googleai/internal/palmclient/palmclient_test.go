@@ -0,0 +1,822 @@
+package palmclient
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/aiplatform/apiv1/aiplatformpb"
+	"github.com/googleapis/gax-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type fakePredictionClient struct {
+	requests        []*aiplatformpb.PredictRequest
+	outgoing        []metadata.MD
+	streamRequests  []*aiplatformpb.StreamingPredictRequest
+	predictions     []map[string]interface{}
+	metadata        map[string]interface{}
+	streamResponses []*aiplatformpb.StreamingPredictResponse
+	streamErr       error
+	err             error
+
+	// errs are returned by the first requests, one per request, before
+	// falling back to err.
+	errs []error
+
+	// predictFunc, if set, computes the predictions of each request instead
+	// of the fixed predictions.
+	predictFunc func(req *aiplatformpb.PredictRequest) []map[string]interface{}
+}
+
+func (f *fakePredictionClient) Predict(ctx context.Context, req *aiplatformpb.PredictRequest, _ ...gax.CallOption) (*aiplatformpb.PredictResponse, error) { //nolint:lll
+	f.requests = append(f.requests, req)
+	md, _ := metadata.FromOutgoingContext(ctx)
+	f.outgoing = append(f.outgoing, md)
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	resp := &aiplatformpb.PredictResponse{}
+	predictions := f.predictions
+	if f.predictFunc != nil {
+		predictions = f.predictFunc(req)
+	}
+	for _, p := range predictions {
+		v, err := structpb.NewValue(p)
+		if err != nil {
+			return nil, err
+		}
+		resp.Predictions = append(resp.Predictions, v)
+	}
+	if f.metadata != nil {
+		v, err := structpb.NewValue(f.metadata)
+		if err != nil {
+			return nil, err
+		}
+		resp.Metadata = v
+	}
+	return resp, nil
+}
+
+func (f *fakePredictionClient) ServerStreamingPredict(_ context.Context, req *aiplatformpb.StreamingPredictRequest, _ ...gax.CallOption) (aiplatformpb.PredictionService_ServerStreamingPredictClient, error) { //nolint:lll
+	f.streamRequests = append(f.streamRequests, req)
+	if f.streamErr != nil {
+		return nil, f.streamErr
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &fakeStream{responses: f.streamResponses}, nil
+}
+
+type fakeStream struct {
+	grpc.ClientStream
+	responses []*aiplatformpb.StreamingPredictResponse
+}
+
+func (s *fakeStream) Recv() (*aiplatformpb.StreamingPredictResponse, error) {
+	if len(s.responses) == 0 {
+		return nil, io.EOF
+	}
+	resp := s.responses[0]
+	s.responses = s.responses[1:]
+	return resp, nil
+}
+
+type fakeTokenClient struct {
+	requests []*aiplatformpb.CountTokensRequest
+	err      error
+}
+
+func (f *fakeTokenClient) CountTokens(_ context.Context, req *aiplatformpb.CountTokensRequest, _ ...gax.CallOption) (*aiplatformpb.CountTokensResponse, error) { //nolint:lll
+	f.requests = append(f.requests, req)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &aiplatformpb.CountTokensResponse{TotalTokens: 7}, nil
+}
+
+func newTestClient(f *fakePredictionClient, opts ...Option) *PaLMClient {
+	c := &PaLMClient{
+		client:             f,
+		projectID:          "test-project",
+		location:           defaultLocation,
+		textModel:          TextModelName,
+		embeddingBatchSize: defaultEmbeddingBatchSize,
+		maxRetries:         DefaultMaxRetries,
+		retryBaseDelay:     time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func TestCreateCompletionSamplingParameters(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakePredictionClient{
+		predictions: []map[string]interface{}{{"content": "hello"}},
+	}
+	client := newTestClient(fake)
+
+	resp, err := client.CreateCompletion(context.Background(), &CompletionRequest{
+		Prompts:       []string{"hi"},
+		MaxTokens:     64,
+		Temperature:   0.4,
+		TopP:          0.9,
+		TopK:          20,
+		StopSequences: []string{"\n\n"},
+		Seed:          42,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Completions, 1)
+	assert.Equal(t, "hello", resp.Completions[0].Text)
+
+	require.Len(t, fake.requests, 1)
+	params := fake.requests[0].GetParameters().GetStructValue().AsMap()
+	assert.InDelta(t, 0.9, params["topP"], 1e-9)
+	assert.InDelta(t, 20, params["topK"], 1e-9)
+	assert.InDelta(t, 64, params["maxOutputTokens"], 1e-9)
+	assert.InDelta(t, 0.4, params["temperature"], 1e-9)
+	assert.Equal(t, []interface{}{"\n\n"}, params["stopSequences"])
+	assert.InDelta(t, 42, params["seed"], 1e-9)
+}
+
+func TestCreateChatSamplingParameters(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakePredictionClient{
+		predictions: []map[string]interface{}{{
+			"candidates": []interface{}{
+				map[string]interface{}{"author": "bot", "content": "hello"},
+			},
+		}},
+	}
+	client := newTestClient(fake)
+
+	resp, err := client.CreateChat(context.Background(), &ChatRequest{
+		Messages:      []*ChatMessage{{Author: "user", Content: "hi"}},
+		TopP:          0.7,
+		TopK:          10,
+		StopSequences: []string{"END"},
+		Seed:          7,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Candidates, 1)
+	assert.Equal(t, "hello", resp.Candidates[0].Content)
+
+	require.Len(t, fake.requests, 1)
+	params := fake.requests[0].GetParameters().GetStructValue().AsMap()
+	assert.InDelta(t, 0.7, params["topP"], 1e-9)
+	assert.InDelta(t, 10, params["topK"], 1e-9)
+	assert.Equal(t, []interface{}{"END"}, params["stopSequences"])
+	assert.InDelta(t, 7, params["seed"], 1e-9)
+}
+
+func TestCreateCompletionDefaultParameters(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakePredictionClient{
+		predictions: []map[string]interface{}{{"content": "hello"}},
+	}
+	client := newTestClient(fake)
+
+	_, err := client.CreateCompletion(context.Background(), &CompletionRequest{
+		Prompts: []string{"hi"},
+	})
+	require.NoError(t, err)
+
+	params := fake.requests[0].GetParameters().GetStructValue().AsMap()
+	assert.InDelta(t, 0.8, params["topP"], 1e-9)
+	assert.InDelta(t, 40, params["topK"], 1e-9)
+	assert.NotContains(t, params, "top_p")
+	assert.NotContains(t, params, "top_k")
+	assert.NotContains(t, params, "seed")
+}
+
+func TestCreateCompletionTokenUsage(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakePredictionClient{
+		predictions: []map[string]interface{}{{
+			"content": "hello",
+			"safetyAttributes": map[string]interface{}{
+				"blocked":    false,
+				"categories": []interface{}{"Toxic"},
+				"scores":     []interface{}{0.1},
+			},
+		}},
+		metadata: map[string]interface{}{
+			"tokenMetadata": map[string]interface{}{
+				"inputTokenCount":  map[string]interface{}{"totalTokens": 3, "totalBillableCharacters": 9},
+				"outputTokenCount": map[string]interface{}{"totalTokens": 5, "totalBillableCharacters": 20},
+			},
+		},
+	}
+	client := newTestClient(fake)
+
+	resp, err := client.CreateCompletion(context.Background(), &CompletionRequest{
+		Prompts: []string{"hi"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, TokenUsage{PromptTokens: 3, CompletionTokens: 5}, resp.Usage)
+	require.NotNil(t, resp.Completions[0].SafetyAttributes)
+	assert.Equal(t, []string{"Toxic"}, resp.Completions[0].SafetyAttributes.Categories)
+	assert.Equal(t, []float64{0.1}, resp.Completions[0].SafetyAttributes.Scores)
+}
+
+func TestCreateChatCandidateCount(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakePredictionClient{
+		predictions: []map[string]interface{}{{
+			"candidates": []interface{}{
+				map[string]interface{}{"author": "bot", "content": "one"},
+				map[string]interface{}{"author": "bot", "content": "two"},
+				map[string]interface{}{"author": "bot", "content": "three"},
+			},
+		}},
+	}
+	client := newTestClient(fake)
+
+	resp, err := client.CreateChat(context.Background(), &ChatRequest{
+		Messages:       []*ChatMessage{{Author: "user", Content: "hi"}},
+		CandidateCount: 3,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Candidates, 3)
+	require.Len(t, resp.SafetyAttributes, 3)
+	assert.Equal(t, "three", resp.Candidates[2].Content)
+
+	params := fake.requests[0].GetParameters().GetStructValue().AsMap()
+	assert.InDelta(t, 3, params["candidateCount"], 1e-9)
+
+	_, err = client.CreateChat(context.Background(), &ChatRequest{
+		Messages: []*ChatMessage{{Author: "user", Content: "hi"}},
+	})
+	require.NoError(t, err)
+	params = fake.requests[1].GetParameters().GetStructValue().AsMap()
+	assert.NotContains(t, params, "candidateCount")
+}
+
+func TestCreateCompletionTextModel(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakePredictionClient{
+		predictions: []map[string]interface{}{{"content": "hello"}},
+	}
+	client := newTestClient(fake, WithTextModel("text-bison@002"))
+
+	_, err := client.CreateCompletion(context.Background(), &CompletionRequest{
+		Prompts: []string{"hi"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t,
+		"projects/test-project/locations/us-central1/publishers/google/models/text-bison@002",
+		fake.requests[0].GetEndpoint())
+}
+
+func TestCreateChatLocation(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakePredictionClient{
+		predictions: []map[string]interface{}{{
+			"candidates": []interface{}{
+				map[string]interface{}{"author": "bot", "content": "hello"},
+			},
+		}},
+	}
+	client := newTestClient(fake, WithLocation("europe-west4"))
+
+	_, err := client.CreateChat(context.Background(), &ChatRequest{
+		Messages: []*ChatMessage{{Author: "user", Content: "hi"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t,
+		"projects/test-project/locations/europe-west4/publishers/google/models/chat-bison",
+		fake.requests[0].GetEndpoint())
+	assert.Equal(t, "europe-west4-aiplatform.googleapis.com:443", apiEndpoint("europe-west4"))
+}
+
+func TestEndpointOverride(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "europe-west4-aiplatform.googleapis.com:443", grpcEndpoint("europe-west4", ""))
+	assert.Equal(t, "localhost:8080", grpcEndpoint("europe-west4", "localhost:8080"))
+	assert.Equal(t, "localhost:8080", grpcEndpoint("europe-west4", "http://localhost:8080/"))
+
+	assert.Equal(t, "https://europe-west4-aiplatform.googleapis.com", restBaseURL("europe-west4", ""))
+	assert.Equal(t, "https://psc.example.com", restBaseURL("europe-west4", "psc.example.com"))
+	assert.Equal(t, "http://127.0.0.1:8080", restBaseURL("europe-west4", "http://127.0.0.1:8080/"))
+}
+
+func TestCreateEmbeddingTaskType(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakePredictionClient{
+		predictions: []map[string]interface{}{
+			{"embeddings": map[string]interface{}{"values": []interface{}{0.1, 0.2}}},
+		},
+	}
+	client := newTestClient(fake)
+
+	embeddings, err := client.CreateEmbedding(context.Background(), &EmbeddingRequest{
+		Input:    []string{"hello"},
+		TaskType: "RETRIEVAL_DOCUMENT",
+		Title:    "greeting",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{0.1, 0.2}}, embeddings)
+
+	instance := fake.requests[0].GetInstances()[0].GetStructValue().AsMap()
+	assert.Equal(t, "hello", instance["content"])
+	assert.Equal(t, "RETRIEVAL_DOCUMENT", instance["task_type"])
+	assert.Equal(t, "greeting", instance["title"])
+
+	_, err = client.CreateEmbedding(context.Background(), &EmbeddingRequest{
+		Input: []string{"hello"},
+	})
+	require.NoError(t, err)
+	instance = fake.requests[1].GetInstances()[0].GetStructValue().AsMap()
+	assert.Equal(t, map[string]interface{}{"content": "hello"}, instance)
+}
+
+func TestCreateEmbeddingOutputDimensionality(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakePredictionClient{
+		predictions: []map[string]interface{}{
+			{"embeddings": map[string]interface{}{"values": []interface{}{0.1, 0.2}}},
+		},
+	}
+	client := newTestClient(fake)
+
+	_, err := client.CreateEmbedding(context.Background(), &EmbeddingRequest{
+		Input:                []string{"hello"},
+		OutputDimensionality: 256,
+	})
+	require.NoError(t, err)
+	params := fake.requests[0].GetParameters().GetStructValue().AsMap()
+	assert.Equal(t, float64(256), params["outputDimensionality"])
+
+	_, err = client.CreateEmbedding(context.Background(), &EmbeddingRequest{
+		Input: []string{"hello"},
+	})
+	require.NoError(t, err)
+	assert.NotContains(t, fake.requests[1].GetParameters().GetStructValue().AsMap(), "outputDimensionality")
+}
+
+func TestCreateEmbeddingPartial(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	fake := &fakePredictionClient{
+		predictFunc: func(req *aiplatformpb.PredictRequest) []map[string]interface{} {
+			calls++
+			predictions := []map[string]interface{}{}
+			for _, instance := range req.GetInstances() {
+				content := instance.GetStructValue().AsMap()["content"]
+				if content == "flaky" && calls == 1 || content == "broken" {
+					predictions = append(predictions, map[string]interface{}{})
+					continue
+				}
+				predictions = append(predictions, map[string]interface{}{
+					"embeddings": map[string]interface{}{"values": []interface{}{1.0}},
+				})
+			}
+			return predictions
+		},
+	}
+	client := newTestClient(fake)
+
+	embeddings, missing, err := client.CreateEmbeddingPartial(context.Background(), &EmbeddingRequest{
+		Input: []string{"ok", "flaky", "broken", "ok"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, missing)
+	assert.Equal(t, [][]float32{{1}, {1}, nil, {1}}, embeddings)
+
+	require.Len(t, fake.requests, 2)
+	assert.Len(t, fake.requests[1].GetInstances(), 2)
+}
+
+func TestCreateEmbeddingBatches(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakePredictionClient{
+		predictFunc: func(req *aiplatformpb.PredictRequest) []map[string]interface{} {
+			predictions := []map[string]interface{}{}
+			for _, instance := range req.GetInstances() {
+				content, _ := instance.GetStructValue().AsMap()["content"].(string)
+				predictions = append(predictions, map[string]interface{}{
+					"embeddings": map[string]interface{}{"values": []interface{}{float64(len(content))}},
+				})
+			}
+			return predictions
+		},
+	}
+	client := newTestClient(fake)
+
+	input := []string{}
+	expected := [][]float32{}
+	for i := 1; i <= 12; i++ {
+		input = append(input, strings.Repeat("x", i))
+		expected = append(expected, []float32{float32(i)})
+	}
+	embeddings, err := client.CreateEmbedding(context.Background(), &EmbeddingRequest{Input: input})
+	require.NoError(t, err)
+	assert.Equal(t, expected, embeddings)
+
+	require.Len(t, fake.requests, 3)
+	assert.Len(t, fake.requests[0].GetInstances(), 5)
+	assert.Len(t, fake.requests[1].GetInstances(), 5)
+	assert.Len(t, fake.requests[2].GetInstances(), 2)
+}
+
+func TestPredictRetries(t *testing.T) {
+	t.Parallel()
+
+	completion := []map[string]interface{}{{"content": "hello"}}
+
+	t.Run("retryable", func(t *testing.T) {
+		t.Parallel()
+		fake := &fakePredictionClient{
+			predictions: completion,
+			errs: []error{
+				status.Error(codes.ResourceExhausted, "quota exceeded"),
+				status.Error(codes.Unavailable, "try again"),
+			},
+		}
+		client := newTestClient(fake)
+		resp, err := client.CreateCompletion(context.Background(), &CompletionRequest{Prompts: []string{"hi"}})
+		require.NoError(t, err)
+		assert.Equal(t, "hello", resp.Completions[0].Text)
+		assert.Len(t, fake.requests, 3)
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		t.Parallel()
+		fake := &fakePredictionClient{err: status.Error(codes.Unavailable, "down")}
+		client := newTestClient(fake, WithMaxRetries(2))
+		_, err := client.CreateChat(context.Background(), &ChatRequest{})
+		require.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Len(t, fake.requests, 3)
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		t.Parallel()
+		fake := &fakePredictionClient{err: status.Error(codes.InvalidArgument, "bad request")}
+		client := newTestClient(fake)
+		_, err := client.CreateEmbedding(context.Background(), &EmbeddingRequest{Input: []string{"a"}})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Len(t, fake.requests, 1)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		t.Parallel()
+		fake := &fakePredictionClient{err: status.Error(codes.Unavailable, "down")}
+		client := newTestClient(fake)
+		client.retryBaseDelay = time.Hour
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := client.CreateCompletion(ctx, &CompletionRequest{Prompts: []string{"hi"}})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Len(t, fake.requests, 1)
+	})
+}
+
+func TestCountTokens(t *testing.T) {
+	t.Parallel()
+
+	tokens := &fakeTokenClient{}
+	c := newTestClient(&fakePredictionClient{}, WithTextModel("text-bison@002"))
+	c.tokens = tokens
+
+	n, err := c.CountTokens(context.Background(), "hello world")
+	require.NoError(t, err)
+	assert.Equal(t, 7, n)
+	require.Len(t, tokens.requests, 1)
+	assert.Equal(t, "projects/test-project/locations/us-central1/publishers/google/models/text-bison@002", tokens.requests[0].GetEndpoint())
+	assert.Equal(t, "hello world", tokens.requests[0].GetInstances()[0].GetStructValue().GetFields()["content"].GetStringValue())
+
+	tokens.err = status.Error(codes.Unavailable, "unavailable")
+	_, err = c.CountTokens(context.Background(), "hello world")
+	require.Error(t, err)
+}
+
+func TestContentFiltered(t *testing.T) {
+	t.Parallel()
+
+	blocked := map[string]interface{}{
+		"blocked":    true,
+		"categories": []interface{}{"Derogatory", "Toxic"},
+	}
+
+	client := newTestClient(&fakePredictionClient{
+		predictions: []map[string]interface{}{{"safetyAttributes": blocked}},
+	})
+	_, err := client.CreateCompletion(context.Background(), &CompletionRequest{Prompts: []string{"hi"}})
+	require.ErrorIs(t, err, ErrContentFiltered)
+	assert.Contains(t, err.Error(), "Derogatory, Toxic")
+
+	client = newTestClient(&fakePredictionClient{
+		predictions: []map[string]interface{}{{
+			"candidates":       []interface{}{},
+			"safetyAttributes": []interface{}{blocked},
+		}},
+	})
+	_, err = client.CreateChat(context.Background(), &ChatRequest{
+		Messages: []*ChatMessage{{Author: "user", Content: "hi"}},
+	})
+	require.ErrorIs(t, err, ErrContentFiltered)
+}
+
+func TestCreateCompletionPartial(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakePredictionClient{
+		predictions: []map[string]interface{}{
+			{"content": "Tokyo"},
+			{"safetyAttributes": map[string]interface{}{"blocked": true}},
+		},
+	}
+	client := newTestClient(fake)
+	req := &CompletionRequest{Prompts: []string{"japan", "blocked", "france"}}
+
+	resp, missing, err := client.CreateCompletionPartial(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, missing)
+	require.Len(t, resp.Completions, 3)
+	assert.Equal(t, "Tokyo", resp.Completions[0].Text)
+	assert.Nil(t, resp.Completions[1])
+	assert.Nil(t, resp.Completions[2])
+	require.Len(t, fake.requests, 1)
+	assert.Len(t, fake.requests[0].GetInstances(), 3)
+
+	_, err = client.CreateCompletion(context.Background(), req)
+	require.ErrorIs(t, err, ErrContentFiltered)
+}
+
+func TestRequestLabels(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakePredictionClient{
+		predictions: []map[string]interface{}{{"content": "Tokyo"}},
+	}
+	client := newTestClient(fake, WithRequestLabels(map[string]string{"Team": "search", "env": "prod"}))
+	_, err := client.CreateCompletion(context.Background(), &CompletionRequest{Prompts: []string{"japan"}})
+	require.NoError(t, err)
+	assert.Equal(t, metadata.Pairs("team", "search", "env", "prod"), fake.outgoing[0])
+
+	client = newTestClient(fake)
+	_, err = client.CreateCompletion(context.Background(), &CompletionRequest{Prompts: []string{"japan"}})
+	require.NoError(t, err)
+	assert.Nil(t, fake.outgoing[1])
+}
+
+// textChunk returns a streaming response holding a chunk of a completion.
+func textChunk(content string) *aiplatformpb.StreamingPredictResponse {
+	return &aiplatformpb.StreamingPredictResponse{
+		Outputs: []*aiplatformpb.Tensor{toTensor(map[string]interface{}{"content": content})},
+	}
+}
+
+// chatChunk returns a streaming response holding a chunk of each of the
+// candidates of a chat.
+func chatChunk(contents ...string) *aiplatformpb.StreamingPredictResponse {
+	candidates := make([]interface{}, len(contents))
+	for i, content := range contents {
+		candidates[i] = map[string]interface{}{"author": "bot", "content": content}
+	}
+	return &aiplatformpb.StreamingPredictResponse{
+		Outputs: []*aiplatformpb.Tensor{toTensor(map[string]interface{}{"candidates": candidates})},
+	}
+}
+
+func TestCreateChatStreamCandidates(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakePredictionClient{
+		streamResponses: []*aiplatformpb.StreamingPredictResponse{
+			chatChunk("Tok", "Osa"),
+			chatChunk("yo", "ka"),
+		},
+	}
+	client := newTestClient(fake)
+
+	var chunks []string
+	resp, err := client.CreateChat(context.Background(), &ChatRequest{
+		Messages:       []*ChatMessage{{Author: "user", Content: "a city in japan?"}},
+		CandidateCount: 2,
+		StreamingFunc: func(_ context.Context, chunk []byte) error {
+			chunks = append(chunks, string(chunk))
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Tok", "yo"}, chunks)
+	assert.Equal(t, []ChatMessage{{Author: "bot", Content: "Tokyo"}, {Author: "bot", Content: "Osaka"}}, resp.Candidates)
+	assert.Len(t, resp.SafetyAttributes, 2)
+}
+
+func TestStreamContentFiltered(t *testing.T) {
+	t.Parallel()
+
+	blocked := map[string]interface{}{
+		"blocked":    true,
+		"categories": []interface{}{"Derogatory", "Toxic"},
+		"scores":     []interface{}{0.9, 0.8},
+	}
+	streamFunc := func(context.Context, []byte) error { return nil }
+
+	client := newTestClient(&fakePredictionClient{
+		streamResponses: []*aiplatformpb.StreamingPredictResponse{{
+			Outputs: []*aiplatformpb.Tensor{toTensor(map[string]interface{}{"safetyAttributes": blocked})},
+		}},
+	})
+	_, err := client.CreateCompletion(context.Background(), &CompletionRequest{
+		Prompts:       []string{"hi"},
+		StreamingFunc: streamFunc,
+	})
+	require.ErrorIs(t, err, ErrContentFiltered)
+	assert.Contains(t, err.Error(), "Derogatory, Toxic")
+
+	client = newTestClient(&fakePredictionClient{
+		streamResponses: []*aiplatformpb.StreamingPredictResponse{{
+			Outputs: []*aiplatformpb.Tensor{toTensor(map[string]interface{}{
+				"candidates":       []interface{}{},
+				"safetyAttributes": []interface{}{blocked},
+			})},
+		}},
+	})
+	_, err = client.CreateChat(context.Background(), &ChatRequest{
+		Messages:      []*ChatMessage{{Author: "user", Content: "hi"}},
+		StreamingFunc: streamFunc,
+	})
+	require.ErrorIs(t, err, ErrContentFiltered)
+
+	// The safety attributes of a response with content are returned.
+	client = newTestClient(&fakePredictionClient{
+		streamResponses: []*aiplatformpb.StreamingPredictResponse{{
+			Outputs: []*aiplatformpb.Tensor{toTensor(map[string]interface{}{
+				"content":          "hello",
+				"safetyAttributes": map[string]interface{}{"categories": []interface{}{"Toxic"}, "scores": []interface{}{0.1}},
+			})},
+		}},
+	})
+	resp, err := client.CreateCompletion(context.Background(), &CompletionRequest{
+		Prompts:       []string{"hi"},
+		StreamingFunc: streamFunc,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &SafetyAttributes{Categories: []string{"Toxic"}, Scores: []float64{0.1}},
+		resp.Completions[0].SafetyAttributes)
+}
+
+func TestCreateCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakePredictionClient{
+		streamResponses: []*aiplatformpb.StreamingPredictResponse{textChunk("Hel"), textChunk("lo")},
+	}
+	client := newTestClient(fake, WithTextModel("text-bison@002"))
+
+	var chunks []string
+	resp, err := client.CreateCompletion(context.Background(), &CompletionRequest{
+		Prompts: []string{"hi"},
+		StreamingFunc: func(_ context.Context, chunk []byte) error {
+			chunks = append(chunks, string(chunk))
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hel", "lo"}, chunks)
+	assert.Equal(t, "Hello", resp.Completions[0].Text)
+	assert.Empty(t, fake.requests)
+	require.Len(t, fake.streamRequests, 1)
+	assert.Equal(t, "projects/test-project/locations/us-central1/publishers/google/models/text-bison@002",
+		fake.streamRequests[0].GetEndpoint())
+}
+
+func TestCreateCompletionStreamFallback(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakePredictionClient{
+		predictions: []map[string]interface{}{{"content": "hello"}},
+		streamErr:   status.Error(codes.Unimplemented, "streaming is not supported"),
+	}
+	client := newTestClient(fake)
+
+	var chunks []string
+	resp, err := client.CreateCompletion(context.Background(), &CompletionRequest{
+		Prompts: []string{"hi"},
+		StreamingFunc: func(_ context.Context, chunk []byte) error {
+			chunks = append(chunks, string(chunk))
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hello"}, chunks)
+	assert.Equal(t, "hello", resp.Completions[0].Text)
+	assert.Len(t, fake.requests, 1)
+
+	// Other errors aren't retried without streaming.
+	fake = &fakePredictionClient{
+		predictions: []map[string]interface{}{{"content": "hello"}},
+		streamErr:   status.Error(codes.PermissionDenied, "denied"),
+	}
+	client = newTestClient(fake)
+	_, err = client.CreateCompletion(context.Background(), &CompletionRequest{
+		Prompts:       []string{"hi"},
+		StreamingFunc: func(context.Context, []byte) error { return nil },
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Empty(t, fake.requests)
+	// Nor are invalid requests.
+	fake = &fakePredictionClient{
+		predictions: []map[string]interface{}{{"content": "hello"}},
+		streamErr:   status.Error(codes.InvalidArgument, "temperature must be between 0 and 1"),
+	}
+	client = newTestClient(fake)
+	_, err = client.CreateCompletion(context.Background(), &CompletionRequest{
+		Prompts:       []string{"hi"},
+		Temperature:   3,
+		StreamingFunc: func(context.Context, []byte) error { return nil },
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.NotErrorIs(t, err, errStreamingUnsupported)
+	assert.Len(t, fake.streamRequests, 1)
+	assert.Empty(t, fake.requests)
+}
+
+func TestLogger(t *testing.T) {
+	t.Parallel()
+
+	type call struct {
+		req, resp map[string]interface{}
+	}
+	var calls []call
+	logger := func(req, resp []byte) {
+		var c call
+		require.NoError(t, json.Unmarshal(req, &c.req))
+		var r interface{}
+		require.NoError(t, json.Unmarshal(resp, &r))
+		if m, ok := r.(map[string]interface{}); ok {
+			c.resp = m
+		} else {
+			c.resp = map[string]interface{}{"stream": r}
+		}
+		calls = append(calls, c)
+	}
+
+	fake := &fakePredictionClient{
+		predictFunc: func(req *aiplatformpb.PredictRequest) []map[string]interface{} {
+			if strings.HasSuffix(req.GetEndpoint(), ChatModelName) {
+				return []map[string]interface{}{{"candidates": []interface{}{
+					map[string]interface{}{"author": "bot", "content": "hello"},
+				}}}
+			}
+			return []map[string]interface{}{{"content": "hello"}}
+		},
+		streamResponses: []*aiplatformpb.StreamingPredictResponse{textChunk("Hel"), textChunk("lo")},
+		errs:            []error{nil, status.Error(codes.Unavailable, "try again")},
+	}
+	client := newTestClient(fake, WithLogger(logger))
+
+	_, err := client.CreateCompletion(context.Background(), &CompletionRequest{Prompts: []string{"hi"}})
+	require.NoError(t, err)
+	_, err = client.CreateChat(context.Background(), &ChatRequest{Messages: []*ChatMessage{{Author: "user", Content: "hi"}}})
+	require.NoError(t, err)
+	_, err = client.CreateCompletion(context.Background(), &CompletionRequest{
+		Prompts:       []string{"hi"},
+		StreamingFunc: func(context.Context, []byte) error { return nil },
+	})
+	require.NoError(t, err)
+
+	require.Len(t, calls, 4)
+	assert.Contains(t, calls[0].req["endpoint"], "models/text-bison")
+	assert.Contains(t, calls[0].resp, "predictions")
+	// The failed attempt of the chat request is logged before its retry.
+	assert.Contains(t, calls[1].req["endpoint"], "models/chat-bison")
+	assert.Equal(t, map[string]interface{}{"error": "rpc error: code = Unavailable desc = try again"}, calls[1].resp)
+	assert.Contains(t, calls[2].resp, "predictions")
+	assert.Contains(t, calls[3].req, "inputs")
+	assert.Len(t, calls[3].resp["stream"], 2)
+}
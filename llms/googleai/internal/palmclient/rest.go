@@ -0,0 +1,176 @@
+package palmclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"cloud.google.com/go/aiplatform/apiv1/aiplatformpb"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// restClient is a client of the Vertex AI REST API, used instead of the gRPC
+// client when the PaLM client is given an HTTP client.
+type restClient struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// newRESTClient returns a REST client of the API at the given base URL.
+func newRESTClient(httpClient *http.Client, baseURL string) *restClient {
+	return &restClient{
+		httpClient: httpClient,
+		baseURL:    baseURL,
+	}
+}
+
+// restBaseURL returns the base URL of the REST API: the endpoint set with
+// WithEndpoint, https if it has no scheme, or the regional endpoint of the
+// location.
+func restBaseURL(location, endpoint string) string {
+	if endpoint == "" {
+		return "https://" + location + "-aiplatform.googleapis.com"
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	return strings.TrimSuffix(endpoint, "/")
+}
+
+func (c *restClient) Predict(ctx context.Context, req *aiplatformpb.PredictRequest, _ ...gax.CallOption) (*aiplatformpb.PredictResponse, error) { //nolint:lll
+	resp := &aiplatformpb.PredictResponse{}
+	if err := c.call(ctx, req.GetEndpoint()+":predict", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *restClient) CountTokens(ctx context.Context, req *aiplatformpb.CountTokensRequest, _ ...gax.CallOption) (*aiplatformpb.CountTokensResponse, error) { //nolint:lll
+	resp := &aiplatformpb.CountTokensResponse{}
+	if err := c.call(ctx, req.GetEndpoint()+":countTokens", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ServerStreamingPredict issues a streaming prediction. The REST API streams
+// the responses as the elements of a JSON array, which are decoded as they
+// arrive.
+func (c *restClient) ServerStreamingPredict(ctx context.Context, req *aiplatformpb.StreamingPredictRequest, _ ...gax.CallOption) (aiplatformpb.PredictionService_ServerStreamingPredictClient, error) { //nolint:lll
+	body, err := c.do(ctx, req.GetEndpoint()+":serverStreamingPredict", req)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(body)
+	if _, err := decoder.Token(); err != nil { // The opening bracket.
+		body.Close()
+		return nil, fmt.Errorf("%w: streaming response: %w", ErrInvalidValue, err)
+	}
+	return &restStream{body: body, decoder: decoder}, nil
+}
+
+// call issues a request of the REST API and decodes its response into resp.
+func (c *restClient) call(ctx context.Context, path string, req, resp proto.Message) error {
+	body, err := c.do(ctx, path, req)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, resp)
+}
+
+// do posts req to the given path of the REST API and returns the body of a
+// successful response. Failed requests are returned as gRPC status errors, so
+// they are retried like the errors of the gRPC client.
+func (c *restClient) do(ctx context.Context, path string, req proto.Message) (io.ReadCloser, error) {
+	payload, err := protojson.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/"+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	// The outgoing metadata, sent as headers like the gRPC metadata.
+	md, _ := metadata.FromOutgoingContext(ctx)
+	for key, values := range md {
+		for _, value := range values {
+			httpReq.Header.Add(key, value)
+		}
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, status.Errorf(httpStatusCode(resp.StatusCode), "%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return resp.Body, nil
+}
+
+// httpStatusCode maps an HTTP status of the REST API to its gRPC code.
+func httpStatusCode(code int) codes.Code {
+	switch code {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Unknown
+	}
+}
+
+// restStream is a streaming prediction of the REST API.
+type restStream struct {
+	grpc.ClientStream
+	body    io.ReadCloser
+	decoder *json.Decoder
+}
+
+func (s *restStream) Recv() (*aiplatformpb.StreamingPredictResponse, error) {
+	if !s.decoder.More() {
+		s.body.Close()
+		return nil, io.EOF
+	}
+	var raw json.RawMessage
+	if err := s.decoder.Decode(&raw); err != nil {
+		s.body.Close()
+		return nil, err
+	}
+	resp := &aiplatformpb.StreamingPredictResponse{}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(raw, resp); err != nil {
+		s.body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,128 @@
+package palmclient
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newRESTTestClient returns a client sending its requests to the REST API
+// served by handler.
+func newRESTTestClient(t *testing.T, handler http.HandlerFunc) *PaLMClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := New("test-project", WithHTTPClient(server.Client()))
+	require.NoError(t, err)
+	c.client.(*restClient).baseURL = server.URL
+	c.retryBaseDelay = time.Millisecond
+	return c
+}
+
+func TestHTTPClientWithClientOptions(t *testing.T) {
+	t.Parallel()
+
+	_, err := New("test-project", WithHTTPClient(http.DefaultClient), WithClientOptions(option.WithAPIKey("secret")))
+	require.ErrorIs(t, err, ErrInvalidValue)
+}
+
+func TestRESTCreateCompletion(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu    sync.Mutex
+		paths []string
+		body  map[string]interface{}
+	)
+	c := newRESTTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+		if len(paths) == 1 {
+			http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+		_, _ = io.WriteString(w, `{"predictions": [{"content": "hello"}], "deployedModelId": "ignored"}`)
+	})
+
+	resp, err := c.CreateCompletion(context.Background(), &CompletionRequest{Prompts: []string{"hi"}})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", resp.Completions[0].Text)
+
+	mu.Lock()
+	defer mu.Unlock()
+	path := "/v1/projects/test-project/locations/us-central1/publishers/google/models/text-bison:predict"
+	assert.Equal(t, []string{path, path}, paths)
+	instances, _ := body["instances"].([]interface{})
+	require.Len(t, instances, 1)
+	assert.Equal(t, map[string]interface{}{"content": "hi"}, instances[0])
+}
+
+func TestRESTErrors(t *testing.T) {
+	t.Parallel()
+
+	c := newRESTTestClient(t, func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "bad prompt", http.StatusBadRequest)
+	})
+
+	_, err := c.CreateCompletion(context.Background(), &CompletionRequest{Prompts: []string{"hi"}})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, err.Error(), "bad prompt")
+}
+
+func TestRESTRequestLabels(t *testing.T) {
+	t.Parallel()
+
+	var header http.Header
+	c := newRESTTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		_, _ = io.WriteString(w, `{"predictions": [{"content": "hello"}]}`)
+	})
+	WithRequestLabels(map[string]string{"team": "search"})(c)
+
+	_, err := c.CreateCompletion(context.Background(), &CompletionRequest{Prompts: []string{"hi"}})
+	require.NoError(t, err)
+	assert.Equal(t, "search", header.Get("Team"))
+}
+
+func TestRESTCreateChatStream(t *testing.T) {
+	t.Parallel()
+
+	var path string
+	c := newRESTTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_, _ = io.WriteString(w, `[
+			{"outputs": [{"structVal": {"candidates": {"listVal": [{"structVal": {"author": {"stringVal": ["bot"]}, "content": {"stringVal": ["Hel"]}}}]}}}]},
+			{"outputs": [{"structVal": {"candidates": {"listVal": [{"structVal": {"content": {"stringVal": ["lo"]}}}]}}}]}
+		]`)
+	})
+
+	var chunks []string
+	resp, err := c.CreateChat(context.Background(), &ChatRequest{
+		Messages: []*ChatMessage{{Author: "user", Content: "hi"}},
+		StreamingFunc: func(_ context.Context, chunk []byte) error {
+			chunks = append(chunks, string(chunk))
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/projects/test-project/locations/us-central1/publishers/google/models/chat-bison:serverStreamingPredict", path) //nolint:lll
+	assert.Equal(t, []string{"Hel", "lo"}, chunks)
+	assert.Equal(t, "Hello", resp.Candidates[0].Content)
+	assert.Equal(t, "bot", resp.Candidates[0].Author)
+}
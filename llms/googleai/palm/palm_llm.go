@@ -6,31 +6,193 @@ package palm
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/tmc/langchaingo/callbacks"
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/googleai/internal/palmclient"
+	"github.com/tmc/langchaingo/prompts"
 )
 
 var (
 	ErrEmptyResponse            = errors.New("no response")
 	ErrMissingProjectID         = errors.New("missing the GCP Project ID, set it in the GOOGLE_CLOUD_PROJECT environment variable") //nolint:lll
 	ErrUnexpectedResponseLength = errors.New("unexpected length of response")
-	ErrNotImplemented           = errors.New("not implemented")
+	// ErrInvalidOutputDimensionality is returned for an output dimensionality
+	// of the embeddings that isn't positive.
+	ErrInvalidOutputDimensionality = errors.New("output dimensionality must be positive")
+	ErrNotImplemented              = errors.New("not implemented")
+	ErrMissingModel                = errors.New("missing the model name")
+	// ErrContentFiltered is returned when Vertex AI blocked the response for
+	// safety reasons; the error wraps the blocked safety categories.
+	ErrContentFiltered = palmclient.ErrContentFiltered
+)
+
+const (
+	userAuthor = "user"
+	botAuthor  = "bot"
+)
+
+// The reasons PaLM stopped generating a candidate, reported as the
+// StopReason of the content choices and the llms.GenerationInfoFinishReason
+// of their generation info.
+const (
+	// FinishReasonStop is reported when the candidate ended naturally or at a
+	// stop sequence.
+	FinishReasonStop = "STOP"
+	// FinishReasonMaxTokens is reported when the candidate was cut off at the
+	// MaxTokens limit.
+	FinishReasonMaxTokens = "MAX_TOKENS"
+	// FinishReasonSafety is reported when the candidate was flagged by the
+	// safety filters.
+	FinishReasonSafety = "SAFETY"
 )
 
 type LLM struct {
 	CallbacksHandler callbacks.Handler
 	client           *palmclient.PaLMClient
+	model            string
+	retryOnEmpty     int
+	keepStopWords    bool
+	countTokensAPI   bool
 }
 
-var _ llms.Model = (*LLM)(nil)
+var (
+	_ llms.Model       = (*LLM)(nil)
+	_ llms.BatchCaller = (*LLM)(nil)
+)
 
 // Call requests a completion for the given prompt.
 func (o *LLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
 	return llms.GenerateFromSinglePrompt(ctx, o, prompt, options...)
 }
 
+// CallTemplate renders the Go template tmpl with vars, as a
+// prompts.PromptTemplate does, and requests a completion for the result.
+func (o *LLM) CallTemplate(ctx context.Context, tmpl string, vars map[string]any, options ...llms.CallOption) (string, error) { //nolint:lll
+	prompt, err := prompts.NewPromptTemplate(tmpl, nil).Format(vars)
+	if err != nil {
+		return "", fmt.Errorf("rendering prompt template: %w", err)
+	}
+	return o.Call(ctx, prompt, options...)
+}
+
+// CallBatch requests the completions of several independent prompts in one
+// request to the text model, and returns them in the order of the prompts. If
+// a prompt gets no completion, e.g. because it was filtered, the batch fails;
+// see CallBatchPartial to tolerate it.
+func (o *LLM) CallBatch(ctx context.Context, prompts []string, options ...llms.CallOption) ([]string, error) {
+	texts, _, err := o.callBatch(ctx, prompts, false, options...)
+	return texts, err
+}
+
+// CallBatchPartial requests the completions of several prompts like
+// CallBatch, but a prompt without a completion doesn't fail the batch. The
+// returned completions are aligned with prompts, with empty entries for the
+// prompts that got none; their indices are returned as the second value,
+// along with an error wrapping ErrEmptyResponse.
+func (o *LLM) CallBatchPartial(ctx context.Context, prompts []string, options ...llms.CallOption) ([]string, []int, error) { //nolint:lll
+	texts, missing, err := o.callBatch(ctx, prompts, true, options...)
+	if err != nil {
+		return texts, missing, err
+	}
+	if len(missing) > 0 {
+		return texts, missing, fmt.Errorf("%w: no completions for prompts %v", ErrEmptyResponse, missing)
+	}
+	return texts, nil, nil
+}
+
+// callBatch requests the completions of the prompts, tolerating the prompts
+// without a completion if partial is set.
+func (o *LLM) callBatch(ctx context.Context, prompts []string, partial bool, options ...llms.CallOption) ([]string, []int, error) { //nolint:lll
+	if len(prompts) == 0 {
+		return []string{}, nil, nil
+	}
+	if o.CallbacksHandler != nil {
+		o.CallbacksHandler.HandleLLMStart(ctx, prompts)
+	}
+
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	req := &palmclient.CompletionRequest{
+		Prompts:       prompts,
+		MaxTokens:     opts.MaxTokens,
+		Temperature:   opts.Temperature,
+		TopP:          opts.TopP,
+		TopK:          opts.TopK,
+		StopSequences: opts.StopWords,
+		Seed:          opts.Seed,
+	}
+
+	var (
+		results *palmclient.CompletionResponse
+		missing []int
+		err     error
+	)
+	if partial {
+		results, missing, err = o.client.CreateCompletionPartial(ctx, req)
+	} else {
+		results, err = o.client.CreateCompletion(ctx, req)
+		if err == nil && len(results.Completions) != len(prompts) {
+			err = fmt.Errorf("%w: %d completions for %d prompts",
+				ErrUnexpectedResponseLength, len(results.Completions), len(prompts))
+		}
+	}
+	if err != nil {
+		if o.CallbacksHandler != nil {
+			o.CallbacksHandler.HandleLLMError(ctx, err)
+		}
+		return nil, nil, err
+	}
+
+	texts := make([]string, len(prompts))
+	choices := make([]*llms.ContentChoice, len(prompts))
+	for i, completion := range results.Completions {
+		choices[i] = &llms.ContentChoice{}
+		if completion == nil {
+			continue
+		}
+		reason := finishReason(completion.SafetyAttributes, results.Usage, opts.MaxTokens, len(prompts))
+		texts[i] = o.trimStopWords(completion.Text, opts.StopWords)
+		choices[i] = &llms.ContentChoice{
+			Content:        texts[i],
+			StopReason:     reason,
+			GenerationInfo: generationInfo(results.Usage, completion.SafetyAttributes, reason),
+		}
+	}
+	if o.CallbacksHandler != nil {
+		o.CallbacksHandler.HandleLLMGenerateContentEnd(ctx, &llms.ContentResponse{Choices: choices})
+	}
+	return texts, missing, nil
+}
+
+// GenerateContentPartial generates the content of several independent message
+// sets, e.g. a batch of chats, one after the other. A failed set, e.g. one
+// whose response is empty, doesn't discard the others: the responses are
+// aligned with messageSets, with nil entries for the failed sets, and the
+// error joins the errors of the failed sets. Only a cancellation of the
+// context stops the remaining sets.
+func (o *LLM) GenerateContentPartial(ctx context.Context, messageSets [][]llms.MessageContent, options ...llms.CallOption) ([]*llms.ContentResponse, error) { //nolint:lll
+	responses := make([]*llms.ContentResponse, len(messageSets))
+	var errs []error
+	for i, messages := range messageSets {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		resp, err := o.GenerateContent(ctx, messages, options...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("message set %d: %w", i, err))
+			continue
+		}
+		responses[i] = resp
+	}
+	return responses, errors.Join(errs...)
+}
+
 // GenerateContent implements the Model interface.
 func (o *LLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) { //nolint: lll, cyclop, whitespace
 
@@ -43,42 +205,215 @@ func (o *LLM) GenerateContent(ctx context.Context, messages []llms.MessageConten
 		opt(&opts)
 	}
 
+	var (
+		resp *llms.ContentResponse
+		err  error
+	)
+	for attempt := 0; ; attempt++ {
+		if len(messages) == 1 && messages[0].Role == llms.ChatMessageTypeHuman {
+			resp, err = o.generateCompletion(ctx, messages[0], opts)
+		} else {
+			resp, err = o.generateChat(ctx, messages, opts)
+		}
+		if attempt >= o.retryOnEmpty || !isEmptyResponse(err) || ctx.Err() != nil {
+			break
+		}
+	}
+	if err != nil {
+		if o.CallbacksHandler != nil {
+			o.CallbacksHandler.HandleLLMError(ctx, err)
+		}
+		return nil, err
+	}
+
+	if o.CallbacksHandler != nil {
+		o.CallbacksHandler.HandleLLMGenerateContentEnd(ctx, resp)
+	}
+
+	return resp, nil
+}
+
+// isEmptyResponse reports whether err is an empty response of Vertex AI,
+// which WithRetryOnEmpty retries.
+func isEmptyResponse(err error) bool {
+	return errors.Is(err, ErrEmptyResponse) || errors.Is(err, palmclient.ErrEmptyResponse)
+}
+
+// generateCompletion generates a response from a single prompt using the
+// PaLM text model.
+func (o *LLM) generateCompletion(ctx context.Context, msg llms.MessageContent, opts llms.CallOptions) (*llms.ContentResponse, error) { //nolint:lll
 	// Assume we get a single text message
-	msg0 := messages[0]
-	part := msg0.Parts[0]
+	part := msg.Parts[0]
 
 	results, err := o.client.CreateCompletion(ctx, &palmclient.CompletionRequest{
 		Prompts:       []string{part.(llms.TextContent).Text},
 		MaxTokens:     opts.MaxTokens,
 		Temperature:   opts.Temperature,
+		TopP:          opts.TopP,
+		TopK:          opts.TopK,
 		StopSequences: opts.StopWords,
+		Seed:          opts.Seed,
+		StreamingFunc: opts.StreamingFunc,
 	})
 	if err != nil {
-		if o.CallbacksHandler != nil {
-			o.CallbacksHandler.HandleLLMError(ctx, err)
-		}
 		return nil, err
 	}
 
-	resp := &llms.ContentResponse{
+	completion := results.Completions[0]
+	reason := finishReason(completion.SafetyAttributes, results.Usage, opts.MaxTokens, 1)
+	return &llms.ContentResponse{
 		Choices: []*llms.ContentChoice{
 			{
-				Content: results[0].Text,
+				Content:        o.trimStopWords(completion.Text, opts.StopWords),
+				StopReason:     reason,
+				GenerationInfo: generationInfo(results.Usage, completion.SafetyAttributes, reason),
 			},
 		},
+	}, nil
+}
+
+// generateChat generates a response from a sequence of messages using the
+// PaLM chat model.
+func (o *LLM) generateChat(ctx context.Context, messages []llms.MessageContent, opts llms.CallOptions) (*llms.ContentResponse, error) { //nolint:lll
+	chatContext, chatMessages, err := toClientChatMessages(messages)
+	if err != nil {
+		return nil, err
 	}
-	if o.CallbacksHandler != nil {
-		o.CallbacksHandler.HandleLLMGenerateContentEnd(ctx, resp)
+
+	result, err := o.client.CreateChat(ctx, &palmclient.ChatRequest{
+		Context:        chatContext,
+		Messages:       chatMessages,
+		MaxTokens:      opts.MaxTokens,
+		Temperature:    opts.Temperature,
+		TopP:           opts.TopP,
+		TopK:           opts.TopK,
+		StopSequences:  opts.StopWords,
+		CandidateCount: opts.N,
+		Seed:           opts.Seed,
+		StreamingFunc:  opts.StreamingFunc,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Candidates) == 0 {
+		return nil, ErrEmptyResponse
 	}
 
-	return resp, nil
+	choices := make([]*llms.ContentChoice, 0, len(result.Candidates))
+	for i, candidate := range result.Candidates {
+		reason := finishReason(result.SafetyAttributes[i], result.Usage, opts.MaxTokens, len(result.Candidates))
+		choices = append(choices, &llms.ContentChoice{
+			Content:        o.trimStopWords(candidate.Content, opts.StopWords),
+			StopReason:     reason,
+			GenerationInfo: generationInfo(result.Usage, result.SafetyAttributes[i], reason),
+		})
+	}
+
+	return &llms.ContentResponse{Choices: choices}, nil
+}
+
+// trimStopWords trims text at the first occurrence of any of the stop words,
+// unless the LLM keeps them.
+func (o *LLM) trimStopWords(text string, stopWords []string) string {
+	if o.keepStopWords {
+		return text
+	}
+	return llms.TrimStopWords(text, stopWords)
+}
+
+// finishReason returns the reason PaLM stopped generating one of the given
+// number of candidates. PaLM doesn't report it, so it is derived from the
+// safety attributes and the token usage of the response: the usage covers all
+// the candidates, so MAX_TOKENS is only reported when every candidate reached
+// the maxTokens limit.
+func finishReason(safety *palmclient.SafetyAttributes, usage palmclient.TokenUsage, maxTokens, candidates int) string { //nolint:lll
+	switch {
+	case safety != nil && safety.Blocked:
+		return FinishReasonSafety
+	case maxTokens > 0 && usage.CompletionTokens >= maxTokens*candidates:
+		return FinishReasonMaxTokens
+	default:
+		return FinishReasonStop
+	}
+}
+
+// generationInfo returns the generation info reported for a candidate.
+func generationInfo(usage palmclient.TokenUsage, safety *palmclient.SafetyAttributes, reason string) llms.GenerationInfo {
+	info := llms.NewGenerationInfo(usage.PromptTokens, usage.CompletionTokens, reason)
+	if safety != nil {
+		info["SafetyAttributes"] = safety
+	}
+	return info
+}
+
+// toClientChatMessages splits messages into the chat context, built from the
+// system messages, and the PaLM chat messages of the conversation.
+func toClientChatMessages(messages []llms.MessageContent) (string, []*palmclient.ChatMessage, error) {
+	systemPrompts := []string{}
+	chatMessages := make([]*palmclient.ChatMessage, 0, len(messages))
+	for _, m := range messages {
+		if m.Role == llms.ChatMessageTypeSystem {
+			text, err := textContent(m)
+			if err != nil {
+				return "", nil, err
+			}
+			systemPrompts = append(systemPrompts, text)
+			continue
+		}
+		msg, err := toClientChatMessage(m)
+		if err != nil {
+			return "", nil, err
+		}
+		chatMessages = append(chatMessages, msg)
+	}
+	return strings.Join(systemPrompts, "\n"), chatMessages, nil
+}
+
+// toClientChatMessage converts a message into a PaLM chat message.
+func toClientChatMessage(msg llms.MessageContent) (*palmclient.ChatMessage, error) {
+	content, err := textContent(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	author := userAuthor
+	if msg.Role == llms.ChatMessageTypeAI {
+		author = botAuthor
+	}
+
+	return &palmclient.ChatMessage{
+		Author:  author,
+		Content: content,
+	}, nil
+}
+
+// textContent concatenates the text parts of a message.
+func textContent(msg llms.MessageContent) (string, error) {
+	var content strings.Builder
+	for _, part := range msg.Parts {
+		text, ok := part.(llms.TextContent)
+		if !ok {
+			return "", fmt.Errorf("%w: %T in chat messages", ErrNotImplemented, part)
+		}
+		content.WriteString(text.Text)
+	}
+	return content.String(), nil
 }
 
 // CreateEmbedding creates embeddings for the given input texts.
 func (o *LLM) CreateEmbedding(ctx context.Context, inputTexts []string) ([][]float32, error) {
-	embeddings, err := o.client.CreateEmbedding(ctx, &palmclient.EmbeddingRequest{
-		Input: inputTexts,
-	})
+	return o.CreateEmbeddingWithOptions(ctx, inputTexts)
+}
+
+// CreateEmbeddingWithOptions creates embeddings for the given input texts,
+// configured by the given options (e.g. the task type).
+func (o *LLM) CreateEmbeddingWithOptions(ctx context.Context, inputTexts []string, options ...EmbeddingOption) ([][]float32, error) { //nolint:lll
+	opts, err := newEmbeddingOptions(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	embeddings, err := o.client.CreateEmbedding(ctx, opts.request(inputTexts))
 	if err != nil {
 		return [][]float32{}, err
 	}
@@ -93,13 +428,61 @@ func (o *LLM) CreateEmbedding(ctx context.Context, inputTexts []string) ([][]flo
 	return embeddings, nil
 }
 
+// CreateEmbeddingPartial creates embeddings for the given input texts like
+// CreateEmbedding, but a dropped result doesn't discard the whole batch. The
+// returned embeddings are aligned with inputTexts, with nil entries for the
+// inputs that got no embedding (after one retry of just those inputs); their
+// indices are returned as the second value, along with an error wrapping
+// ErrUnexpectedResponseLength.
+func (o *LLM) CreateEmbeddingPartial(ctx context.Context, inputTexts []string, options ...EmbeddingOption) ([][]float32, []int, error) { //nolint:lll
+	opts, err := newEmbeddingOptions(options...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	embeddings, missing, err := o.client.CreateEmbeddingPartial(ctx, opts.request(inputTexts))
+	if err != nil {
+		return embeddings, missing, err
+	}
+	if len(missing) > 0 {
+		return embeddings, missing, fmt.Errorf("%w: no embeddings for inputs %v", ErrUnexpectedResponseLength, missing)
+	}
+	return embeddings, nil, nil
+}
+
 // New returns a new palmclient PaLM LLM.
 func New(opts ...Option) (*LLM, error) {
-	client, err := newClient(opts...)
-	return &LLM{client: client}, err
+	options := newOptions(opts...)
+	client, err := newClient(options)
+	return &LLM{
+		CallbacksHandler: options.callbackHandler,
+		client:           client,
+		model:            options.model,
+		retryOnEmpty:     options.retryOnEmpty,
+		keepStopWords:    options.keepStopWords,
+		countTokensAPI:   options.countTokensAPI,
+	}, err
 }
 
-func newClient(opts ...Option) (*palmclient.PaLMClient, error) {
+// GetNumTokens returns the number of tokens the text contains for the
+// configured model.
+func (o *LLM) GetNumTokens(text string) int {
+	return o.GetNumTokensCtx(context.Background(), text)
+}
+
+// GetNumTokensCtx is like GetNumTokens, with a context for the countTokens
+// API call if WithCountTokensAPI is enabled. The count falls back to the
+// local estimator if the call fails.
+func (o *LLM) GetNumTokensCtx(ctx context.Context, text string) int {
+	if o.countTokensAPI && o.client != nil {
+		if n, err := o.client.CountTokens(ctx, text); err == nil {
+			return n
+		}
+	}
+	return llms.CountTokens(o.model, text)
+}
+
+func newOptions(opts ...Option) *options {
 	// Ensure options are initialized only once.
 	initOptions.Do(initOpts)
 	options := &options{}
@@ -108,9 +491,26 @@ func newClient(opts ...Option) (*palmclient.PaLMClient, error) {
 	for _, opt := range opts {
 		opt(options)
 	}
+	return options
+}
+
+func newClient(options *options) (*palmclient.PaLMClient, error) {
 	if len(options.projectID) == 0 {
 		return nil, ErrMissingProjectID
 	}
+	if len(options.model) == 0 {
+		return nil, ErrMissingModel
+	}
 
-	return palmclient.New(options.projectID, options.clientOptions...)
+	return palmclient.New(options.projectID,
+		palmclient.WithClientOptions(options.clientOptions...),
+		palmclient.WithHTTPClient(options.httpClient),
+		palmclient.WithLocation(options.location),
+		palmclient.WithEndpoint(options.endpoint),
+		palmclient.WithTextModel(options.model),
+		palmclient.WithEmbeddingBatchSize(options.embeddingBatchSize),
+		palmclient.WithMaxRetries(options.maxRetries),
+		palmclient.WithLogger(options.logger),
+		palmclient.WithRequestLabels(options.requestLabels),
+	)
 }
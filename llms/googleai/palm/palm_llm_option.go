@@ -1,16 +1,20 @@
 package palm
 
 import (
+	"fmt"
 	"net/http"
 	"os"
 	"sync"
 
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/llms/googleai/internal/palmclient"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
 )
 
 const (
-	projectIDEnvVarName = "GOOGLE_CLOUD_PROJECT" //nolint:gosec
+	projectIDEnvVarName = "GOOGLE_CLOUD_PROJECT"  //nolint:gosec
+	locationEnvVarName  = "GOOGLE_CLOUD_LOCATION" //nolint:gosec
 )
 
 var (
@@ -22,8 +26,20 @@ var (
 )
 
 type options struct {
-	projectID     string
-	clientOptions []option.ClientOption
+	projectID          string
+	location           string
+	endpoint           string
+	model              string
+	embeddingBatchSize int
+	maxRetries         int
+	retryOnEmpty       int
+	keepStopWords      bool
+	countTokensAPI     bool
+	httpClient         *http.Client
+	clientOptions      []option.ClientOption
+	logger             func(req, resp []byte)
+	requestLabels      map[string]string
+	callbackHandler    callbacks.Handler
 }
 
 // Option is a function that can be passed to NewClient to configure options.
@@ -32,7 +48,10 @@ type Option func(*options)
 // initOpts initializes defaultOptions with the environment variables.
 func initOpts() {
 	defaultOptions = &options{
-		projectID: os.Getenv(projectIDEnvVarName),
+		projectID:  os.Getenv(projectIDEnvVarName),
+		location:   os.Getenv(locationEnvVarName),
+		model:      palmclient.TextModelName,
+		maxRetries: palmclient.DefaultMaxRetries,
 	}
 }
 
@@ -44,22 +63,131 @@ func WithProjectID(projectID string) Option {
 	}
 }
 
+// WithLocation sets the GCP region (e.g. "europe-west4") the client sends
+// requests to. If not set, the location is read from the GOOGLE_CLOUD_LOCATION
+// environment variable, falling back to "us-central1".
+func WithLocation(location string) Option {
+	return func(opts *options) {
+		opts.location = location
+	}
+}
+
+// WithEndpoint overrides the Vertex AI API endpoint, e.g. to send the
+// requests to an httptest.Server in tests or to a Private Service Connect
+// endpoint. It is either a host:port or, with WithHTTPClient, a base URL such
+// as "http://127.0.0.1:8080". If not set, the regional Google endpoint of the
+// location is used.
+func WithEndpoint(endpoint string) Option {
+	return func(opts *options) {
+		opts.endpoint = endpoint
+	}
+}
+
+// WithModel sets the name of the PaLM text model (e.g. "text-bison@002") used
+// for completions and token counting. Defaults to "text-bison".
+func WithModel(model string) Option {
+	return func(opts *options) {
+		opts.model = model
+	}
+}
+
+// WithEmbeddingBatchSize sets the maximum number of texts sent per embedding
+// request; larger inputs of CreateEmbedding are split into several requests.
+// Defaults to 5, the limit of the Vertex AI embedding models.
+func WithEmbeddingBatchSize(size int) Option {
+	return func(opts *options) {
+		opts.embeddingBatchSize = size
+	}
+}
+
+// WithMaxRetries sets how many times a request rejected by Vertex AI with a
+// retryable status (429/503) is retried, with exponential backoff. Zero
+// disables retries. Defaults to 3.
+func WithMaxRetries(n int) Option {
+	return func(opts *options) {
+		opts.maxRetries = n
+	}
+}
+
+// WithRetryOnEmpty sets how many times GenerateContent retries a request
+// whose response is transiently empty, e.g. a chat response without
+// candidates. Responses blocked by the safety filters, returned as
+// ErrContentFiltered, are not retried. Defaults to 0, no retries.
+func WithRetryOnEmpty(n int) Option {
+	return func(opts *options) {
+		opts.retryOnEmpty = n
+	}
+}
+
+// WithKeepStopWords keeps the generated text as returned by the model. By
+// default the text is trimmed at the first occurrence of any of the stop words
+// of the call, as the model may still return them.
+func WithKeepStopWords(keep bool) Option {
+	return func(opts *options) {
+		opts.keepStopWords = keep
+	}
+}
+
+// WithCountTokensAPI counts tokens in GetNumTokens and GetNumTokensCtx with
+// the Vertex AI countTokens API, which matches the model tokenization, instead
+// of the local estimator. The local estimator is still used if the call fails.
+func WithCountTokensAPI(enabled bool) Option {
+	return func(opts *options) {
+		opts.countTokensAPI = enabled
+	}
+}
+
+// WithRequestLabels attaches the labels to the metadata of every Vertex AI
+// prediction request, e.g. for request attribution in proxies and logs. The
+// label keys are lowercased. Requests carry no labels by default.
+//
+// These are request metadata headers, not Vertex AI resource or billing
+// labels: PredictRequest has no labels field, so they don't show up in Cloud
+// Billing exports or in the Vertex AI monitoring labels.
+func WithRequestLabels(labels map[string]string) Option {
+	return func(opts *options) {
+		opts.requestLabels = labels
+	}
+}
+
+// WithLogger sets a function called with the JSON request and response bodies
+// of every request sent to Vertex AI, e.g. to debug prompts. Failed requests
+// are logged with a JSON object of the error as the response, and streaming
+// requests with the JSON array of the streamed responses. The request
+// headers, which carry the credentials, are never logged. Disabled by default.
+func WithLogger(logger func(req, resp []byte)) Option {
+	return func(opts *options) {
+		opts.logger = logger
+	}
+}
+
+// WithCallback sets the callbacks handler notified at the start and end of
+// every GenerateContent and Call, with the messages and the response, or with
+// the error of a failed call, e.g. for tracing and metrics.
+func WithCallback(callbackHandler callbacks.Handler) Option {
+	return func(opts *options) {
+		opts.callbackHandler = callbackHandler
+	}
+}
+
 // WithAPIKey returns a ClientOption that specifies an API key to be used
 // as the basis for authentication.
 func WithAPIKey(apiKey string) Option {
 	return convertStringOption(option.WithAPIKey)(apiKey)
 }
 
-// WithCredentialsFile returns a ClientOption that authenticates
-// API calls with the given service account or refresh token JSON
-// credentials file.
+// WithCredentialsFile returns an Option that authenticates API calls with the
+// given service account or refresh token JSON credentials file, instead of
+// the Application Default Credentials. The credentials only apply to the LLM
+// created with the option, e.g. one per tenant. They are not used with
+// WithHTTPClient, whose client authenticates the requests.
 func WithCredentialsFile(path string) Option {
 	return convertStringOption(option.WithCredentialsFile)(path)
 }
 
-// WithCredentialsJSON returns a ClientOption that authenticates
-// API calls with the given service account or refresh token JSON
-// credentials.
+// WithCredentialsJSON returns an Option that authenticates API calls with the
+// given service account or refresh token JSON credentials, like
+// WithCredentialsFile.
 func WithCredentialsJSON(json []byte) Option {
 	return convertByteArrayOption(option.WithCredentialsJSON)(json)
 }
@@ -70,9 +198,15 @@ func WithGRPCDialOption(opt grpc.DialOption) Option {
 	}
 }
 
+// WithHTTPClient sends all requests with the given HTTP client, e.g. for
+// proxies, custom TLS or request logging, through the Vertex AI REST API
+// instead of gRPC. The client is responsible for authenticating the requests,
+// e.g. one from golang.org/x/oauth2/google.DefaultClient: WithCredentialsFile,
+// WithCredentialsJSON, WithAPIKey and WithGRPCDialOption don't apply to it, and
+// New fails if any of them is also given.
 func WithHTTPClient(client *http.Client) Option {
 	return func(opts *options) {
-		opts.clientOptions = append(opts.clientOptions, option.WithHTTPClient(client))
+		opts.httpClient = client
 	}
 }
 
@@ -91,3 +225,75 @@ func convertByteArrayOption(fopt func([]byte) option.ClientOption) func([]byte)
 		}
 	}
 }
+
+// TaskType is the intended downstream application of embeddings.
+type TaskType string
+
+const (
+	TaskTypeRetrievalQuery     TaskType = "RETRIEVAL_QUERY"
+	TaskTypeRetrievalDocument  TaskType = "RETRIEVAL_DOCUMENT"
+	TaskTypeSemanticSimilarity TaskType = "SEMANTIC_SIMILARITY"
+	TaskTypeClassification     TaskType = "CLASSIFICATION"
+	TaskTypeClustering         TaskType = "CLUSTERING"
+)
+
+type embeddingOptions struct {
+	taskType             TaskType
+	title                string
+	outputDimensionality *int
+}
+
+// EmbeddingOption is a function that can be passed to CreateEmbeddingWithOptions
+// to configure an embedding request.
+type EmbeddingOption func(*embeddingOptions)
+
+// WithTaskType sets the task type the embeddings are optimized for. If not
+// set, no task type is sent and the model default is used.
+func WithTaskType(taskType TaskType) EmbeddingOption {
+	return func(opts *embeddingOptions) {
+		opts.taskType = taskType
+	}
+}
+
+// WithTitle sets the title of the embedded documents. Only valid with
+// TaskTypeRetrievalDocument.
+func WithTitle(title string) EmbeddingOption {
+	return func(opts *embeddingOptions) {
+		opts.title = title
+	}
+}
+
+// WithOutputDimensionality sets the size of the embeddings, e.g. 256 instead
+// of 768, for the models supporting it such as text-embedding-004, to match
+// the dimension of a vector store. It must be positive. If not set, the model
+// default is used.
+func WithOutputDimensionality(dimensionality int) EmbeddingOption {
+	return func(opts *embeddingOptions) {
+		opts.outputDimensionality = &dimensionality
+	}
+}
+
+// newEmbeddingOptions applies the embedding options and validates them.
+func newEmbeddingOptions(options ...EmbeddingOption) (embeddingOptions, error) {
+	opts := embeddingOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if opts.outputDimensionality != nil && *opts.outputDimensionality <= 0 {
+		return opts, fmt.Errorf("%w: %d", ErrInvalidOutputDimensionality, *opts.outputDimensionality)
+	}
+	return opts, nil
+}
+
+// request returns the embedding request of the input texts.
+func (opts embeddingOptions) request(inputTexts []string) *palmclient.EmbeddingRequest {
+	r := &palmclient.EmbeddingRequest{
+		Input:    inputTexts,
+		TaskType: string(opts.taskType),
+		Title:    opts.title,
+	}
+	if opts.outputDimensionality != nil {
+		r.OutputDimensionality = *opts.outputDimensionality
+	}
+	return r
+}
@@ -0,0 +1,396 @@
+package palm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/googleai/internal/palmclient"
+)
+
+func TestToClientChatMessagesSystemContext(t *testing.T) {
+	t.Parallel()
+
+	chatContext, messages, err := toClientChatMessages([]llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "You are a helpful assistant."),
+		llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+		llms.TextParts(llms.ChatMessageTypeAI, "Hi there!"),
+		llms.TextParts(llms.ChatMessageTypeHuman, "What is the capital of France?"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "You are a helpful assistant.", chatContext)
+	assert.Equal(t, []*palmclient.ChatMessage{
+		{Author: userAuthor, Content: "Hello"},
+		{Author: botAuthor, Content: "Hi there!"},
+		{Author: userAuthor, Content: "What is the capital of France?"},
+	}, messages)
+}
+
+func TestNewMissingModel(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(WithProjectID("test-project"), WithModel(""))
+	require.ErrorIs(t, err, ErrMissingModel)
+}
+
+func TestTrimStopWords(t *testing.T) {
+	t.Parallel()
+
+	llm := &LLM{}
+	assert.Equal(t, "Paris is the capital", llm.trimStopWords("Paris is the capital.\nQ: And Spain?", []string{"\nQ:", "."}))
+	assert.Equal(t, "Paris", llm.trimStopWords("Paris", []string{"", "\n"}))
+	assert.Equal(t, "Paris", llm.trimStopWords("Paris", nil))
+
+	llm = &LLM{keepStopWords: true}
+	assert.Equal(t, "Paris.\nQ:", llm.trimStopWords("Paris.\nQ:", []string{"\nQ:"}))
+}
+
+func TestGetNumTokensFallback(t *testing.T) {
+	t.Parallel()
+
+	llm := &LLM{model: palmclient.TextModelName, countTokensAPI: true}
+	assert.Equal(t, llms.CountTokens(palmclient.TextModelName, "hello world"), llm.GetNumTokens("hello world"))
+}
+
+func TestNewWithHTTPClient(t *testing.T) {
+	t.Parallel()
+
+	// The HTTP client replaces the gRPC transport, so no credentials are
+	// looked up.
+	llm, err := New(WithProjectID("test-project"), WithHTTPClient(&http.Client{}))
+	require.NoError(t, err)
+	assert.NotNil(t, llm.client)
+
+	// The credentials would be ignored by the HTTP client.
+	_, err = New(WithProjectID("test-project"), WithHTTPClient(&http.Client{}), WithAPIKey("secret"))
+	require.ErrorIs(t, err, palmclient.ErrInvalidValue)
+	_, err = New(WithProjectID("test-project"), WithHTTPClient(&http.Client{}),
+		WithCredentialsJSON([]byte(`{"type": "service_account"}`)))
+	require.ErrorIs(t, err, palmclient.ErrInvalidValue)
+}
+
+func TestFinishReason(t *testing.T) {
+	t.Parallel()
+
+	usage := palmclient.TokenUsage{PromptTokens: 5, CompletionTokens: 10}
+	blocked := &palmclient.SafetyAttributes{Blocked: true}
+	safe := &palmclient.SafetyAttributes{}
+
+	assert.Equal(t, FinishReasonStop, finishReason(safe, usage, 0, 1))
+	assert.Equal(t, FinishReasonStop, finishReason(nil, usage, 11, 1))
+	assert.Equal(t, FinishReasonMaxTokens, finishReason(safe, usage, 10, 1))
+	assert.Equal(t, FinishReasonSafety, finishReason(blocked, usage, 10, 1))
+	// Two candidates of up to 5 tokens each used 10 tokens: both were cut off.
+	assert.Equal(t, FinishReasonMaxTokens, finishReason(nil, usage, 5, 2))
+	assert.Equal(t, FinishReasonStop, finishReason(nil, usage, 6, 2))
+
+	info := generationInfo(usage, safe, FinishReasonMaxTokens)
+	assert.Equal(t, FinishReasonMaxTokens, info.FinishReason())
+	assert.Equal(t, 15, info.TotalTokens())
+}
+
+// roundTripperFunc is an http.RoundTripper answering the requests with a
+// function.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// recordingHandler records the callbacks of the LLM calls.
+type recordingHandler struct {
+	callbacks.SimpleHandler
+	events []string
+}
+
+func (h *recordingHandler) HandleLLMGenerateContentStart(_ context.Context, ms []llms.MessageContent) {
+	h.events = append(h.events, "start "+ms[0].Parts[0].(llms.TextContent).Text)
+}
+
+func (h *recordingHandler) HandleLLMGenerateContentEnd(_ context.Context, res *llms.ContentResponse) {
+	h.events = append(h.events, "end "+res.Choices[0].Content)
+}
+
+func (h *recordingHandler) HandleLLMError(_ context.Context, err error) {
+	h.events = append(h.events, "error "+err.Error())
+}
+
+func TestCallbacks(t *testing.T) {
+	t.Parallel()
+
+	fail := false
+	client := &http.Client{Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		if fail {
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"predictions": [{"content": "hello"}]}`)),
+		}, nil
+	})}
+	handler := &recordingHandler{}
+	llm, err := New(WithProjectID("test-project"), WithHTTPClient(client), WithCallback(handler), WithMaxRetries(0))
+	require.NoError(t, err)
+
+	out, err := llm.Call(context.Background(), "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", out)
+
+	fail = true
+	_, err = llm.Call(context.Background(), "again")
+	require.Error(t, err)
+
+	require.Len(t, handler.events, 4)
+	assert.Equal(t, []string{"start hi", "end hello", "start again"}, handler.events[:3])
+	assert.Contains(t, handler.events[3], "error ")
+	assert.Contains(t, handler.events[3], "connection refused")
+}
+
+func TestCallTemplate(t *testing.T) {
+	t.Parallel()
+
+	var body string
+	client := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = string(b)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"predictions": [{"content": "Tokyo"}]}`)),
+		}, nil
+	})}
+	llm, err := New(WithProjectID("test-project"), WithHTTPClient(client), WithMaxRetries(0))
+	require.NoError(t, err)
+
+	out, err := llm.CallTemplate(context.Background(), "What is the capital of {{.country}}?",
+		map[string]any{"country": "Japan"})
+	require.NoError(t, err)
+	assert.Equal(t, "Tokyo", out)
+	assert.Contains(t, body, "What is the capital of Japan?")
+
+	_, err = llm.CallTemplate(context.Background(), "{{.country", nil)
+	require.ErrorContains(t, err, "rendering prompt template")
+}
+
+func TestCredentialsOptionsIsolation(t *testing.T) {
+	t.Parallel()
+
+	a := newOptions(WithCredentialsJSON([]byte(`{"type": "service_account"}`)))
+	b := newOptions(WithCredentialsFile("tenant-b.json"))
+	c := newOptions()
+
+	assert.Len(t, a.clientOptions, 1)
+	assert.Len(t, b.clientOptions, 1)
+	assert.Empty(t, c.clientOptions)
+}
+
+func TestEmbeddingOutputDimensionality(t *testing.T) {
+	t.Parallel()
+
+	opts, err := newEmbeddingOptions(WithOutputDimensionality(256))
+	require.NoError(t, err)
+	assert.Equal(t, 256, opts.request([]string{"hello"}).OutputDimensionality)
+
+	opts, err = newEmbeddingOptions()
+	require.NoError(t, err)
+	assert.Zero(t, opts.request([]string{"hello"}).OutputDimensionality)
+
+	for _, dimensionality := range []int{0, -1} {
+		_, err := newEmbeddingOptions(WithOutputDimensionality(dimensionality))
+		require.ErrorIs(t, err, ErrInvalidOutputDimensionality)
+	}
+}
+
+func TestCallBatch(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	client := &http.Client{Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body: io.NopCloser(strings.NewReader(`{"predictions": [
+				{"content": "Tokyo"},
+				{"content": "", "safetyAttributes": {"blocked": true}},
+				{"content": "Paris"}
+			]}`)),
+		}, nil
+	})}
+	llm, err := New(WithProjectID("test-project"), WithHTTPClient(client), WithMaxRetries(0))
+	require.NoError(t, err)
+	prompts := []string{"japan", "blocked", "france"}
+
+	_, err = llm.CallBatch(context.Background(), prompts)
+	require.Error(t, err)
+
+	out, missing, err := llm.CallBatchPartial(context.Background(), prompts)
+	require.ErrorIs(t, err, ErrEmptyResponse)
+	assert.Equal(t, []string{"Tokyo", "", "Paris"}, out)
+	assert.Equal(t, []int{1}, missing)
+	assert.Equal(t, 2, requests)
+
+	out, err = llm.CallBatch(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, out)
+	assert.Equal(t, 2, requests)
+}
+
+func TestRetryOnEmpty(t *testing.T) {
+	t.Parallel()
+
+	// newLLM returns an LLM whose requests get the responses in turn, and the
+	// number of requests sent.
+	newLLM := func(t *testing.T, responses []string, opts ...Option) (*LLM, *int) {
+		t.Helper()
+		requests := 0
+		client := &http.Client{Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			body := responses[requests]
+			requests++
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(body)),
+			}, nil
+		})}
+		opts = append([]Option{WithProjectID("test-project"), WithHTTPClient(client), WithMaxRetries(0)}, opts...)
+		llm, err := New(opts...)
+		require.NoError(t, err)
+		return llm, &requests
+	}
+	chat := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "Answer briefly."),
+		llms.TextParts(llms.ChatMessageTypeHuman, "What is the capital of Japan?"),
+	}
+
+	llm, requests := newLLM(t, []string{`{"predictions": []}`, `{"predictions": [{"content": "Tokyo"}]}`})
+	_, err := llm.Call(context.Background(), "What is the capital of Japan?")
+	require.Error(t, err)
+	assert.Equal(t, 1, *requests)
+
+	llm, requests = newLLM(t, []string{`{"predictions": []}`, `{"predictions": [{"content": "Tokyo"}]}`},
+		WithRetryOnEmpty(2))
+	out, err := llm.Call(context.Background(), "What is the capital of Japan?")
+	require.NoError(t, err)
+	assert.Equal(t, "Tokyo", out)
+	assert.Equal(t, 2, *requests)
+
+	llm, requests = newLLM(t, []string{
+		`{"predictions": [{"candidates": []}]}`,
+		`{"predictions": [{"candidates": [{"author": "bot", "content": "Tokyo"}]}]}`,
+	}, WithRetryOnEmpty(1))
+	resp, err := llm.GenerateContent(context.Background(), chat)
+	require.NoError(t, err)
+	assert.Equal(t, "Tokyo", resp.Choices[0].Content)
+	assert.Equal(t, 2, *requests)
+
+	llm, requests = newLLM(t, []string{
+		`{"predictions": [{"candidates": [], "safetyAttributes": [{"blocked": true}]}]}`,
+	}, WithRetryOnEmpty(1))
+	_, err = llm.GenerateContent(context.Background(), chat)
+	require.ErrorIs(t, err, ErrContentFiltered)
+	assert.Equal(t, 1, *requests)
+}
+
+func TestWithEndpoint(t *testing.T) {
+	t.Parallel()
+
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"predictions": [{"content": "Tokyo"}]}`)
+	}))
+	t.Cleanup(server.Close)
+
+	llm, err := New(WithProjectID("test-project"), WithHTTPClient(server.Client()),
+		WithEndpoint(server.URL), WithMaxRetries(0))
+	require.NoError(t, err)
+
+	out, err := llm.Call(context.Background(), "What is the capital of Japan?")
+	require.NoError(t, err)
+	assert.Equal(t, "Tokyo", out)
+	assert.Equal(t,
+		"/v1/projects/test-project/locations/us-central1/publishers/google/models/text-bison:predict", path)
+}
+
+func TestGenerateContentPartial(t *testing.T) {
+	t.Parallel()
+
+	responses := []string{
+		`{"predictions": [{"candidates": [{"author": "bot", "content": "Tokyo"}]}]}`,
+		`{"predictions": [{"candidates": []}]}`,
+		`{"predictions": [{"candidates": [{"author": "bot", "content": "Paris"}]}]}`,
+	}
+	requests := 0
+	client := &http.Client{Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		body := responses[requests]
+		requests++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})}
+	llm, err := New(WithProjectID("test-project"), WithHTTPClient(client), WithMaxRetries(0))
+	require.NoError(t, err)
+
+	chat := func(question string) []llms.MessageContent {
+		return []llms.MessageContent{
+			llms.TextParts(llms.ChatMessageTypeSystem, "Answer briefly."),
+			llms.TextParts(llms.ChatMessageTypeHuman, question),
+		}
+	}
+	resps, err := llm.GenerateContentPartial(context.Background(), [][]llms.MessageContent{
+		chat("What is the capital of Japan?"),
+		chat("What is the capital of Atlantis?"),
+		chat("What is the capital of France?"),
+	})
+	require.ErrorIs(t, err, ErrEmptyResponse)
+	assert.ErrorContains(t, err, "message set 1")
+	require.Len(t, resps, 3)
+	assert.Equal(t, "Tokyo", resps[0].Choices[0].Content)
+	assert.Nil(t, resps[1])
+	assert.Equal(t, "Paris", resps[2].Choices[0].Content)
+	assert.Equal(t, 3, requests)
+}
+
+func TestCompletionStopWords(t *testing.T) {
+	t.Parallel()
+
+	var body string
+	client := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = string(b)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"predictions": [{"content": "Tokyo\nQuestion: and France?"}]}`)),
+		}, nil
+	})}
+	llm, err := New(WithProjectID("test-project"), WithHTTPClient(client), WithMaxRetries(0))
+	require.NoError(t, err)
+
+	out, err := llm.Call(context.Background(), "What is the capital of Japan?", llms.WithStopWords([]string{"\nQuestion:"}))
+	require.NoError(t, err)
+	assert.Equal(t, "Tokyo", out)
+	assert.Contains(t, body, `"stopSequences"`)
+
+	texts, err := llm.CallBatch(context.Background(), []string{"What is the capital of Japan?"},
+		llms.WithStopWords([]string{"\nQuestion:"}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Tokyo"}, texts)
+}
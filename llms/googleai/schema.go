@@ -0,0 +1,103 @@
+package googleai
+
+import (
+	"fmt"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// setResponseSchema sets the schema of the JSON responses of the model, a
+// *genai.Schema or the map[string]any of a JSON schema.
+func setResponseSchema(model *genai.GenerativeModel, schema any) error {
+	switch s := schema.(type) {
+	case *genai.Schema:
+		model.ResponseSchema = s
+	case map[string]any:
+		converted, err := convertSchema(s)
+		if err != nil {
+			return fmt.Errorf("response schema: %w", err)
+		}
+		model.ResponseSchema = converted
+	default:
+		return fmt.Errorf("unsupported type %T of response schema", schema)
+	}
+	return nil
+}
+
+// convertSchema converts a JSON schema to a genai schema. Only the type,
+// description, enum, items, properties and required keywords are supported.
+func convertSchema(schema map[string]any) (*genai.Schema, error) {
+	result := &genai.Schema{}
+	if ty, ok := schema["type"]; ok {
+		tyString, ok := ty.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string for type, got %T", ty)
+		}
+		result.Type = convertToolSchemaType(tyString)
+	}
+	if desc, ok := schema["description"]; ok {
+		descString, ok := desc.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string for description, got %T", desc)
+		}
+		result.Description = descString
+	}
+
+	var err error
+	if result.Enum, err = stringList(schema["enum"]); err != nil {
+		return nil, fmt.Errorf("enum: %w", err)
+	}
+	if result.Required, err = stringList(schema["required"]); err != nil {
+		return nil, fmt.Errorf("required: %w", err)
+	}
+
+	if items, ok := schema["items"]; ok {
+		itemsMap, ok := items.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected a map for items, got %T", items)
+		}
+		if result.Items, err = convertSchema(itemsMap); err != nil {
+			return nil, fmt.Errorf("items: %w", err)
+		}
+	}
+
+	if properties, ok := schema["properties"]; ok {
+		propertiesMap, ok := properties.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected a map for properties, got %T", properties)
+		}
+		result.Properties = make(map[string]*genai.Schema, len(propertiesMap))
+		for name, property := range propertiesMap {
+			propertyMap, ok := property.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("property [%v]: expected a map, got %T", name, property)
+			}
+			if result.Properties[name], err = convertSchema(propertyMap); err != nil {
+				return nil, fmt.Errorf("property [%v]: %w", name, err)
+			}
+		}
+	}
+	return result, nil
+}
+
+// stringList converts a list of strings of a JSON schema, nil if absent.
+func stringList(value any) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		return v, nil
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected strings, got %T", item)
+			}
+			list = append(list, s)
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("expected a list of strings, got %T", value)
+	}
+}
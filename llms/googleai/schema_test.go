@@ -0,0 +1,79 @@
+package googleai
+
+import (
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestSetResponseFormat(t *testing.T) {
+	t.Parallel()
+
+	callOptions := func(options ...llms.CallOption) llms.CallOptions {
+		opts := llms.CallOptions{}
+		for _, opt := range options {
+			opt(&opts)
+		}
+		return opts
+	}
+
+	model := &genai.GenerativeModel{}
+	require.NoError(t, setResponseFormat(model, callOptions()))
+	assert.Empty(t, model.ResponseMIMEType)
+	assert.Nil(t, model.ResponseSchema)
+
+	require.NoError(t, setResponseFormat(model, callOptions(llms.WithJSONMode())))
+	assert.Equal(t, "application/json", model.ResponseMIMEType)
+	assert.Nil(t, model.ResponseSchema)
+
+	model = &genai.GenerativeModel{}
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"city":  map[string]any{"type": "string", "description": "The city."},
+			"tags":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"scale": map[string]any{"type": "string", "enum": []any{"small", "large"}},
+		},
+		"required": []any{"city"},
+	}
+	require.NoError(t, setResponseFormat(model, callOptions(llms.WithResponseSchema(schema))))
+	assert.Equal(t, "application/json", model.ResponseMIMEType)
+	assert.Equal(t, &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"city":  {Type: genai.TypeString, Description: "The city."},
+			"tags":  {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}},
+			"scale": {Type: genai.TypeString, Enum: []string{"small", "large"}},
+		},
+		Required: []string{"city"},
+	}, model.ResponseSchema)
+
+	native := &genai.Schema{Type: genai.TypeString}
+	require.NoError(t, setResponseFormat(model, callOptions(llms.WithResponseSchema(native))))
+	assert.Same(t, native, model.ResponseSchema)
+
+	err := setResponseFormat(model, callOptions(llms.WithResponseSchema(map[string]any{"required": "city"})))
+	require.ErrorContains(t, err, "required")
+	err = setResponseFormat(model, callOptions(llms.WithResponseSchema("object")))
+	require.ErrorContains(t, err, "unsupported type string")
+}
+
+func TestCallTools(t *testing.T) {
+	t.Parallel()
+
+	tools := make([]llms.Tool, 1, 2)
+	tools[0] = llms.Tool{Type: "function", Function: &llms.FunctionDefinition{Name: "search"}}
+	got := callTools(llms.CallOptions{
+		Tools:     tools,
+		Functions: []llms.FunctionDefinition{{Name: "weather"}},
+	})
+	require.Len(t, got, 2)
+	assert.Equal(t, "weather", got[1].Function.Name)
+
+	// The tools of the caller aren't overwritten.
+	tools = tools[:2]
+	assert.Nil(t, tools[1].Function)
+}
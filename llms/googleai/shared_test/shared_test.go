@@ -81,6 +81,7 @@ var testConfigs = []testConfig{
 	{testCandidateCountSetting, nil},
 	{testMaxTokensSetting, nil},
 	{testTools, nil},
+	{testFunctions, nil},
 	{
 		testMultiContentText,
 		[]googleai.Option{googleai.WithHarmThreshold(googleai.HarmBlockMediumAndAbove)},
@@ -380,6 +381,40 @@ func testTools(t *testing.T, llm llms.Model) {
 	assert.Regexp(t, "64 and sunny", strings.ToLower(c1.Content))
 }
 
+func testFunctions(t *testing.T, llm llms.Model) {
+	t.Helper()
+	t.Parallel()
+
+	functions := []llms.FunctionDefinition{
+		{
+			Name:        "getCurrentWeather",
+			Description: "Get the current weather in a given location",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"location": map[string]any{
+						"type":        "string",
+						"description": "The city and state, e.g. San Francisco, CA",
+					},
+				},
+				"required": []string{"location"},
+			},
+		},
+	}
+
+	content := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "What is the weather like in Chicago?"),
+	}
+	resp, err := llm.GenerateContent(context.Background(), content, llms.WithFunctions(functions))
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.Choices)
+
+	c1 := resp.Choices[0]
+	require.NotNil(t, c1.FuncCall)
+	assert.Equal(t, "getCurrentWeather", c1.FuncCall.Name)
+	assert.Contains(t, c1.FuncCall.Arguments, "Chicago")
+}
+
 func testMaxTokensSetting(t *testing.T, llm llms.Model) {
 	t.Helper()
 	t.Parallel()
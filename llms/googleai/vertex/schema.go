@@ -0,0 +1,18 @@
+package vertex
+
+import (
+	"errors"
+
+	"cloud.google.com/go/vertexai/genai"
+)
+
+// ErrResponseSchemaNotSupported is returned for the calls with
+// llms.WithResponseSchema, which the Vertex AI SDK doesn't support yet. Use
+// llms.WithJSONMode, describing the schema in the prompt, instead.
+var ErrResponseSchemaNotSupported = errors.New("response schema not supported by vertex")
+
+// setResponseSchema returns ErrResponseSchemaNotSupported: the Vertex AI SDK
+// has no response schema yet.
+func setResponseSchema(_ *genai.GenerativeModel, _ any) error {
+	return ErrResponseSchemaNotSupported
+}
@@ -82,7 +82,10 @@ func (g *Vertex) GenerateContent(ctx context.Context, messages []llms.MessageCon
 		},
 	}
 	var err error
-	if model.Tools, err = convertTools(opts.Tools); err != nil {
+	if model.Tools, err = convertTools(callTools(opts)); err != nil {
+		return nil, err
+	}
+	if err := setResponseFormat(model, opts); err != nil {
 		return nil, err
 	}
 
@@ -111,10 +114,10 @@ func (g *Vertex) GenerateContent(ctx context.Context, messages []llms.MessageCon
 // convertCandidates converts a sequence of genai.Candidate to a response.
 func convertCandidates(candidates []*genai.Candidate) (*llms.ContentResponse, error) {
 	var contentResponse llms.ContentResponse
-	var toolCalls []llms.ToolCall
 
 	for _, candidate := range candidates {
 		buf := strings.Builder{}
+		var toolCalls []llms.ToolCall
 
 		if candidate.Content != nil {
 			for _, part := range candidate.Content.Parts {
@@ -146,13 +149,16 @@ func convertCandidates(candidates []*genai.Candidate) (*llms.ContentResponse, er
 		metadata[CITATIONS] = candidate.CitationMetadata
 		metadata[SAFETY] = candidate.SafetyRatings
 
-		contentResponse.Choices = append(contentResponse.Choices,
-			&llms.ContentChoice{
-				Content:        buf.String(),
-				StopReason:     candidate.FinishReason.String(),
-				GenerationInfo: metadata,
-				ToolCalls:      toolCalls,
-			})
+		choice := &llms.ContentChoice{
+			Content:        buf.String(),
+			StopReason:     candidate.FinishReason.String(),
+			GenerationInfo: metadata,
+			ToolCalls:      toolCalls,
+		}
+		if len(toolCalls) > 0 {
+			choice.FuncCall = toolCalls[0].FunctionCall
+		}
+		contentResponse.Choices = append(contentResponse.Choices, choice)
 	}
 	return &contentResponse, nil
 }
@@ -218,10 +224,8 @@ func convertContent(content llms.MessageContent) (*genai.Content, error) {
 		c.Role = RoleUser
 	case llms.ChatMessageTypeGeneric:
 		c.Role = RoleUser
-	case llms.ChatMessageTypeTool:
+	case llms.ChatMessageTypeTool, llms.ChatMessageTypeFunction:
 		c.Role = RoleUser
-	case llms.ChatMessageTypeFunction:
-		fallthrough
 	default:
 		return nil, fmt.Errorf("role %v not supported", content.Role)
 	}
@@ -333,6 +337,29 @@ DoStream:
 	return convertCandidates([]*genai.Candidate{candidate})
 }
 
+// callTools returns the tools of the call options, including the functions
+// set with the deprecated llms.WithFunctions.
+func callTools(opts llms.CallOptions) []llms.Tool {
+	tools := append([]llms.Tool(nil), opts.Tools...)
+	for i := range opts.Functions {
+		tools = append(tools, llms.Tool{Type: "function", Function: &opts.Functions[i]})
+	}
+	return tools
+}
+
+// setResponseFormat sets the MIME type and schema of the responses of the
+// model in JSON mode.
+func setResponseFormat(model *genai.GenerativeModel, opts llms.CallOptions) error {
+	if !opts.JSONMode {
+		return nil
+	}
+	model.ResponseMIMEType = "application/json"
+	if opts.ResponseSchema == nil {
+		return nil
+	}
+	return setResponseSchema(model, opts.ResponseSchema)
+}
+
 // convertTools converts from a list of langchaingo tools to a list of genai
 // tools.
 func convertTools(tools []llms.Tool) ([]*genai.Tool, error) {
@@ -422,6 +449,8 @@ func convertToolSchemaType(ty string) genai.Type {
 		return genai.TypeInteger
 	case "boolean":
 		return genai.TypeBoolean
+	case "array":
+		return genai.TypeArray
 	default:
 		return genai.TypeUnspecified
 	}
@@ -54,6 +54,7 @@ func (o *LLM) GenerateContent(ctx context.Context, messages []llms.MessageConten
 		MaxLength:         opts.MaxLength,
 		RepetitionPenalty: opts.RepetitionPenalty,
 		Seed:              opts.Seed,
+		Stop:              opts.StopWords,
 	})
 	if err != nil {
 		if o.CallbacksHandler != nil {
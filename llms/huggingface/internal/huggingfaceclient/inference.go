@@ -27,13 +27,14 @@ type inferencePayload struct {
 }
 
 type parameters struct {
-	Temperature       float64 `json:"temperature"`
-	TopP              float64 `json:"top_p,omitempty"`
-	TopK              int     `json:"top_k,omitempty"`
-	MinLength         int     `json:"min_length,omitempty"`
-	MaxLength         int     `json:"max_length,omitempty"`
-	RepetitionPenalty float64 `json:"repetition_penalty,omitempty"`
-	Seed              int     `json:"seed,omitempty"`
+	Temperature       float64  `json:"temperature"`
+	TopP              float64  `json:"top_p,omitempty"`
+	TopK              int      `json:"top_k,omitempty"`
+	MinLength         int      `json:"min_length,omitempty"`
+	MaxLength         int      `json:"max_length,omitempty"`
+	RepetitionPenalty float64  `json:"repetition_penalty,omitempty"`
+	Seed              int      `json:"seed,omitempty"`
+	Stop              []string `json:"stop,omitempty"`
 }
 
 type (
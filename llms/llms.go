@@ -50,3 +50,31 @@ func GenerateFromSinglePrompt(ctx context.Context, llm Model, prompt string, opt
 	c1 := choices[0]
 	return c1.Content, nil
 }
+
+// BatchCaller is implemented by the models generating the completions of
+// several independent prompts in one request, e.g. PaLM's text model.
+type BatchCaller interface {
+	// CallBatch generates a string response for each of the prompts, in
+	// order. It fails if any of the prompts fails.
+	CallBatch(ctx context.Context, prompts []string, options ...CallOption) ([]string, error)
+}
+
+// CallBatch generates a string response for each of the prompts, in order,
+// in one request if llm implements BatchCaller, otherwise with
+// GenerateFromSinglePrompt for each prompt in turn. The first failed prompt
+// fails the batch.
+func CallBatch(ctx context.Context, llm Model, prompts []string, options ...CallOption) ([]string, error) {
+	if caller, ok := llm.(BatchCaller); ok {
+		return caller.CallBatch(ctx, prompts, options...)
+	}
+
+	results := make([]string, 0, len(prompts))
+	for _, prompt := range prompts {
+		result, err := GenerateFromSinglePrompt(ctx, llm, prompt, options...)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,73 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// echoModel is a Model responding with its prompts, failing on "fail".
+type echoModel struct {
+	calls int
+}
+
+func (m *echoModel) GenerateContent(_ context.Context, messages []MessageContent, _ ...CallOption) (*ContentResponse, error) { //nolint:lll
+	m.calls++
+	text := messages[0].Parts[0].(TextContent).Text //nolint:forcetypeassert
+	if text == "fail" {
+		return nil, errors.New("failed")
+	}
+	return &ContentResponse{Choices: []*ContentChoice{{Content: "echo " + text}}}, nil
+}
+
+func (m *echoModel) Call(ctx context.Context, prompt string, options ...CallOption) (string, error) {
+	return GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// batchModel is an echoModel implementing BatchCaller.
+type batchModel struct {
+	echoModel
+	batches [][]string
+}
+
+func (m *batchModel) CallBatch(_ context.Context, prompts []string, _ ...CallOption) ([]string, error) {
+	m.batches = append(m.batches, prompts)
+	results := make([]string, len(prompts))
+	for i, prompt := range prompts {
+		results[i] = "batch " + prompt
+	}
+	return results, nil
+}
+
+func TestCallBatch(t *testing.T) {
+	t.Parallel()
+
+	model := &echoModel{}
+	results, err := CallBatch(context.Background(), model, []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"echo a", "echo b"}; !reflect.DeepEqual(results, want) {
+		t.Errorf("CallBatch() = %v, want %v", results, want)
+	}
+
+	if _, err := CallBatch(context.Background(), model, []string{"fail", "c"}); err == nil {
+		t.Error("CallBatch() succeeded with a failed prompt")
+	}
+	if model.calls != 3 {
+		t.Errorf("%d calls, want 3", model.calls)
+	}
+
+	batch := &batchModel{}
+	results, err = CallBatch(context.Background(), batch, []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"batch a", "batch b"}; !reflect.DeepEqual(results, want) {
+		t.Errorf("CallBatch() = %v, want %v", results, want)
+	}
+	if len(batch.batches) != 1 || batch.calls != 0 {
+		t.Errorf("%d batches and %d calls, want a single batch", len(batch.batches), batch.calls)
+	}
+}
@@ -88,7 +88,7 @@ func (o *LLM) GenerateContent(ctx context.Context, messages []llms.MessageConten
 	resp := &llms.ContentResponse{
 		Choices: []*llms.ContentChoice{
 			{
-				Content: result.Text,
+				Content: llms.TrimStopWords(result.Text, opts.StopWords),
 			},
 		},
 	}
@@ -62,7 +62,7 @@ func (m *Model) Call(ctx context.Context, prompt string, options ...llms.CallOpt
 		return "", errors.New("unexpected response from Mistral SDK, length of the Choices slice must be 1")
 	}
 
-	return res.Choices[0].Message.Content, nil
+	return llms.TrimStopWords(res.Choices[0].Message.Content, callOptions.StopWords), nil
 }
 
 // GenerateContent implements the langchaingo llms.Model interface.
@@ -148,7 +148,7 @@ func generateNonStreamingContent(ctx context.Context, m *Model, callOptions *llm
 	}
 	for idx, choice := range res.Choices {
 		langchainContentResponse.Choices = append(langchainContentResponse.Choices, &llms.ContentChoice{
-			Content:    choice.Message.Content,
+			Content:    llms.TrimStopWords(choice.Message.Content, callOptions.StopWords),
 			StopReason: string(choice.FinishReason),
 			GenerationInfo: map[string]any{
 				"created": res.Created,
@@ -203,6 +203,7 @@ func generateStreamingContent(ctx context.Context, m *Model, callOptions *llms.C
 		}
 	}
 
+	langchainContentResponse.Choices[0].Content = llms.TrimStopWords(langchainContentResponse.Choices[0].Content, callOptions.StopWords)
 	return langchainContentResponse, nil
 }
 
@@ -149,12 +149,8 @@ func (o *LLM) GenerateContent(ctx context.Context, messages []llms.MessageConten
 
 	choices := []*llms.ContentChoice{
 		{
-			Content: resp.Message.Content,
-			GenerationInfo: map[string]any{
-				"CompletionTokens": resp.EvalCount,
-				"PromptTokens":     resp.PromptEvalCount,
-				"TotalTokens":      resp.EvalCount + resp.PromptEvalCount,
-			},
+			Content:        resp.Message.Content,
+			GenerationInfo: llms.NewGenerationInfo(resp.PromptEvalCount, resp.EvalCount, ""),
 		},
 	}
 
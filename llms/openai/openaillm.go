@@ -147,11 +147,8 @@ func (o *LLM) GenerateContent(ctx context.Context, messages []llms.MessageConten
 		choices[i] = &llms.ContentChoice{
 			Content:    c.Message.Content,
 			StopReason: fmt.Sprint(c.FinishReason),
-			GenerationInfo: map[string]any{
-				"CompletionTokens": result.Usage.CompletionTokens,
-				"PromptTokens":     result.Usage.PromptTokens,
-				"TotalTokens":      result.Usage.TotalTokens,
-			},
+			GenerationInfo: llms.NewGenerationInfo(
+				result.Usage.PromptTokens, result.Usage.CompletionTokens, string(c.FinishReason)),
 		}
 
 		// Legacy function call handling
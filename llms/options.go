@@ -42,6 +42,9 @@ type CallOptions struct {
 
 	// JSONMode is a flag to enable JSON mode.
 	JSONMode bool `json:"json"`
+	// ResponseSchema is the JSON schema the response must follow in JSON mode,
+	// for the backends supporting it.
+	ResponseSchema any `json:"response_schema,omitempty"`
 
 	// Tools is a list of tools to use. Each tool can be a specific tool or a function.
 	Tools []Tool `json:"tools,omitempty"`
@@ -134,7 +137,10 @@ func WithTemperature(temperature float64) CallOption {
 	}
 }
 
-// WithStopWords specifies a list of words to stop generation on.
+// WithStopWords specifies a list of words to stop generation on. The models
+// whose API has no stop sequences, mistral, local and cloudflare, trim the
+// generated text at the first stop word with TrimStopWords instead; their
+// streamed chunks aren't trimmed.
 func WithStopWords(stopWords []string) CallOption {
 	return func(o *CallOptions) {
 		o.StopWords = stopWords
@@ -258,6 +264,17 @@ func WithJSONMode() CallOption {
 	}
 }
 
+// WithResponseSchema will add an option to set the response format to JSON
+// following the schema, e.g. a map[string]any of a JSON schema like the
+// parameters of a FunctionDefinition. The backends not supporting schemas
+// fall back to WithJSONMode, or return an error.
+func WithResponseSchema(schema any) CallOption {
+	return func(o *CallOptions) {
+		o.JSONMode = true
+		o.ResponseSchema = schema
+	}
+}
+
 // WithMetadata will add an option to set metadata to include in the request.
 // The meaning of this field is specific to the backend in use.
 func WithMetadata(metadata map[string]interface{}) CallOption {
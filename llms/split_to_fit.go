@@ -0,0 +1,147 @@
+package llms
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TokenCounter is implemented by the models that count the tokens of a text,
+// e.g. with GetNumTokens.
+type TokenCounter interface {
+	GetNumTokens(text string) int
+}
+
+// SplitToFit splits text into pieces of at most maxTokens tokens each, as
+// counted by the model. The pieces are built greedily from whole paragraphs,
+// or from sentences, words and finally characters for the parts of the text
+// too long to fit otherwise. The pieces are trimmed of surrounding white
+// space. If maxTokens isn't positive, the text is returned as a single piece.
+func SplitToFit(model TokenCounter, text string, maxTokens int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	if maxTokens <= 0 || model.GetNumTokens(text) <= maxTokens {
+		return []string{text}
+	}
+	return splitToFit(model, text, maxTokens, 0)
+}
+
+// splitters split a text into units that concatenate back to it, from the
+// coarsest to the finest.
+var splitters = []func(string) []string{ //nolint:gochecknoglobals
+	splitParagraphs,
+	splitSentences,
+	splitWords,
+	splitRunes,
+}
+
+// splitToFit merges the units of text at the given level of splitters into
+// pieces fitting in maxTokens, splitting the units too long to fit at the
+// next level.
+func splitToFit(model TokenCounter, text string, maxTokens int, level int) []string {
+	var (
+		pieces  []string
+		current string
+	)
+	flush := func() {
+		if piece := strings.TrimSpace(current); piece != "" {
+			pieces = append(pieces, piece)
+		}
+		current = ""
+	}
+	fits := func(s string) bool {
+		return model.GetNumTokens(strings.TrimSpace(s)) <= maxTokens
+	}
+
+	for _, unit := range splitters[level](text) {
+		if fits(current + unit) {
+			current += unit
+			continue
+		}
+		flush()
+		switch {
+		case fits(unit):
+			current = unit
+		case level+1 < len(splitters):
+			pieces = append(pieces, splitToFit(model, unit, maxTokens, level+1)...)
+		default:
+			// A single character too long to fit can't be split further.
+			current = unit
+			flush()
+		}
+	}
+	flush()
+	return pieces
+}
+
+// splitParagraphs splits text after each blank line.
+func splitParagraphs(text string) []string {
+	var units []string
+	for {
+		i := strings.Index(text, "\n\n")
+		if i < 0 {
+			break
+		}
+		end := i + 2 //nolint:gomnd
+		for end < len(text) && text[end] == '\n' {
+			end++
+		}
+		units = append(units, text[:end])
+		text = text[end:]
+	}
+	if text != "" {
+		units = append(units, text)
+	}
+	return units
+}
+
+// splitSentences splits text after each sentence-ending punctuation mark
+// followed by white space.
+func splitSentences(text string) []string {
+	var units []string
+	runes := []rune(text)
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		if !strings.ContainsRune(".!?", runes[i]) || i+1 >= len(runes) || !unicode.IsSpace(runes[i+1]) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && unicode.IsSpace(runes[end]) {
+			end++
+		}
+		units = append(units, string(runes[start:end]))
+		start = end
+		i = end - 1
+	}
+	if start < len(runes) {
+		units = append(units, string(runes[start:]))
+	}
+	return units
+}
+
+// splitWords splits text after the white space following each word.
+func splitWords(text string) []string {
+	var units []string
+	runes := []rune(text)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		if !unicode.IsSpace(runes[i]) && unicode.IsSpace(runes[i-1]) {
+			units = append(units, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		units = append(units, string(runes[start:]))
+	}
+	return units
+}
+
+// splitRunes splits text into its characters.
+func splitRunes(text string) []string {
+	units := make([]string, 0, len(text))
+	for _, r := range text {
+		units = append(units, string(r))
+	}
+	return units
+}
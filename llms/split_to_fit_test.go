@@ -0,0 +1,100 @@
+package llms
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// wordCounter counts one token per word.
+type wordCounter struct{}
+
+func (wordCounter) GetNumTokens(text string) int { return len(strings.Fields(text)) }
+
+// runeCounter counts one token per character.
+type runeCounter struct{}
+
+func (runeCounter) GetNumTokens(text string) int { return utf8.RuneCountInString(text) }
+
+func TestSplitToFit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		model     TokenCounter
+		text      string
+		maxTokens int
+		want      []string
+	}{
+		{
+			"fits",
+			wordCounter{},
+			"  One two three.  ",
+			3,
+			[]string{"One two three."},
+		},
+		{
+			"no limit",
+			wordCounter{},
+			"One two three.",
+			0,
+			[]string{"One two three."},
+		},
+		{
+			"empty",
+			wordCounter{},
+			" \n ",
+			3,
+			nil,
+		},
+		{
+			"paragraphs",
+			wordCounter{},
+			"One two.\n\nThree four.\n\n\nFive six seven.",
+			4,
+			[]string{"One two.\n\nThree four.", "Five six seven."},
+		},
+		{
+			"sentences of an oversized paragraph",
+			wordCounter{},
+			"One two. Three four! Five six? Seven.\n\nEight.",
+			3,
+			[]string{"One two.", "Three four!", "Five six? Seven.", "Eight."},
+		},
+		{
+			"words of an oversized sentence",
+			wordCounter{},
+			"One two three four five six seven.",
+			3,
+			[]string{"One two three", "four five six", "seven."},
+		},
+		{
+			"characters of an oversized word",
+			runeCounter{},
+			"abcdefgh ij",
+			3,
+			[]string{"abc", "def", "gh", "ij"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, SplitToFit(tt.model, tt.text, tt.maxTokens))
+		})
+	}
+}
+
+func TestSplitToFitUnsplittable(t *testing.T) {
+	t.Parallel()
+
+	// Every character counts as more than maxTokens: each is hard-split into
+	// its own piece rather than looping forever.
+	pieces := SplitToFit(runeCounter{}, "ab", 0)
+	assert.Equal(t, []string{"ab"}, pieces)
+
+	pieces = splitToFit(runeCounter{}, "ab", 0, 0)
+	assert.Equal(t, []string{"a", "b"}, pieces)
+}
@@ -0,0 +1,17 @@
+package llms
+
+import "strings"
+
+// TrimStopWords trims text at the first occurrence of any of the stop words,
+// for the models whose API doesn't stop the generation on them.
+func TrimStopWords(text string, stopWords []string) string {
+	for _, stop := range stopWords {
+		if stop == "" {
+			continue
+		}
+		if i := strings.Index(text, stop); i >= 0 {
+			text = text[:i]
+		}
+	}
+	return text
+}
@@ -0,0 +1,16 @@
+package llms
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrimStopWords(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "Tokyo", TrimStopWords("Tokyo\nHuman: and Osaka?", []string{"\nHuman:"}))
+	assert.Equal(t, "Tok", TrimStopWords("Tokyo. Osaka.", []string{".", "yo", ""}))
+	assert.Equal(t, "Tokyo", TrimStopWords("Tokyo", []string{"Osaka"}))
+	assert.Equal(t, "Tokyo", TrimStopWords("Tokyo", nil))
+}
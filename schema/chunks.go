@@ -0,0 +1,55 @@
+package schema
+
+const (
+	// ChunkIndexMetadataKey is the metadata key of the index of a chunk among
+	// the chunks of its parent document, set by SplitDocumentMetadata.
+	ChunkIndexMetadataKey = "chunk_index"
+	// SourceMetadataKey is the metadata key of the source of a document, e.g.
+	// its URL or path, set by SplitDocumentMetadata for citations.
+	SourceMetadataKey = "source"
+)
+
+// SplitOption is an option of SplitDocumentMetadata.
+type SplitOption func(*splitOptions)
+
+type splitOptions struct {
+	source    any
+	hasSource bool
+}
+
+// WithChunkSource sets the source of the chunks of SplitDocumentMetadata,
+// overriding the source of the parent document.
+func WithChunkSource(source string) SplitOption {
+	return func(o *splitOptions) {
+		o.source = source
+		o.hasSource = true
+	}
+}
+
+// SplitDocumentMetadata returns a document for each chunk of the content of
+// the parent document, e.g. split by a text splitter, ready to be added to a
+// vector store. Each chunk has a copy of the metadata of the parent, with its
+// index among the chunks under ChunkIndexMetadataKey and its source under
+// SourceMetadataKey: the one of WithChunkSource, or the source of the parent.
+// The source key isn't set if neither is known.
+func SplitDocumentMetadata(parent Document, chunks []string, options ...SplitOption) []Document {
+	opts := splitOptions{}
+	opts.source, opts.hasSource = parent.Metadata[SourceMetadataKey]
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	docs := make([]Document, len(chunks))
+	for i, chunk := range chunks {
+		metadata := make(map[string]any, len(parent.Metadata)+2) //nolint:gomnd
+		for k, v := range parent.Metadata {
+			metadata[k] = v
+		}
+		if opts.hasSource {
+			metadata[SourceMetadataKey] = opts.source
+		}
+		metadata[ChunkIndexMetadataKey] = i
+		docs[i] = Document{PageContent: chunk, Metadata: metadata}
+	}
+	return docs
+}
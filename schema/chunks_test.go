@@ -0,0 +1,49 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitDocumentMetadata(t *testing.T) {
+	t.Parallel()
+
+	parent := Document{
+		PageContent: "Tokyo. Osaka.",
+		Metadata:    map[string]any{"source": "japan.txt", "lang": "en"},
+	}
+	docs := SplitDocumentMetadata(parent, []string{"Tokyo.", "Osaka."})
+	require.Len(t, docs, 2)
+	assert.Equal(t, Document{
+		PageContent: "Tokyo.",
+		Metadata:    map[string]any{"source": "japan.txt", "lang": "en", ChunkIndexMetadataKey: 0},
+	}, docs[0])
+	assert.Equal(t, Document{
+		PageContent: "Osaka.",
+		Metadata:    map[string]any{"source": "japan.txt", "lang": "en", ChunkIndexMetadataKey: 1},
+	}, docs[1])
+
+	// The metadata of the chunks are copies.
+	docs[0].Metadata["lang"] = "ja"
+	assert.Equal(t, "en", parent.Metadata["lang"])
+	assert.Equal(t, "en", docs[1].Metadata["lang"])
+	assert.NotContains(t, parent.Metadata, ChunkIndexMetadataKey)
+
+	docs = SplitDocumentMetadata(parent, []string{"Tokyo."}, WithChunkSource("https://example.com/japan"))
+	assert.Equal(t, "https://example.com/japan", docs[0].Metadata[SourceMetadataKey])
+}
+
+func TestSplitDocumentMetadataNilMetadata(t *testing.T) {
+	t.Parallel()
+
+	docs := SplitDocumentMetadata(Document{PageContent: "Tokyo."}, []string{"Tokyo."})
+	require.Len(t, docs, 1)
+	assert.Equal(t, map[string]any{ChunkIndexMetadataKey: 0}, docs[0].Metadata)
+
+	docs = SplitDocumentMetadata(Document{}, []string{"Tokyo."}, WithChunkSource("japan.txt"))
+	assert.Equal(t, map[string]any{ChunkIndexMetadataKey: 0, SourceMetadataKey: "japan.txt"}, docs[0].Metadata)
+
+	assert.Empty(t, SplitDocumentMetadata(Document{}, nil))
+}
@@ -0,0 +1,29 @@
+package vectorstores
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tmc/langchaingo/schema"
+)
+
+// ContentHash returns the hex-encoded SHA-256 of the page content of a
+// document and of its metadata values of the given keys, in order. It is the
+// ID of the documents added with WithContentHashID, which the stores may
+// reformat into their own ID format.
+func ContentHash(doc schema.Document, metadataKeys ...string) string {
+	h := sha256.New()
+	h.Write([]byte(doc.PageContent))
+	for _, key := range metadataKeys {
+		value, err := json.Marshal(doc.Metadata[key])
+		if err != nil {
+			value = []byte(fmt.Sprint(doc.Metadata[key]))
+		}
+		// The keys and values are length-prefixed so that no two sets of
+		// them hash the same.
+		fmt.Fprintf(h, "\x00%d:%s%d:%s", len(key), key, len(value), value)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,88 @@
+package vectorstores
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// EvalCase is a query of EvaluateRecall, with the IDs of the documents
+// relevant to it.
+type EvalCase struct {
+	Query string
+	// RelevantIDs are the IDs the relevant documents are returned with under
+	// IDMetadataKey.
+	RelevantIDs []string
+}
+
+// EvalResult is the result of EvaluateRecall.
+type EvalResult struct {
+	// Recall is the mean recall@k of the cases: the fraction of their
+	// relevant documents among the k first results of their search.
+	Recall float64
+	// MeanLatency is the mean duration of the searches.
+	MeanLatency time.Duration
+	// Recalls are the recall@k of each case, in the order of the cases.
+	Recalls []float64
+}
+
+// EvaluateRecall runs SimilaritySearch for the query of each case in turn,
+// with the k and the options to evaluate, e.g. a score threshold, and reports
+// the recall@k and the mean latency of the searches, to pick the search
+// parameters of a store empirically. The documents are identified by their ID
+// under IDMetadataKey, so the store must set it. The first error is returned.
+func EvaluateRecall(
+	ctx context.Context,
+	store VectorStore,
+	cases []EvalCase,
+	k int,
+	options ...Option,
+) (EvalResult, error) {
+	if k <= 0 {
+		return EvalResult{}, errors.New("k must be positive")
+	}
+	if len(cases) == 0 {
+		return EvalResult{}, errors.New("no evaluation cases")
+	}
+
+	result := EvalResult{Recalls: make([]float64, len(cases))}
+	var total time.Duration
+	for i, c := range cases {
+		if len(c.RelevantIDs) == 0 {
+			return EvalResult{}, fmt.Errorf("case %q has no relevant IDs", c.Query)
+		}
+
+		start := time.Now()
+		docs, err := store.SimilaritySearch(ctx, c.Query, k, options...)
+		total += time.Since(start)
+		if err != nil {
+			return EvalResult{}, fmt.Errorf("searching %q: %w", c.Query, err)
+		}
+		if len(docs) > k {
+			docs = docs[:k]
+		}
+
+		relevant := make(map[string]bool, len(c.RelevantIDs))
+		for _, id := range c.RelevantIDs {
+			relevant[id] = true
+		}
+		found := 0
+		for _, doc := range docs {
+			id, ok := doc.Metadata[IDMetadataKey]
+			if !ok || id == nil {
+				continue
+			}
+			if key := fmt.Sprint(id); relevant[key] {
+				found++
+				// A document returned twice is found once.
+				delete(relevant, key)
+			}
+		}
+		result.Recalls[i] = float64(found) / float64(len(c.RelevantIDs))
+		result.Recall += result.Recalls[i]
+	}
+	result.Recall /= float64(len(cases))
+	result.MeanLatency = total / time.Duration(len(cases))
+	return result, nil
+}
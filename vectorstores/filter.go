@@ -0,0 +1,143 @@
+package vectorstores
+
+import "errors"
+
+// ErrUnsupportedFilter is returned by the vector stores for the filters they
+// can't translate into their query language.
+var ErrUnsupportedFilter = errors.New("unsupported filter")
+
+// Operator is the comparison operator of a Filter on a metadata field.
+type Operator string
+
+const (
+	OperatorEq  Operator = "eq"
+	OperatorNe  Operator = "ne"
+	OperatorGt  Operator = "gt"
+	OperatorGte Operator = "gte"
+	OperatorLt  Operator = "lt"
+	OperatorLte Operator = "lte"
+	// OperatorIn matches the fields equal to one of the values of a slice.
+	OperatorIn Operator = "in"
+)
+
+// Combinator is the boolean combinator of a Filter of filters.
+type Combinator string
+
+const (
+	CombinatorAnd Combinator = "and"
+	CombinatorOr  Combinator = "or"
+	CombinatorNot Combinator = "not"
+)
+
+// Filter is a backend-agnostic metadata filter, passed to WithFilters and
+// translated by each vector store into its own query language. It is either a
+// comparison of a metadata field with a value, or a combination of filters.
+// Filters are built with Eq, Ne, Gt, Gte, Lt, Lte, In, And, Or and Not:
+//
+//	vectorstores.WithFilters(vectorstores.And(
+//		vectorstores.Eq("country", "japan"),
+//		vectorstores.Or(vectorstores.Gte("population", 1000000), vectorstores.Eq("capital", true)),
+//	))
+type Filter struct {
+	// Field, Operator and Value are the comparison of a leaf filter.
+	Field    string
+	Operator Operator
+	Value    any
+
+	// Combinator combines the Filters of a combined filter. The filters of
+	// a CombinatorNot filter must all not match.
+	Combinator Combinator
+	Filters    []Filter
+}
+
+// Eq returns a filter matching the documents whose field equals value.
+func Eq(field string, value any) Filter {
+	return Filter{Field: field, Operator: OperatorEq, Value: value}
+}
+
+// Ne returns a filter matching the documents whose field doesn't equal value.
+func Ne(field string, value any) Filter {
+	return Filter{Field: field, Operator: OperatorNe, Value: value}
+}
+
+// Gt returns a filter matching the documents whose field is greater than value.
+func Gt(field string, value any) Filter {
+	return Filter{Field: field, Operator: OperatorGt, Value: value}
+}
+
+// Gte returns a filter matching the documents whose field is greater than or
+// equal to value.
+func Gte(field string, value any) Filter {
+	return Filter{Field: field, Operator: OperatorGte, Value: value}
+}
+
+// Lt returns a filter matching the documents whose field is less than value.
+func Lt(field string, value any) Filter {
+	return Filter{Field: field, Operator: OperatorLt, Value: value}
+}
+
+// Lte returns a filter matching the documents whose field is less than or
+// equal to value.
+func Lte(field string, value any) Filter {
+	return Filter{Field: field, Operator: OperatorLte, Value: value}
+}
+
+// In returns a filter matching the documents whose field equals one of the
+// values.
+func In(field string, values ...any) Filter {
+	return Filter{Field: field, Operator: OperatorIn, Value: values}
+}
+
+// And returns a filter matching the documents matched by all the filters.
+func And(filters ...Filter) Filter {
+	return Filter{Combinator: CombinatorAnd, Filters: filters}
+}
+
+// Or returns a filter matching the documents matched by at least one of the
+// filters.
+func Or(filters ...Filter) Filter {
+	return Filter{Combinator: CombinatorOr, Filters: filters}
+}
+
+// Not returns a filter matching the documents matched by none of the filters.
+func Not(filters ...Filter) Filter {
+	return Filter{Combinator: CombinatorNot, Filters: filters}
+}
+
+// Values returns the values of an OperatorIn filter.
+func (f Filter) Values() []any {
+	values, _ := f.Value.([]any)
+	return values
+}
+
+// Number returns the value of a filter as a float64, if it is a number.
+func (f Filter) Number() (float64, bool) {
+	switch v := f.Value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
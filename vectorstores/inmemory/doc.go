@@ -0,0 +1,5 @@
+// Package inmemory contains an implementation of the VectorStore interface
+// that keeps the documents and their vectors in memory and searches them by
+// brute force. It has no dependencies, and is meant as a test double for the
+// code using a vector store.
+package inmemory
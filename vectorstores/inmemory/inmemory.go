@@ -0,0 +1,332 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// ErrInvalidScoreThreshold is returned when the score threshold isn't
+// between 0 and 1.
+var ErrInvalidScoreThreshold = errors.New("score threshold must be between 0 and 1")
+
+// Store is an in-memory vector store. It is safe for concurrent use.
+type Store struct {
+	embedder embeddings.Embedder
+
+	mu sync.RWMutex
+	// entries are the entries of each name space, in insertion order.
+	entries map[string][]entry
+}
+
+// entry is a stored document and its vector.
+type entry struct {
+	id     string
+	doc    schema.Document
+	vector []float32
+}
+
+var _ vectorstores.VectorStore = &Store{}
+
+// New creates an empty in-memory vector store.
+func New(opts ...Option) (*Store, error) {
+	return applyClientOptions(opts...)
+}
+
+// AddDocuments embeds and stores the documents, in the name space of the
+// options, and returns their generated IDs. The documents for which the
+// deduplicater of the options returns true are skipped. With
+// vectorstores.WithContentHashID, the IDs are the content hashes of the
+// documents, and a document overwrites the stored document of the same ID.
+func (s *Store) AddDocuments(ctx context.Context,
+	docs []schema.Document,
+	options ...vectorstores.Option,
+) ([]string, error) {
+	opts := s.getOptions(options...)
+
+	docs = s.deduplicate(ctx, opts, docs)
+	if len(docs) == 0 {
+		return nil, nil
+	}
+
+	texts := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		texts = append(texts, doc.PageContent)
+	}
+	vectors, err := s.getEmbedder(opts).EmbedDocuments(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(docs) {
+		return nil, errors.New("number of vectors from embedder does not match number of documents")
+	}
+
+	ids := make([]string, 0, len(docs))
+	entries := make([]entry, 0, len(docs))
+	for i, doc := range docs {
+		id := uuid.NewString()
+		if opts.ContentHashID {
+			id = vectorstores.ContentHash(doc, opts.ContentHashKeys...)
+		}
+		ids = append(ids, id)
+		entries = append(entries, entry{id: id, doc: copyDocument(doc), vector: vectors[i]})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries == nil {
+		s.entries = make(map[string][]entry)
+	}
+	for _, e := range entries {
+		s.entries[opts.NameSpace] = upsert(s.entries[opts.NameSpace], e)
+	}
+
+	return ids, nil
+}
+
+// SimilaritySearch returns the numDocuments documents of the name space of
+// the options most similar to the query by cosine similarity, with the
+// similarity as their score. The options filters are either a
+// map[string]any, matching the documents whose metadata has all of its
+// values, or a vectorstores.Filter.
+func (s *Store) SimilaritySearch(ctx context.Context,
+	query string,
+	numDocuments int,
+	options ...vectorstores.Option,
+) ([]schema.Document, error) {
+	opts := s.getOptions(options...)
+
+	if opts.ScoreThreshold < 0 || opts.ScoreThreshold > 1 {
+		return nil, ErrInvalidScoreThreshold
+	}
+
+	vector, err := s.getEmbedder(opts).EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type match struct {
+		entry entry
+		score float32
+	}
+	var matches []match
+	for _, e := range s.entries[opts.NameSpace] {
+		ok, err := matchFilters(opts.Filters, e.doc.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		score := float32(cosineSimilarity(vector, e.vector))
+		if score < opts.ScoreThreshold {
+			continue
+		}
+		matches = append(matches, match{entry: e, score: score})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	if numDocuments >= 0 && len(matches) > numDocuments {
+		matches = matches[:numDocuments]
+	}
+
+	docs := make([]schema.Document, 0, len(matches))
+	for _, m := range matches {
+		doc := copyDocument(m.entry.doc)
+		doc.Score = m.score
+		if opts.IncludeVectors {
+			if doc.Metadata == nil {
+				doc.Metadata = map[string]any{}
+			}
+			doc.Metadata[vectorstores.VectorMetadataKey] = append([]float32(nil), m.entry.vector...)
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// Len returns the number of documents stored in the name space.
+func (s *Store) Len(nameSpace string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entries[nameSpace])
+}
+
+func (s *Store) getOptions(options ...vectorstores.Option) vectorstores.Options {
+	opts := vectorstores.Options{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	return opts
+}
+
+func (s *Store) getEmbedder(opts vectorstores.Options) embeddings.Embedder {
+	if opts.Embedder != nil {
+		return opts.Embedder
+	}
+	return s.embedder
+}
+
+func (s *Store) deduplicate(ctx context.Context,
+	opts vectorstores.Options,
+	docs []schema.Document,
+) []schema.Document {
+	if opts.Deduplicater == nil {
+		return docs
+	}
+
+	filtered := make([]schema.Document, 0, len(docs))
+	for _, doc := range docs {
+		if !opts.Deduplicater(ctx, doc) {
+			filtered = append(filtered, doc)
+		}
+	}
+
+	return filtered
+}
+
+// upsert replaces the entry of the same ID as e, or appends e.
+func upsert(entries []entry, e entry) []entry {
+	for i := range entries {
+		if entries[i].id == e.id {
+			entries[i] = e
+			return entries
+		}
+	}
+	return append(entries, e)
+}
+
+// copyDocument returns a copy of the document with its own metadata map, so
+// that the stored documents aren't changed by the callers.
+func copyDocument(doc schema.Document) schema.Document {
+	if doc.Metadata != nil {
+		metadata := make(map[string]any, len(doc.Metadata))
+		for k, v := range doc.Metadata {
+			metadata[k] = v
+		}
+		doc.Metadata = metadata
+	}
+	return doc
+}
+
+// matchFilters reports whether the metadata matches the filters of the
+// options.
+func matchFilters(filters any, metadata map[string]any) (bool, error) {
+	switch f := filters.(type) {
+	case nil:
+		return true, nil
+	case map[string]any:
+		for k, v := range f {
+			if !equal(metadata[k], v) {
+				return false, nil
+			}
+		}
+		return true, nil
+	case vectorstores.Filter:
+		return matchFilter(f, metadata)
+	case *vectorstores.Filter:
+		return matchFilter(*f, metadata)
+	default:
+		return false, fmt.Errorf("%w: filters of type %T", vectorstores.ErrUnsupportedFilter, filters)
+	}
+}
+
+// matchFilter reports whether the metadata matches a backend-agnostic filter.
+func matchFilter(f vectorstores.Filter, metadata map[string]any) (bool, error) {
+	switch f.Combinator {
+	case "":
+	case vectorstores.CombinatorAnd, vectorstores.CombinatorOr, vectorstores.CombinatorNot:
+		for _, child := range f.Filters {
+			ok, err := matchFilter(child, metadata)
+			if err != nil {
+				return false, err
+			}
+			switch {
+			case ok && f.Combinator == vectorstores.CombinatorOr:
+				return true, nil
+			case !ok && f.Combinator == vectorstores.CombinatorAnd:
+				return false, nil
+			case ok && f.Combinator == vectorstores.CombinatorNot:
+				return false, nil
+			}
+		}
+		return f.Combinator != vectorstores.CombinatorOr, nil
+	default:
+		return false, fmt.Errorf("%w: combinator %q", vectorstores.ErrUnsupportedFilter, f.Combinator)
+	}
+
+	value, found := metadata[f.Field]
+	switch f.Operator {
+	case vectorstores.OperatorEq:
+		return found && equal(value, f.Value), nil
+	case vectorstores.OperatorNe:
+		return !found || !equal(value, f.Value), nil
+	case vectorstores.OperatorIn:
+		for _, v := range f.Values() {
+			if found && equal(value, v) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case vectorstores.OperatorGt, vectorstores.OperatorGte, vectorstores.OperatorLt, vectorstores.OperatorLte:
+		n, ok := f.Number()
+		if !ok {
+			return false, fmt.Errorf("%w: %s of %q with non-numeric value %v",
+				vectorstores.ErrUnsupportedFilter, f.Operator, f.Field, f.Value)
+		}
+		m, ok := vectorstores.Filter{Value: value}.Number()
+		if !ok {
+			return false, nil
+		}
+		switch f.Operator { //nolint:exhaustive
+		case vectorstores.OperatorGt:
+			return m > n, nil
+		case vectorstores.OperatorGte:
+			return m >= n, nil
+		case vectorstores.OperatorLt:
+			return m < n, nil
+		default:
+			return m <= n, nil
+		}
+	default:
+		return false, fmt.Errorf("%w: operator %q", vectorstores.ErrUnsupportedFilter, f.Operator)
+	}
+}
+
+// equal reports whether two metadata values are equal, comparing the numbers
+// of different types by value.
+func equal(a, b any) bool {
+	if x, ok := (vectorstores.Filter{Value: a}).Number(); ok {
+		if y, ok := (vectorstores.Filter{Value: b}).Number(); ok {
+			return x == y
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// cosineSimilarity returns the cosine similarity of two vectors, or 0 if one
+// of them is zero.
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := 0; i < len(a) && i < len(b); i++ {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,175 @@
+package inmemory_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+	"github.com/tmc/langchaingo/vectorstores/inmemory"
+)
+
+// fakeEmbedder embeds every text as the counts of the letters a, b and c.
+type fakeEmbedder struct{}
+
+func (e fakeEmbedder) EmbedDocuments(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+func (e fakeEmbedder) EmbedQuery(_ context.Context, text string) ([]float32, error) {
+	return e.embed(text), nil
+}
+
+func (fakeEmbedder) embed(text string) []float32 {
+	return []float32{
+		float32(strings.Count(text, "a")),
+		float32(strings.Count(text, "b")),
+		float32(strings.Count(text, "c")),
+	}
+}
+
+func newTestStore(t *testing.T) *inmemory.Store {
+	t.Helper()
+
+	store, err := inmemory.New(inmemory.WithEmbedder(fakeEmbedder{}))
+	require.NoError(t, err)
+	ids, err := store.AddDocuments(context.Background(), []schema.Document{
+		{PageContent: "aaa", Metadata: map[string]any{"kind": "a", "rank": 1}},
+		{PageContent: "aab", Metadata: map[string]any{"kind": "a", "rank": 2}},
+		{PageContent: "bbb", Metadata: map[string]any{"kind": "b", "rank": 3}},
+		{PageContent: "ccc", Metadata: map[string]any{"kind": "c", "rank": 4}},
+	})
+	require.NoError(t, err)
+	require.Len(t, ids, 4)
+	return store
+}
+
+func contents(docs []schema.Document) []string {
+	result := make([]string, len(docs))
+	for i, doc := range docs {
+		result[i] = doc.PageContent
+	}
+	return result
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	_, err := inmemory.New()
+	require.ErrorIs(t, err, inmemory.ErrInvalidOptions)
+}
+
+func TestSimilaritySearch(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	docs, err := store.SimilaritySearch(ctx, "a", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aaa", "aab"}, contents(docs))
+	assert.InDelta(t, 1, docs[0].Score, 1e-6)
+	assert.Equal(t, map[string]any{"kind": "a", "rank": 1}, docs[0].Metadata)
+
+	docs, err = store.SimilaritySearch(ctx, "a", 10, vectorstores.WithScoreThreshold(0.5))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aaa", "aab"}, contents(docs))
+
+	_, err = store.SimilaritySearch(ctx, "a", 10, vectorstores.WithScoreThreshold(1.5))
+	require.ErrorIs(t, err, inmemory.ErrInvalidScoreThreshold)
+
+	docs, err = store.SimilaritySearch(ctx, "a", 1, vectorstores.WithIncludeVectors(true))
+	require.NoError(t, err)
+	assert.Equal(t, []float32{3, 0, 0}, docs[0].Metadata[vectorstores.VectorMetadataKey])
+
+	docs, err = store.SimilaritySearch(ctx, "a", 10, vectorstores.WithNameSpace("other"))
+	require.NoError(t, err)
+	assert.Empty(t, docs)
+}
+
+func TestSimilaritySearchFilters(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		filters any
+		want    []string
+	}{
+		{"metadata", map[string]any{"kind": "a", "rank": 2.0}, []string{"aab"}},
+		{"eq", vectorstores.Eq("kind", "b"), []string{"bbb"}},
+		{"ne", vectorstores.Ne("kind", "a"), []string{"bbb", "ccc"}},
+		{"in", vectorstores.In("kind", "a", "c"), []string{"aaa", "aab", "ccc"}},
+		{
+			"combinators",
+			vectorstores.Or(
+				vectorstores.And(vectorstores.Gte("rank", 2), vectorstores.Lt("rank", 4)),
+				vectorstores.Not(vectorstores.Lte("rank", 3)),
+			),
+			[]string{"aab", "bbb", "ccc"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			docs, err := store.SimilaritySearch(ctx, "abc", 10, vectorstores.WithFilters(tt.filters))
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, contents(docs))
+		})
+	}
+
+	_, err := store.SimilaritySearch(ctx, "a", 10, vectorstores.WithFilters(vectorstores.Gt("kind", "a")))
+	require.ErrorIs(t, err, vectorstores.ErrUnsupportedFilter)
+	_, err = store.SimilaritySearch(ctx, "a", 10, vectorstores.WithFilters("kind = a"))
+	require.ErrorIs(t, err, vectorstores.ErrUnsupportedFilter)
+}
+
+func TestAddDocumentsDeduplicater(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	ids, err := store.AddDocuments(context.Background(), []schema.Document{
+		{PageContent: "aaa"},
+		{PageContent: "abc"},
+	}, vectorstores.WithDeduplicater(func(_ context.Context, doc schema.Document) bool {
+		return doc.PageContent == "aaa"
+	}))
+	require.NoError(t, err)
+	assert.Len(t, ids, 1)
+	assert.Equal(t, 5, store.Len(""))
+}
+
+func TestAddDocumentsContentHashID(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	ctx := context.Background()
+	docs := []schema.Document{
+		{PageContent: "abc", Metadata: map[string]any{"source": "x", "page": 1}},
+		{PageContent: "abc", Metadata: map[string]any{"source": "y", "page": 2}},
+	}
+
+	ids, err := store.AddDocuments(ctx, docs, vectorstores.WithContentHashID())
+	require.NoError(t, err)
+	assert.Equal(t, ids[0], ids[1])
+	assert.Equal(t, 5, store.Len(""))
+
+	again, err := store.AddDocuments(ctx, docs, vectorstores.WithContentHashID("source"))
+	require.NoError(t, err)
+	assert.NotEqual(t, again[0], again[1])
+	assert.Equal(t, 7, store.Len(""))
+
+	_, err = store.AddDocuments(ctx, docs, vectorstores.WithContentHashID("source"))
+	require.NoError(t, err)
+	assert.Equal(t, 7, store.Len(""))
+}
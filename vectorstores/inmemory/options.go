@@ -0,0 +1,35 @@
+package inmemory
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tmc/langchaingo/embeddings"
+)
+
+// ErrInvalidOptions is returned when the options given are invalid.
+var ErrInvalidOptions = errors.New("invalid options")
+
+// Option is a function that configures a Store.
+type Option func(s *Store)
+
+// WithEmbedder returns an Option for setting the embedder to be used when
+// adding documents or doing similarity search. Required.
+func WithEmbedder(embedder embeddings.Embedder) Option {
+	return func(s *Store) {
+		s.embedder = embedder
+	}
+}
+
+func applyClientOptions(opts ...Option) (*Store, error) {
+	s := &Store{}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.embedder == nil {
+		return nil, fmt.Errorf("%w: missing embedder", ErrInvalidOptions)
+	}
+
+	return s, nil
+}
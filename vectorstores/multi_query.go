@@ -0,0 +1,91 @@
+package vectorstores
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/tmc/langchaingo/schema"
+)
+
+// MultiQuerySearch runs SimilaritySearch for each of the queries, e.g.
+// paraphrases of a question, concurrently with the options, and returns the
+// union of their results: the numDocuments documents with the best scores.
+// The documents found by several queries are returned once with their best
+// score, identified by their ID under IDMetadataKey if set, by their content
+// otherwise. The first error cancels the other searches and is returned.
+func MultiQuerySearch(
+	ctx context.Context,
+	store VectorStore,
+	queries []string,
+	numDocuments int,
+	options ...Option,
+) ([]schema.Document, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]schema.Document, len(queries))
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for i, query := range queries {
+		wg.Add(1)
+		go func(i int, query string) {
+			defer wg.Done()
+			docs, err := store.SimilaritySearch(ctx, query, numDocuments, options...)
+			if err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("searching %q: %w", query, err)
+					cancel()
+				})
+				return
+			}
+			results[i] = docs
+		}(i, query)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return mergeResults(results, numDocuments), nil
+}
+
+// mergeResults returns the numDocuments best scoring documents of the results
+// of several searches, each document once with its best score.
+func mergeResults(results [][]schema.Document, numDocuments int) []schema.Document {
+	best := map[string]int{}
+	var merged []schema.Document
+	for _, docs := range results {
+		for _, doc := range docs {
+			key := documentKey(doc)
+			if i, ok := best[key]; ok {
+				if doc.Score > merged[i].Score {
+					merged[i] = doc
+				}
+				continue
+			}
+			best[key] = len(merged)
+			merged = append(merged, doc)
+		}
+	}
+
+	// The documents of the first queries come first among equal scores.
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Score > merged[j].Score })
+	if len(merged) > numDocuments {
+		merged = merged[:numDocuments]
+	}
+	return merged
+}
+
+// documentKey returns the key identifying a document among the results of
+// several searches.
+func documentKey(doc schema.Document) string {
+	if id, ok := doc.Metadata[IDMetadataKey]; ok && id != nil {
+		return fmt.Sprintf("id:%v", id)
+	}
+	return "content:" + doc.PageContent
+}
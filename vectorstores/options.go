@@ -2,11 +2,21 @@ package vectorstores
 
 import (
 	"context"
+	"time"
 
 	"github.com/tmc/langchaingo/embeddings"
 	"github.com/tmc/langchaingo/schema"
 )
 
+// VectorMetadataKey is the metadata key of the stored vector of the documents
+// returned by a search with WithIncludeVectors.
+const VectorMetadataKey = "_vector"
+
+// IDMetadataKey is the metadata key of the ID of the documents returned by the
+// stores setting it, e.g. qdrant with qdrant.WithIDKey(""). MultiQuerySearch
+// and EvaluateRecall identify the documents by it.
+const IDMetadataKey = "_id"
+
 // Option is a function that configures an Options.
 type Option func(*Options)
 
@@ -17,6 +27,11 @@ type Options struct {
 	Filters        any
 	Embedder       embeddings.Embedder
 	Deduplicater   func(context.Context, schema.Document) bool
+	IncludeVectors bool
+	// ContentHashID and ContentHashKeys are set by WithContentHashID.
+	ContentHashID   bool
+	ContentHashKeys []string
+	Timeout         time.Duration
 }
 
 // WithNameSpace returns an Option for setting the name space.
@@ -36,6 +51,8 @@ func WithScoreThreshold(scoreThreshold float32) Option {
 // filters retrieve exactly the number of nearest-neighbors results that match the filters. In
 // most cases the search latency will be lower than unfiltered searches
 // See https://docs.pinecone.io/docs/metadata-filtering
+// The filters are either in the query language of the vector store, or a Filter
+// for the stores that translate it.
 func WithFilters(filters any) Option {
 	return func(o *Options) {
 		o.Filters = filters
@@ -59,3 +76,41 @@ func WithDeduplicater(fn func(ctx context.Context, doc schema.Document) bool) Op
 		o.Deduplicater = fn
 	}
 }
+
+// WithIncludeVectors returns an Option for returning the stored vectors of the
+// documents found by a search, as a []float32 in their metadata under
+// VectorMetadataKey. This is useful for client-side re-ranking or clustering.
+func WithIncludeVectors(include bool) Option {
+	return func(o *Options) {
+		o.IncludeVectors = include
+	}
+}
+
+// WithContentHashID returns an Option for deriving the ID of the added
+// documents from a hash of their content and of the metadata values of the
+// given keys, see ContentHash. Identical documents then get the same ID, so
+// adding them again overwrites them instead of duplicating them.
+func WithContentHashID(metadataKeys ...string) Option {
+	return func(o *Options) {
+		o.ContentHashID = true
+		o.ContentHashKeys = metadataKeys
+	}
+}
+
+// WithTimeout returns an Option for bounding the duration of an operation of
+// a vector store, independently of the deadline of its context: the earlier
+// of both applies. This is a safety net against hung requests.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = timeout
+	}
+}
+
+// TimeoutContext returns a copy of ctx canceled after the timeout of the
+// options, if set, for the stores to run their operations with.
+func TimeoutContext(ctx context.Context, opts Options) (context.Context, context.CancelFunc) {
+	if opts.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, opts.Timeout)
+}
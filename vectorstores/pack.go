@@ -0,0 +1,50 @@
+package vectorstores
+
+import (
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+)
+
+// PackOption is an option of PackToTokenBudget.
+type PackOption func(*packOptions)
+
+type packOptions struct {
+	reservedTokens int
+}
+
+// WithReservedTokens reserves tokens of the budget of PackToTokenBudget for
+// the rest of the prompt, e.g. its instructions and question.
+func WithReservedTokens(tokens int) PackOption {
+	return func(o *packOptions) {
+		o.reservedTokens = tokens
+	}
+}
+
+// PackToTokenBudget returns the documents, e.g. returned by SimilaritySearch,
+// whose contents fit together in maxTokens tokens as counted by the model,
+// less the tokens reserved with WithReservedTokens. The documents are included
+// greedily in order, the ones too long to fit in the remaining budget being
+// skipped for the next ones.
+func PackToTokenBudget(
+	model llms.TokenCounter,
+	docs []schema.Document,
+	maxTokens int,
+	options ...PackOption,
+) []schema.Document {
+	opts := packOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	budget := maxTokens - opts.reservedTokens
+	var packed []schema.Document
+	for _, doc := range docs {
+		tokens := model.GetNumTokens(doc.PageContent)
+		if tokens > budget {
+			continue
+		}
+		budget -= tokens
+		packed = append(packed, doc)
+	}
+	return packed
+}
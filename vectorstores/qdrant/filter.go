@@ -0,0 +1,155 @@
+package qdrant
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// Filter is a Qdrant filter, built with Match, MatchAny, Range and the And,
+// Or and Not combinators, and passed to vectorstores.WithFilters:
+//
+//	vectorstores.WithFilters(qdrant.And(
+//		qdrant.Match("country", "japan"),
+//		qdrant.Or(qdrant.Range("population", 1e6, math.Inf(1)), qdrant.Match("capital", true)),
+//	))
+//
+// It has the structure of the filters of the REST API, with must, should and
+// must_not clauses, and works with the gRPC API too.
+type Filter map[string]any
+
+// Match returns a filter matching the points whose payload field has the
+// given value, a string, an integer or a bool.
+func Match(field string, value any) Filter {
+	return Filter{"must": []any{map[string]any{
+		"key":   field,
+		"match": map[string]any{"value": value},
+	}}}
+}
+
+// MatchAny returns a filter matching the points whose payload field has one of
+// the given values, strings or integers.
+func MatchAny(field string, values ...any) Filter {
+	return Filter{"must": []any{map[string]any{
+		"key":   field,
+		"match": map[string]any{"any": values},
+	}}}
+}
+
+// Range returns a filter matching the points whose payload field is between
+// gte and lte, inclusive. An infinite bound, math.Inf(-1) or math.Inf(1),
+// leaves the range open on its side.
+func Range(field string, gte, lte float64) Filter {
+	r := map[string]any{}
+	if !math.IsInf(gte, 0) {
+		r["gte"] = gte
+	}
+	if !math.IsInf(lte, 0) {
+		r["lte"] = lte
+	}
+	return Filter{"must": []any{map[string]any{"key": field, "range": r}}}
+}
+
+// And returns a filter matching the points matched by all the filters.
+func And(filters ...Filter) Filter {
+	must := make([]any, 0, len(filters))
+	for _, f := range filters {
+		if conditions, ok := f.mustOnly(); ok {
+			must = append(must, conditions...)
+			continue
+		}
+		must = append(must, f)
+	}
+	return Filter{"must": must}
+}
+
+// Or returns a filter matching the points matched by at least one of the
+// filters.
+func Or(filters ...Filter) Filter {
+	return Filter{"should": conditions(filters)}
+}
+
+// Not returns a filter matching the points matched by none of the filters.
+func Not(filters ...Filter) Filter {
+	return Filter{"must_not": conditions(filters)}
+}
+
+// conditions returns the filters as the conditions of a clause, unwrapping
+// the filters of a single condition.
+func conditions(filters []Filter) []any {
+	result := make([]any, 0, len(filters))
+	for _, f := range filters {
+		if conditions, ok := f.mustOnly(); ok && len(conditions) == 1 {
+			result = append(result, conditions[0])
+			continue
+		}
+		result = append(result, f)
+	}
+	return result
+}
+
+// mustOnly returns the conditions of a filter that only has a must clause.
+func (f Filter) mustOnly() ([]any, bool) {
+	if len(f) != 1 {
+		return nil, false
+	}
+	must, ok := f["must"].([]any)
+	return must, ok
+}
+
+// fromFilter translates a backend-agnostic filter into a Qdrant filter.
+func fromFilter(f vectorstores.Filter) (Filter, error) {
+	if f.Combinator != "" {
+		filters := make([]Filter, len(f.Filters))
+		for i, child := range f.Filters {
+			filter, err := fromFilter(child)
+			if err != nil {
+				return nil, err
+			}
+			filters[i] = filter
+		}
+		switch f.Combinator {
+		case vectorstores.CombinatorAnd:
+			return And(filters...), nil
+		case vectorstores.CombinatorOr:
+			return Or(filters...), nil
+		case vectorstores.CombinatorNot:
+			return Not(filters...), nil
+		default:
+			return nil, fmt.Errorf("%w: combinator %q", vectorstores.ErrUnsupportedFilter, f.Combinator)
+		}
+	}
+
+	switch f.Operator {
+	case vectorstores.OperatorEq:
+		// Qdrant only matches exact keywords, integers and bools: other
+		// numbers are matched with a range of a single value.
+		if n, ok := f.Number(); ok && n != math.Trunc(n) {
+			return Range(f.Field, n, n), nil
+		}
+		return Match(f.Field, f.Value), nil
+	case vectorstores.OperatorNe:
+		eq := f
+		eq.Operator = vectorstores.OperatorEq
+		filter, err := fromFilter(eq)
+		if err != nil {
+			return nil, err
+		}
+		return Not(filter), nil
+	case vectorstores.OperatorGt, vectorstores.OperatorGte, vectorstores.OperatorLt, vectorstores.OperatorLte:
+		n, ok := f.Number()
+		if !ok {
+			return nil, fmt.Errorf("%w: %s of %q with non-numeric value %v",
+				vectorstores.ErrUnsupportedFilter, f.Operator, f.Field, f.Value)
+		}
+		return Filter{"must": []any{map[string]any{
+			"key":   f.Field,
+			"range": map[string]any{string(f.Operator): n},
+		}}}, nil
+	case vectorstores.OperatorIn:
+		return MatchAny(f.Field, f.Values()...), nil
+	default:
+		return nil, fmt.Errorf("%w: operator %q", vectorstores.ErrUnsupportedFilter, f.Operator)
+	}
+}
@@ -0,0 +1,86 @@
+package qdrant
+
+import (
+	"math"
+	"testing"
+
+	pb "github.com/qdrant/go-client/qdrant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+func TestFilterBuilders(t *testing.T) {
+	t.Parallel()
+
+	country := map[string]any{"key": "country", "match": map[string]any{"value": "japan"}}
+	population := map[string]any{"key": "population", "range": map[string]any{"gte": 1e6}}
+	capital := map[string]any{"key": "capital", "match": map[string]any{"value": true}}
+
+	filter := And(
+		Match("country", "japan"),
+		Or(Range("population", 1e6, math.Inf(1)), Match("capital", true)),
+		Not(MatchAny("region", "kanto", "kansai")),
+	)
+	assert.Equal(t, Filter{"must": []any{
+		country,
+		Filter{"should": []any{population, capital}},
+		Filter{"must_not": []any{
+			map[string]any{"key": "region", "match": map[string]any{"any": []any{"kanto", "kansai"}}},
+		}},
+	}}, filter)
+
+	assert.Equal(t, Filter{"must": []any{
+		map[string]any{"key": "year", "range": map[string]any{"gte": 2000.0, "lte": 2024.0}},
+	}}, Range("year", 2000, 2024))
+}
+
+func TestFilterBuildersGRPC(t *testing.T) {
+	t.Parallel()
+
+	filter, err := toGRPCFilter(And(
+		Match("country", "japan"),
+		Or(Range("population", 1e6, math.Inf(1)), Match("capital", true)),
+	))
+	require.NoError(t, err)
+	require.Len(t, filter.GetMust(), 2)
+	assert.Equal(t, "japan", filter.GetMust()[0].GetField().GetMatch().GetKeyword())
+
+	should := filter.GetMust()[1].GetFilter().GetShould()
+	require.Len(t, should, 2)
+	assert.Equal(t, &pb.Range{Gte: ptr(1e6)}, should[0].GetField().GetRange())
+	assert.True(t, should[1].GetField().GetMatch().GetBoolean())
+}
+
+func TestFromFilter(t *testing.T) {
+	t.Parallel()
+
+	filter, err := fromFilter(vectorstores.And(
+		vectorstores.Eq("country", "japan"),
+		vectorstores.Ne("capital", true),
+		vectorstores.Gt("population", 1000000),
+		vectorstores.Eq("area", 377.9),
+		vectorstores.Or(vectorstores.In("continent", "asia", "oceania"), vectorstores.Lte("rank", 10)),
+	))
+	require.NoError(t, err)
+	assert.Equal(t, Filter{"must": []any{
+		map[string]any{"key": "country", "match": map[string]any{"value": "japan"}},
+		Filter{"must_not": []any{map[string]any{"key": "capital", "match": map[string]any{"value": true}}}},
+		map[string]any{"key": "population", "range": map[string]any{"gt": 1e6}},
+		map[string]any{"key": "area", "range": map[string]any{"gte": 377.9, "lte": 377.9}},
+		Filter{"should": []any{
+			map[string]any{"key": "continent", "match": map[string]any{"any": []any{"asia", "oceania"}}},
+			map[string]any{"key": "rank", "range": map[string]any{"lte": 10.0}},
+		}},
+	}}, filter)
+
+	_, err = fromFilter(vectorstores.Gt("country", "japan"))
+	require.ErrorIs(t, err, vectorstores.ErrUnsupportedFilter)
+	_, err = fromFilter(vectorstores.Filter{Field: "country", Operator: "like", Value: "j%"})
+	require.ErrorIs(t, err, vectorstores.ErrUnsupportedFilter)
+	require.ErrorIs(t, err, ErrUnsupportedFilter)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,201 @@
+package qdrant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	pb "github.com/qdrant/go-client/qdrant"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// SimilaritySearchGrouped returns the documents most similar to the query,
+// grouped by the value of their groupBy metadata key: at most groupSize
+// documents for each of the limit best groups, e.g. the best chunks of the
+// limit most relevant source files. The documents are returned group after
+// group, the best group first, each group in the order of its scores. The
+// documents without a value of the key aren't returned.
+func (s Store) SimilaritySearchGrouped(ctx context.Context,
+	query string, groupBy string, groupSize, limit int,
+	options ...vectorstores.Option,
+) ([]schema.Document, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+	if groupBy == "" {
+		return nil, errors.New("missing group by key")
+	}
+	if groupSize <= 0 || limit <= 0 {
+		return nil, errors.New("group size and limit must be positive")
+	}
+
+	opts := s.getOptions(options...)
+	ctx, cancel := vectorstores.TimeoutContext(ctx, opts)
+	defer cancel()
+
+	filters, err := s.getFilters(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	scoreThreshold, err := s.getScoreThreshold(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	vector, err := s.embedQuery(ctx, opts, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.normalizeScores {
+		return s.searchGroups(ctx, vector, s.groupByField(groupBy), groupSize, limit, scoreThreshold, filters)
+	}
+
+	// As in searchDense, the threshold applies to the normalized scores.
+	distance, err := s.collectionDistance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := s.searchGroups(ctx, vector, s.groupByField(groupBy), groupSize, limit, 0, filters)
+	if err != nil {
+		return nil, err
+	}
+	n := 0
+	for _, doc := range docs {
+		score, err := NormalizeScore(distance, doc.Score)
+		if err != nil {
+			return nil, err
+		}
+		if score < scoreThreshold {
+			continue
+		}
+		doc.Score = score
+		if s.scoreKey != "" {
+			// The metadata was set by scoredDocument.
+			doc.Metadata[s.scoreKey] = score
+		}
+		docs[n] = doc
+		n++
+	}
+	return docs[:n], nil
+}
+
+// groupByField returns the payload field of a metadata key.
+func (s Store) groupByField(key string) string {
+	if s.nestedPayload {
+		return metadataKey + "." + key
+	}
+	return key
+}
+
+// searchGroups searches the points most similar to the vector, grouped by the
+// payload field, and returns the documents of the groups one after the other.
+func (s Store) searchGroups(
+	ctx context.Context,
+	vector []float32,
+	groupBy string,
+	groupSize, limit int,
+	scoreThreshold float32,
+	filter any,
+) ([]schema.Document, error) {
+	if s.grpc != nil {
+		return s.grpcSearchGroups(ctx, vector, groupBy, groupSize, limit, scoreThreshold, filter)
+	}
+
+	var searchVector any = vector
+	if s.vectorName != "" {
+		searchVector = namedVector{Name: s.vectorName, Vector: vector}
+	}
+	payload := searchGroupsBody{
+		Vector:         searchVector,
+		Filter:         filter,
+		Limit:          limit,
+		GroupBy:        groupBy,
+		GroupSize:      groupSize,
+		ScoreThreshold: scoreThreshold,
+		WithPayload:    s.payloadSelector(),
+		Params:         s.searchParams(),
+	}
+
+	url := s.withReadConsistency(s.qdrantURL.JoinPath("collections", s.collectionName, "points", "search", "groups"))
+	body, statusCode, err := s.doRequest(ctx, *url, http.MethodPost, payload)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	if statusCode != http.StatusOK {
+		return nil, newAPIError("querying collection groups", statusCode, body)
+	}
+
+	var response searchGroupsResponse
+	if err := json.NewDecoder(body).Decode(&response); err != nil {
+		return nil, err
+	}
+	var docs []schema.Document
+	for _, group := range response.Result.Groups {
+		for _, hit := range group.Hits {
+			doc, err := s.scoredDocument(normalizeID(strings.Trim(string(hit.ID), `"`)), hit.Payload, hit.Score)
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// grpcSearchGroups is searchGroups over gRPC.
+func (s Store) grpcSearchGroups(
+	ctx context.Context,
+	vector []float32,
+	groupBy string,
+	groupSize, limit int,
+	scoreThreshold float32,
+	filter any,
+) ([]schema.Document, error) {
+	grpcFilter, err := toGRPCFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &pb.SearchPointGroups{
+		CollectionName:  s.collectionName,
+		Vector:          vector,
+		Filter:          grpcFilter,
+		Limit:           uint32(limit),
+		WithPayload:     s.grpcPayloadSelector(),
+		GroupBy:         groupBy,
+		GroupSize:       uint32(groupSize),
+		Params:          s.grpcSearchParams(),
+		ReadConsistency: s.grpcReadConsistency(),
+	}
+	if scoreThreshold != 0 {
+		req.ScoreThreshold = &scoreThreshold
+	}
+	if s.vectorName != "" {
+		req.VectorName = &s.vectorName
+	}
+
+	resp, err := s.grpc.points.SearchGroups(s.grpcContext(ctx), req)
+	if err != nil {
+		return nil, fmt.Errorf("querying collection groups: %w", err)
+	}
+
+	var docs []schema.Document
+	for _, group := range resp.GetResult().GetGroups() {
+		for _, hit := range group.GetHits() {
+			doc, err := s.scoredDocument(normalizeID(fromGRPCPointID(hit.GetId())), fromGRPCPayload(hit.GetPayload()), hit.GetScore())
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
@@ -0,0 +1,795 @@
+package qdrant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	pb "github.com/qdrant/go-client/qdrant"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ErrUnsupportedFilter is returned when a filter can't be converted to a
+// filter of the Qdrant gRPC API. It is vectorstores.ErrUnsupportedFilter, also
+// returned for the vectorstores.Filter that can't be translated.
+var ErrUnsupportedFilter = vectorstores.ErrUnsupportedFilter
+
+// grpcClient is the gRPC transport of a Store.
+type grpcClient struct {
+	conn        *grpc.ClientConn
+	points      pb.PointsClient
+	collections pb.CollectionsClient
+}
+
+// newGRPCClient creates a gRPC client of the Qdrant instance at addr. The
+// connection is insecure unless the dial options set transport credentials.
+func newGRPCClient(addr string, opts ...grpc.DialOption) (*grpcClient, error) {
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+	conn, err := grpc.NewClient(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcClient{
+		conn:        conn,
+		points:      pb.NewPointsClient(conn),
+		collections: pb.NewCollectionsClient(conn),
+	}, nil
+}
+
+// grpcContext returns the context of a gRPC request, authenticated with the
+// credentials of the Store.
+func (s Store) grpcContext(ctx context.Context) context.Context {
+	for key, value := range s.authHeaders() {
+		ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+	}
+	return ctx
+}
+
+// grpcGetCollection returns the information of the Qdrant collection, or nil
+// if it doesn't exist.
+func (s Store) grpcGetCollection(ctx context.Context) (*collectionInfo, error) {
+	resp, err := s.grpc.collections.Get(s.grpcContext(ctx), &pb.GetCollectionInfoRequest{
+		CollectionName: s.collectionName,
+	})
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting collection: %w", err)
+	}
+
+	config := resp.GetResult().GetConfig().GetParams().GetVectorsConfig()
+	params := config.GetParams()
+	if s.vectorName != "" {
+		params = config.GetParamsMap().GetMap()[s.vectorName]
+	}
+	return &collectionInfo{vectorSize: params.GetSize(), distance: params.GetDistance().String()}, nil
+}
+
+// grpcCreateCollection creates the Qdrant collection with a single vector of
+// the given size and distance metric.
+func (s Store) grpcCreateCollection(ctx context.Context, vectorSize uint64, distance string) error {
+	params := &pb.VectorParams{
+		Size:     vectorSize,
+		Distance: pb.Distance(pb.Distance_value[distance]),
+	}
+	config := &pb.VectorsConfig{Config: &pb.VectorsConfig_Params{Params: params}}
+	if s.vectorName != "" {
+		config = &pb.VectorsConfig{Config: &pb.VectorsConfig_ParamsMap{
+			ParamsMap: &pb.VectorParamsMap{Map: map[string]*pb.VectorParams{s.vectorName: params}},
+		}}
+	}
+
+	req := &pb.CreateCollection{
+		CollectionName: s.collectionName,
+		VectorsConfig:  config,
+	}
+	if s.sparseEmbedder != nil {
+		req.SparseVectorsConfig = &pb.SparseVectorConfig{
+			Map: map[string]*pb.SparseVectorParams{s.sparseVectorName: {}},
+		}
+	}
+
+	_, err := s.grpc.collections.Create(s.grpcContext(ctx), req)
+	if err != nil {
+		return fmt.Errorf("creating collection: %w", err)
+	}
+	return nil
+}
+
+// grpcFieldTypes are the gRPC field types of the payload schema types.
+var grpcFieldTypes = map[string]pb.FieldType{ //nolint:gochecknoglobals
+	PayloadSchemaKeyword: pb.FieldType_FieldTypeKeyword,
+	PayloadSchemaInteger: pb.FieldType_FieldTypeInteger,
+	PayloadSchemaFloat:   pb.FieldType_FieldTypeFloat,
+	PayloadSchemaBool:    pb.FieldType_FieldTypeBool,
+	PayloadSchemaText:    pb.FieldType_FieldTypeText,
+}
+
+// grpcCreateFieldIndex creates an index of a payload field of the Qdrant
+// collection.
+func (s Store) grpcCreateFieldIndex(ctx context.Context, field string, schemaType string) error {
+	fieldType := grpcFieldTypes[schemaType]
+	wait := true
+	_, err := s.grpc.points.CreateFieldIndex(s.grpcContext(ctx), &pb.CreateFieldIndexCollection{
+		CollectionName: s.collectionName,
+		Wait:           &wait,
+		FieldName:      field,
+		FieldType:      &fieldType,
+	})
+	if err != nil {
+		return fmt.Errorf("creating payload index: %w", err)
+	}
+	return nil
+}
+
+// grpcUpsertBatch updates or inserts a single batch of points into the Qdrant
+// collection.
+func (s Store) grpcUpsertBatch(
+	ctx context.Context,
+	ids []string,
+	vectors [][]float32,
+	sparseVectors []SparseVector,
+	payloads []map[string]interface{},
+) error {
+	points := make([]*pb.PointStruct, len(ids))
+	for i, id := range ids {
+		payload, err := toGRPCPayload(payloads[i])
+		if err != nil {
+			return err
+		}
+		points[i] = &pb.PointStruct{
+			Id:      toGRPCPointID(id),
+			Payload: payload,
+			Vectors: s.toGRPCVectors(vectors[i]),
+		}
+		if sparseVectors != nil {
+			points[i].Vectors = s.toGRPCNamedVectors(vectors[i], sparseVectors[i])
+		}
+	}
+
+	wait := s.writeWait
+	_, err := s.grpc.points.Upsert(s.grpcContext(ctx), &pb.UpsertPoints{
+		CollectionName: s.collectionName,
+		Wait:           &wait,
+		Points:         points,
+		Ordering:       s.grpcWriteOrdering(),
+	})
+	if err != nil {
+		return fmt.Errorf("upserting vectors: %w", err)
+	}
+	return nil
+}
+
+// grpcDeletePoints deletes the points with the given IDs, or matching the
+// filter, from the Qdrant collection.
+func (s Store) grpcDeletePoints(ctx context.Context, ids []string, filter any) error {
+	pointIDs := make([]*pb.PointId, len(ids))
+	for i, id := range ids {
+		pointIDs[i] = toGRPCPointID(id)
+	}
+	grpcFilter, err := toGRPCFilter(filter)
+	if err != nil {
+		return err
+	}
+
+	var selector *pb.PointsSelector
+	switch {
+	case grpcFilter == nil:
+		selector = &pb.PointsSelector{PointsSelectorOneOf: &pb.PointsSelector_Points{
+			Points: &pb.PointsIdsList{Ids: pointIDs},
+		}}
+	case len(ids) == 0:
+		selector = &pb.PointsSelector{PointsSelectorOneOf: &pb.PointsSelector_Filter{Filter: grpcFilter}}
+	default:
+		selector = &pb.PointsSelector{PointsSelectorOneOf: &pb.PointsSelector_Filter{Filter: &pb.Filter{
+			Must: []*pb.Condition{
+				{ConditionOneOf: &pb.Condition_HasId{HasId: &pb.HasIdCondition{HasId: pointIDs}}},
+				{ConditionOneOf: &pb.Condition_Filter{Filter: grpcFilter}},
+			},
+		}}}
+	}
+
+	wait := true
+	_, err = s.grpc.points.Delete(s.grpcContext(ctx), &pb.DeletePoints{
+		CollectionName: s.collectionName,
+		Wait:           &wait,
+		Points:         selector,
+	})
+	if err != nil {
+		return fmt.Errorf("deleting points: %w", err)
+	}
+	return nil
+}
+
+// grpcSearchPoints queries the Qdrant collection for points based on the
+// provided parameters, with a dense []float32 or a SparseVector.
+func (s Store) grpcSearchPoints(
+	ctx context.Context,
+	vector any,
+	numVectors int,
+	scoreThreshold float32,
+	filter any,
+	withVector bool,
+) ([]string, []schema.Document, [][]float32, error) {
+	grpcFilter, err := toGRPCFilter(filter)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	req := &pb.SearchPoints{
+		CollectionName:  s.collectionName,
+		Filter:          grpcFilter,
+		Limit:           uint64(numVectors),
+		WithPayload:     s.grpcPayloadSelector(),
+		WithVectors:     &pb.WithVectorsSelector{SelectorOptions: &pb.WithVectorsSelector_Enable{Enable: withVector}},
+		Params:          s.grpcSearchParams(),
+		ReadConsistency: s.grpcReadConsistency(),
+	}
+	if scoreThreshold != 0 {
+		req.ScoreThreshold = &scoreThreshold
+	}
+	switch v := vector.(type) {
+	case SparseVector:
+		req.Vector = v.Values
+		req.SparseIndices = &pb.SparseIndices{Data: v.Indices}
+		req.VectorName = &s.sparseVectorName
+	case []float32:
+		req.Vector = v
+		if s.vectorName != "" {
+			req.VectorName = &s.vectorName
+		}
+	}
+
+	resp, err := s.grpc.points.Search(s.grpcContext(ctx), req)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("querying collection: %w", err)
+	}
+
+	ids := make([]string, len(resp.GetResult()))
+	docs := make([]schema.Document, len(resp.GetResult()))
+	var vectors [][]float32
+	if withVector {
+		vectors = make([][]float32, len(resp.GetResult()))
+	}
+	for i, match := range resp.GetResult() {
+		ids[i] = normalizeID(fromGRPCPointID(match.GetId()))
+		doc, err := s.scoredDocument(ids[i], fromGRPCPayload(match.GetPayload()), match.GetScore())
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		docs[i] = doc
+		if withVector {
+			vectors[i], err = s.fromGRPCVectors(match.GetVectors())
+			if err != nil {
+				return nil, nil, nil, err
+			}
+		}
+	}
+
+	return ids, docs, vectors, nil
+}
+
+// grpcScroll returns the points of the Qdrant collection matching the filter,
+// starting at the given offset point ID if set, along with the ID to start the
+// next page at, empty after the last page.
+func (s Store) grpcScroll(
+	ctx context.Context,
+	numVectors int,
+	filter any,
+	offset string,
+) ([]schema.Document, string, error) {
+	grpcFilter, err := toGRPCFilter(filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	limit := uint32(numVectors)
+	req := &pb.ScrollPoints{
+		CollectionName: s.collectionName,
+		Filter:         grpcFilter,
+		Limit:          &limit,
+		WithPayload:    &pb.WithPayloadSelector{SelectorOptions: &pb.WithPayloadSelector_Enable{Enable: true}},
+	}
+	if offset != "" {
+		req.Offset = toGRPCPointID(offset)
+	}
+
+	resp, err := s.grpc.points.Scroll(s.grpcContext(ctx), req)
+	if err != nil {
+		return nil, "", fmt.Errorf("querying collection: %w", err)
+	}
+
+	docs := make([]schema.Document, len(resp.GetResult()))
+	for i, match := range resp.GetResult() {
+		doc, err := s.document(normalizeID(fromGRPCPointID(match.GetId())), fromGRPCPayload(match.GetPayload()))
+		if err != nil {
+			return nil, "", err
+		}
+		docs[i] = doc
+	}
+
+	return docs, fromGRPCPointID(resp.GetNextPageOffset()), nil
+}
+
+// grpcRetrievePoints returns the documents of the points with the given IDs,
+// by point ID, over gRPC.
+func (s Store) grpcRetrievePoints(ctx context.Context, ids []string) (map[string]schema.Document, error) {
+	req := &pb.GetPoints{
+		CollectionName: s.collectionName,
+		Ids:            make([]*pb.PointId, len(ids)),
+		WithPayload:    &pb.WithPayloadSelector{SelectorOptions: &pb.WithPayloadSelector_Enable{Enable: true}},
+	}
+	for i, id := range ids {
+		req.Ids[i] = toGRPCPointID(id)
+	}
+
+	resp, err := s.grpc.points.Get(s.grpcContext(ctx), req)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving points: %w", err)
+	}
+
+	docs := make(map[string]schema.Document, len(resp.GetResult()))
+	for _, point := range resp.GetResult() {
+		id := normalizeID(fromGRPCPointID(point.GetId()))
+		doc, err := s.document(id, fromGRPCPayload(point.GetPayload()))
+		if err != nil {
+			return nil, err
+		}
+		docs[id] = doc
+	}
+	return docs, nil
+}
+
+// grpcPayloadSelector returns the payload selector of the searches, like
+// payloadSelector.
+func (s Store) grpcPayloadSelector() *pb.WithPayloadSelector {
+	include, exclude := s.selectedPayload()
+	switch {
+	case include != nil:
+		return &pb.WithPayloadSelector{SelectorOptions: &pb.WithPayloadSelector_Include{
+			Include: &pb.PayloadIncludeSelector{Fields: include},
+		}}
+	case exclude != nil:
+		return &pb.WithPayloadSelector{SelectorOptions: &pb.WithPayloadSelector_Exclude{
+			Exclude: &pb.PayloadExcludeSelector{Fields: exclude},
+		}}
+	default:
+		return &pb.WithPayloadSelector{SelectorOptions: &pb.WithPayloadSelector_Enable{Enable: true}}
+	}
+}
+
+// toGRPCVectors returns the vectors of a point, under the vector name if set.
+func (s Store) toGRPCVectors(vector []float32) *pb.Vectors {
+	if s.vectorName != "" {
+		return &pb.Vectors{VectorsOptions: &pb.Vectors_Vectors{Vectors: &pb.NamedVectors{
+			Vectors: map[string]*pb.Vector{s.vectorName: {Data: vector}},
+		}}}
+	}
+	return &pb.Vectors{VectorsOptions: &pb.Vectors_Vector{Vector: &pb.Vector{Data: vector}}}
+}
+
+// toGRPCNamedVectors returns the dense and sparse vectors of a point, the
+// dense one named "" if the vector name isn't set.
+func (s Store) toGRPCNamedVectors(vector []float32, sparse SparseVector) *pb.Vectors {
+	return &pb.Vectors{VectorsOptions: &pb.Vectors_Vectors{Vectors: &pb.NamedVectors{
+		Vectors: map[string]*pb.Vector{
+			s.vectorName: {Data: vector},
+			s.sparseVectorName: {
+				Data:    sparse.Values,
+				Indices: &pb.SparseIndices{Data: sparse.Indices},
+			},
+		},
+	}}}
+}
+
+// fromGRPCVectors returns the vector of a point: the default vector, or the
+// named one if set.
+func (s Store) fromGRPCVectors(vectors *pb.Vectors) ([]float32, error) {
+	if s.vectorName == "" && s.sparseEmbedder == nil {
+		if vector := vectors.GetVector(); vector != nil {
+			return vector.GetData(), nil
+		}
+		return nil, errors.New("result does not contain the default vector")
+	}
+
+	vector, ok := vectors.GetVectors().GetVectors()[s.vectorName]
+	if !ok {
+		return nil, fmt.Errorf("result does not contain vector '%s'", s.vectorName)
+	}
+	return vector.GetData(), nil
+}
+
+// toGRPCPointID returns the gRPC point ID of an ID.
+func toGRPCPointID(id string) *pb.PointId {
+	if n, ok := pointID(id).(uint64); ok {
+		return &pb.PointId{PointIdOptions: &pb.PointId_Num{Num: n}}
+	}
+	return &pb.PointId{PointIdOptions: &pb.PointId_Uuid{Uuid: id}}
+}
+
+// fromGRPCPointID returns the ID of a gRPC point ID, empty if nil.
+func fromGRPCPointID(id *pb.PointId) string {
+	switch v := id.GetPointIdOptions().(type) {
+	case *pb.PointId_Num:
+		return strconv.FormatUint(v.Num, 10)
+	case *pb.PointId_Uuid:
+		return v.Uuid
+	default:
+		return ""
+	}
+}
+
+// toGRPCPayload converts a payload to gRPC values.
+func toGRPCPayload(payload map[string]interface{}) (map[string]*pb.Value, error) {
+	values := make(map[string]*pb.Value, len(payload))
+	for key, value := range payload {
+		v, err := toGRPCValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("payload field %q: %w", key, err)
+		}
+		values[key] = v
+	}
+	return values, nil
+}
+
+// toGRPCValue converts a value to a gRPC value. Values of types other than
+// the JSON ones are converted through their JSON encoding.
+func toGRPCValue(value any) (*pb.Value, error) { //nolint:cyclop
+	switch v := value.(type) {
+	case nil:
+		return &pb.Value{Kind: &pb.Value_NullValue{NullValue: pb.NullValue_NULL_VALUE}}, nil
+	case bool:
+		return &pb.Value{Kind: &pb.Value_BoolValue{BoolValue: v}}, nil
+	case string:
+		return &pb.Value{Kind: &pb.Value_StringValue{StringValue: v}}, nil
+	case int:
+		return &pb.Value{Kind: &pb.Value_IntegerValue{IntegerValue: int64(v)}}, nil
+	case int32:
+		return &pb.Value{Kind: &pb.Value_IntegerValue{IntegerValue: int64(v)}}, nil
+	case int64:
+		return &pb.Value{Kind: &pb.Value_IntegerValue{IntegerValue: v}}, nil
+	case float32:
+		return &pb.Value{Kind: &pb.Value_DoubleValue{DoubleValue: float64(v)}}, nil
+	case float64:
+		return &pb.Value{Kind: &pb.Value_DoubleValue{DoubleValue: v}}, nil
+	case []any:
+		list := &pb.ListValue{Values: make([]*pb.Value, len(v))}
+		for i, item := range v {
+			itemValue, err := toGRPCValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list.Values[i] = itemValue
+		}
+		return &pb.Value{Kind: &pb.Value_ListValue{ListValue: list}}, nil
+	case map[string]any:
+		fields, err := toGRPCPayload(v)
+		if err != nil {
+			return nil, err
+		}
+		return &pb.Value{Kind: &pb.Value_StructValue{StructValue: &pb.Struct{Fields: fields}}}, nil
+	default:
+		var generic any
+		if err := jsonRoundTrip(v, &generic); err != nil {
+			return nil, err
+		}
+		return toGRPCValue(generic)
+	}
+}
+
+// fromGRPCPayload converts a gRPC payload to plain values.
+func fromGRPCPayload(payload map[string]*pb.Value) map[string]interface{} {
+	values := make(map[string]interface{}, len(payload))
+	for key, value := range payload {
+		values[key] = fromGRPCValue(value)
+	}
+	return values
+}
+
+// fromGRPCValue converts a gRPC value to a plain value.
+func fromGRPCValue(value *pb.Value) any {
+	switch v := value.GetKind().(type) {
+	case *pb.Value_BoolValue:
+		return v.BoolValue
+	case *pb.Value_StringValue:
+		return v.StringValue
+	case *pb.Value_IntegerValue:
+		return v.IntegerValue
+	case *pb.Value_DoubleValue:
+		return v.DoubleValue
+	case *pb.Value_ListValue:
+		list := make([]any, len(v.ListValue.GetValues()))
+		for i, item := range v.ListValue.GetValues() {
+			list[i] = fromGRPCValue(item)
+		}
+		return list
+	case *pb.Value_StructValue:
+		return fromGRPCPayload(v.StructValue.GetFields())
+	default:
+		return nil
+	}
+}
+
+// toGRPCFilter converts a filter to a gRPC filter. The filter is either a
+// *pb.Filter, used as is, or a filter of the REST API; of those, the
+// must/should/must_not clauses with has_id, nested, match (value, text, any,
+// except) and range conditions are supported.
+func toGRPCFilter(filter any) (*pb.Filter, error) {
+	switch f := filter.(type) {
+	case nil:
+		return nil, nil
+	case *pb.Filter:
+		return f, nil
+	}
+
+	var generic map[string]any
+	if err := jsonRoundTrip(filter, &generic); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedFilter, err)
+	}
+	return parseFilter(generic)
+}
+
+// andFilters returns a filter matching the points matched by both filters.
+// The filters are combined as gRPC filters if one of them is one.
+func andFilters(a, b any) (any, error) {
+	if a == nil {
+		return b, nil
+	}
+
+	_, aGRPC := a.(*pb.Filter)
+	_, bGRPC := b.(*pb.Filter)
+	if !aGRPC && !bGRPC {
+		return map[string]any{"must": []any{a, b}}, nil
+	}
+
+	grpcA, err := toGRPCFilter(a)
+	if err != nil {
+		return nil, err
+	}
+	grpcB, err := toGRPCFilter(b)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Filter{Must: []*pb.Condition{
+		{ConditionOneOf: &pb.Condition_Filter{Filter: grpcA}},
+		{ConditionOneOf: &pb.Condition_Filter{Filter: grpcB}},
+	}}, nil
+}
+
+func parseFilter(filter map[string]any) (*pb.Filter, error) {
+	result := &pb.Filter{}
+	for clause, value := range filter {
+		items, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not a list of conditions", ErrUnsupportedFilter, clause)
+		}
+		conditions := make([]*pb.Condition, len(items))
+		for i, item := range items {
+			condition, err := parseCondition(item)
+			if err != nil {
+				return nil, err
+			}
+			conditions[i] = condition
+		}
+		switch clause {
+		case "must":
+			result.Must = conditions
+		case "should":
+			result.Should = conditions
+		case "must_not":
+			result.MustNot = conditions
+		default:
+			return nil, fmt.Errorf("%w: unknown clause %q", ErrUnsupportedFilter, clause)
+		}
+	}
+	return result, nil
+}
+
+func parseCondition(value any) (*pb.Condition, error) { //nolint:cyclop
+	condition, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: condition %v", ErrUnsupportedFilter, value)
+	}
+
+	if ids, ok := condition["has_id"].([]any); ok {
+		pointIDs := make([]*pb.PointId, len(ids))
+		for i, id := range ids {
+			pointIDs[i] = toGRPCPointID(fmt.Sprint(id))
+		}
+		return &pb.Condition{ConditionOneOf: &pb.Condition_HasId{HasId: &pb.HasIdCondition{HasId: pointIDs}}}, nil
+	}
+
+	if nested, ok := condition["nested"].(map[string]any); ok {
+		key, _ := nested["key"].(string)
+		nestedFilter, _ := nested["filter"].(map[string]any)
+		filter, err := parseFilter(nestedFilter)
+		if err != nil {
+			return nil, err
+		}
+		return &pb.Condition{ConditionOneOf: &pb.Condition_Nested{Nested: &pb.NestedCondition{
+			Key:    key,
+			Filter: filter,
+		}}}, nil
+	}
+
+	key, ok := condition["key"].(string)
+	if !ok {
+		filter, err := parseFilter(condition)
+		if err != nil {
+			return nil, err
+		}
+		return &pb.Condition{ConditionOneOf: &pb.Condition_Filter{Filter: filter}}, nil
+	}
+
+	field := &pb.FieldCondition{Key: key}
+	if match, ok := condition["match"].(map[string]any); ok {
+		m, err := parseMatch(match)
+		if err != nil {
+			return nil, err
+		}
+		field.Match = m
+	}
+	if r, ok := condition["range"].(map[string]any); ok {
+		field.Range = &pb.Range{
+			Gt:  floatField(r, "gt"),
+			Gte: floatField(r, "gte"),
+			Lt:  floatField(r, "lt"),
+			Lte: floatField(r, "lte"),
+		}
+	}
+	if field.GetMatch() == nil && field.GetRange() == nil {
+		return nil, fmt.Errorf("%w: condition on %q", ErrUnsupportedFilter, key)
+	}
+	return &pb.Condition{ConditionOneOf: &pb.Condition_Field{Field: field}}, nil
+}
+
+func parseMatch(match map[string]any) (*pb.Match, error) { //nolint:cyclop
+	if text, ok := match["text"].(string); ok {
+		return &pb.Match{MatchValue: &pb.Match_Text{Text: text}}, nil
+	}
+
+	if value, ok := match["value"]; ok {
+		switch v := value.(type) {
+		case string:
+			return &pb.Match{MatchValue: &pb.Match_Keyword{Keyword: v}}, nil
+		case bool:
+			return &pb.Match{MatchValue: &pb.Match_Boolean{Boolean: v}}, nil
+		case float64:
+			if v == math.Trunc(v) {
+				return &pb.Match{MatchValue: &pb.Match_Integer{Integer: int64(v)}}, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: match value %v", ErrUnsupportedFilter, value)
+	}
+
+	for _, kind := range []string{"any", "except"} {
+		values, ok := match[kind].([]any)
+		if !ok {
+			continue
+		}
+		keywords, integers, err := splitMatchValues(values)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case kind == "any" && keywords != nil:
+			return &pb.Match{MatchValue: &pb.Match_Keywords{Keywords: &pb.RepeatedStrings{Strings: keywords}}}, nil
+		case kind == "any":
+			return &pb.Match{MatchValue: &pb.Match_Integers{Integers: &pb.RepeatedIntegers{Integers: integers}}}, nil
+		case keywords != nil:
+			return &pb.Match{MatchValue: &pb.Match_ExceptKeywords{
+				ExceptKeywords: &pb.RepeatedStrings{Strings: keywords},
+			}}, nil
+		default:
+			return &pb.Match{MatchValue: &pb.Match_ExceptIntegers{
+				ExceptIntegers: &pb.RepeatedIntegers{Integers: integers},
+			}}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: match %v", ErrUnsupportedFilter, match)
+}
+
+// splitMatchValues returns the values of an any/except match, either all
+// keywords or all integers.
+func splitMatchValues(values []any) ([]string, []int64, error) {
+	var (
+		keywords []string
+		integers []int64
+	)
+	for _, value := range values {
+		switch v := value.(type) {
+		case string:
+			keywords = append(keywords, v)
+		case float64:
+			integers = append(integers, int64(v))
+		default:
+			return nil, nil, fmt.Errorf("%w: match value %v", ErrUnsupportedFilter, value)
+		}
+	}
+	if keywords != nil && integers != nil {
+		return nil, nil, fmt.Errorf("%w: mixed match values %v", ErrUnsupportedFilter, values)
+	}
+	if keywords == nil && integers == nil {
+		integers = []int64{}
+	}
+	return keywords, integers, nil
+}
+
+func floatField(m map[string]any, key string) *float64 {
+	if v, ok := m[key].(float64); ok {
+		return &v
+	}
+	return nil
+}
+
+// jsonRoundTrip decodes the JSON encoding of value into target.
+func jsonRoundTrip(value, target any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, target)
+}
+
+// grpcReadConsistencyTypes maps the read consistency levels to their gRPC
+// types.
+var grpcReadConsistencyTypes = map[string]pb.ReadConsistencyType{ //nolint:gochecknoglobals
+	ReadConsistencyAll:      pb.ReadConsistencyType_All,
+	ReadConsistencyMajority: pb.ReadConsistencyType_Majority,
+	ReadConsistencyQuorum:   pb.ReadConsistencyType_Quorum,
+}
+
+// grpcReadConsistency returns the read consistency of WithReadConsistency, nil
+// if not set.
+func (s Store) grpcReadConsistency() *pb.ReadConsistency {
+	if s.readConsistency == "" {
+		return nil
+	}
+	if typ, ok := grpcReadConsistencyTypes[s.readConsistency]; ok {
+		return &pb.ReadConsistency{Value: &pb.ReadConsistency_Type{Type: typ}}
+	}
+	// New validated the number of replicas.
+	factor, _ := strconv.ParseUint(s.readConsistency, 10, 64)
+	return &pb.ReadConsistency{Value: &pb.ReadConsistency_Factor{Factor: factor}}
+}
+
+// grpcSearchParams returns the search parameters of WithQuantizationSearch,
+// nil if not set.
+func (s Store) grpcSearchParams() *pb.SearchParams {
+	q := s.quantizationSearch
+	if q == nil {
+		return nil
+	}
+	params := &pb.QuantizationSearchParams{Rescore: &q.Rescore}
+	if q.Oversampling != 0 {
+		params.Oversampling = &q.Oversampling
+	}
+	return &pb.SearchParams{Quantization: params}
+}
+
+// grpcWriteOrderingTypes maps the write orderings to their gRPC types.
+var grpcWriteOrderingTypes = map[string]pb.WriteOrderingType{ //nolint:gochecknoglobals
+	WriteOrderingWeak:   pb.WriteOrderingType_Weak,
+	WriteOrderingMedium: pb.WriteOrderingType_Medium,
+	WriteOrderingStrong: pb.WriteOrderingType_Strong,
+}
+
+// grpcWriteOrdering returns the write ordering of WithWriteOrdering, nil if
+// not set.
+func (s Store) grpcWriteOrdering() *pb.WriteOrdering {
+	if s.writeOrdering == "" {
+		return nil
+	}
+	return &pb.WriteOrdering{Type: grpcWriteOrderingTypes[s.writeOrdering]}
+}
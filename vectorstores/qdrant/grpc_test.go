@@ -0,0 +1,190 @@
+package qdrant_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	pb "github.com/qdrant/go-client/qdrant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+	"github.com/tmc/langchaingo/vectorstores/qdrant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeGRPCQdrant is a fake Qdrant gRPC points API recording the requests it
+// receives.
+type fakeGRPCQdrant struct {
+	pb.UnimplementedPointsServer
+
+	mu       sync.Mutex
+	apiKeys  []string
+	tokens   []string
+	creates  []*pb.CreateCollection
+	upserts  []*pb.UpsertPoints
+	searches []*pb.SearchPoints
+	deletes  []*pb.DeletePoints
+	gets     []*pb.GetPoints
+}
+
+func (f *fakeGRPCQdrant) record(ctx context.Context) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	f.apiKeys = append(f.apiKeys, md.Get("api-key")...)
+	f.tokens = append(f.tokens, md.Get("authorization")...)
+}
+
+// fakeGRPCCollections is the collections API of a fakeGRPCQdrant.
+type fakeGRPCCollections struct {
+	pb.UnimplementedCollectionsServer
+	f *fakeGRPCQdrant
+}
+
+func (c fakeGRPCCollections) Get(ctx context.Context, _ *pb.GetCollectionInfoRequest) (*pb.GetCollectionInfoResponse, error) { //nolint:lll
+	f := c.f
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(ctx)
+	return nil, status.Error(codes.NotFound, "collection not found")
+}
+
+func (c fakeGRPCCollections) Create(ctx context.Context, req *pb.CreateCollection) (*pb.CollectionOperationResponse, error) {
+	f := c.f
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(ctx)
+	f.creates = append(f.creates, req)
+	return &pb.CollectionOperationResponse{Result: true}, nil
+}
+
+func (f *fakeGRPCQdrant) Upsert(ctx context.Context, req *pb.UpsertPoints) (*pb.PointsOperationResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(ctx)
+	f.upserts = append(f.upserts, req)
+	return &pb.PointsOperationResponse{Result: &pb.UpdateResult{Status: pb.UpdateStatus_Completed}}, nil
+}
+
+func (f *fakeGRPCQdrant) Search(ctx context.Context, req *pb.SearchPoints) (*pb.SearchResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(ctx)
+	f.searches = append(f.searches, req)
+	return &pb.SearchResponse{Result: []*pb.ScoredPoint{{
+		Score: 0.5,
+		Payload: map[string]*pb.Value{
+			"content": {Kind: &pb.Value_StringValue{StringValue: "tokyo"}},
+			"country": {Kind: &pb.Value_StringValue{StringValue: "japan"}},
+		},
+	}}}, nil
+}
+
+func (f *fakeGRPCQdrant) Delete(ctx context.Context, req *pb.DeletePoints) (*pb.PointsOperationResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(ctx)
+	f.deletes = append(f.deletes, req)
+	return &pb.PointsOperationResponse{Result: &pb.UpdateResult{Status: pb.UpdateStatus_Completed}}, nil
+}
+
+func (f *fakeGRPCQdrant) Get(ctx context.Context, req *pb.GetPoints) (*pb.GetResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(ctx)
+	f.gets = append(f.gets, req)
+	return &pb.GetResponse{Result: []*pb.RetrievedPoint{{
+		Id: req.GetIds()[0],
+		Payload: map[string]*pb.Value{
+			"content": {Kind: &pb.Value_StringValue{StringValue: "tokyo"}},
+		},
+	}}}, nil
+}
+
+func TestGRPCTransport(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeGRPCQdrant{}
+	server := grpc.NewServer()
+	pb.RegisterPointsServer(server, fake)
+	pb.RegisterCollectionsServer(server, fakeGRPCCollections{f: fake})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	store, err := qdrant.New(
+		qdrant.WithGRPC(listener.Addr().String()),
+		qdrant.WithAPIKey("secret"),
+		qdrant.WithBearerToken("jwt"),
+		qdrant.WithCollectionName("test"),
+		qdrant.WithEmbedder(fakeEmbedder{dimension: 2}),
+		qdrant.WithCreateCollectionIfNotExists(0, qdrant.DistanceCosine),
+		qdrant.WithReadConsistency("2"),
+		qdrant.WithWriteOrdering(qdrant.WriteOrderingMedium),
+		qdrant.WithQuantizationSearch(true, 2),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, store.Close()) })
+
+	ids, err := store.AddDocuments(context.Background(), []schema.Document{
+		{PageContent: "tokyo", Metadata: map[string]any{"country": "japan", "population": 14}},
+	})
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+
+	docs, err := store.SimilaritySearch(context.Background(), "japan", 3, vectorstores.WithFilters(map[string]any{
+		"must": []any{map[string]any{"key": "country", "match": map[string]any{"value": "japan"}}},
+	}))
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "tokyo", docs[0].PageContent)
+	assert.Equal(t, map[string]any{"country": "japan"}, docs[0].Metadata)
+	assert.InDelta(t, 0.5, docs[0].Score, 1e-6)
+
+	docs, err = store.GetDocuments(context.Background(), append(ids, "7"))
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "tokyo", docs[0].PageContent)
+
+	require.NoError(t, store.DeleteDocuments(context.Background(), ids))
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	require.Len(t, fake.creates, 1)
+	assert.Equal(t, uint64(2), fake.creates[0].GetVectorsConfig().GetParams().GetSize())
+	assert.Equal(t, pb.Distance_Cosine, fake.creates[0].GetVectorsConfig().GetParams().GetDistance())
+
+	require.Len(t, fake.upserts, 1)
+	point := fake.upserts[0].GetPoints()[0]
+	assert.Equal(t, ids[0], point.GetId().GetUuid())
+	assert.Equal(t, []float32{5, 5}, point.GetVectors().GetVector().GetData())
+	assert.Equal(t, "japan", point.GetPayload()["country"].GetStringValue())
+	assert.Equal(t, int64(14), point.GetPayload()["population"].GetIntegerValue())
+	assert.Equal(t, "tokyo", point.GetPayload()["content"].GetStringValue())
+	assert.True(t, fake.upserts[0].GetWait())
+	assert.Equal(t, pb.WriteOrderingType_Medium, fake.upserts[0].GetOrdering().GetType())
+
+	require.Len(t, fake.searches, 1)
+	assert.Equal(t, uint64(3), fake.searches[0].GetLimit())
+	assert.Equal(t, uint64(2), fake.searches[0].GetReadConsistency().GetFactor())
+	assert.True(t, fake.searches[0].GetParams().GetQuantization().GetRescore())
+	assert.InDelta(t, 2, fake.searches[0].GetParams().GetQuantization().GetOversampling(), 1e-9)
+	condition := fake.searches[0].GetFilter().GetMust()[0].GetField()
+	assert.Equal(t, "country", condition.GetKey())
+	assert.Equal(t, "japan", condition.GetMatch().GetKeyword())
+
+	require.Len(t, fake.deletes, 1)
+	assert.Equal(t, ids[0], fake.deletes[0].GetPoints().GetPoints().GetIds()[0].GetUuid())
+
+	require.Len(t, fake.gets, 1)
+	assert.Equal(t, uint64(7), fake.gets[0].GetIds()[1].GetNum())
+
+	assert.Equal(t, []string{"secret", "secret", "secret", "secret", "secret", "secret"}, fake.apiKeys)
+	assert.Equal(t, []string{"Bearer jwt", "Bearer jwt", "Bearer jwt", "Bearer jwt", "Bearer jwt", "Bearer jwt"}, fake.tokens)
+}
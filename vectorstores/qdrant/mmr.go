@@ -0,0 +1,72 @@
+package qdrant
+
+import "math"
+
+// maximalMarginalRelevance picks up to k of the candidate vectors by maximal
+// marginal relevance to the query vector, returning their indices in the
+// order they were picked. Each pick maximizes
+//
+//	lambda*sim(query, candidate) - (1-lambda)*max(sim(candidate, picked))
+//
+// where sim is the cosine similarity.
+func maximalMarginalRelevance(query []float32, candidates [][]float32, k int, lambda float64) []int {
+	if k > len(candidates) {
+		k = len(candidates)
+	}
+	if k <= 0 {
+		return []int{}
+	}
+
+	relevance := make([]float64, len(candidates))
+	for i, candidate := range candidates {
+		relevance[i] = cosineSimilarity(query, candidate)
+	}
+
+	// redundancy holds the highest similarity of each candidate to the
+	// picked ones.
+	redundancy := make([]float64, len(candidates))
+	for i := range redundancy {
+		redundancy[i] = math.Inf(-1)
+	}
+	picked := make([]bool, len(candidates))
+	selected := make([]int, 0, k)
+	for len(selected) < k {
+		best, bestScore := -1, math.Inf(-1)
+		for i := range candidates {
+			if picked[i] {
+				continue
+			}
+			score := lambda * relevance[i]
+			if len(selected) > 0 {
+				score -= (1 - lambda) * redundancy[i]
+			}
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		picked[best] = true
+		selected = append(selected, best)
+		for i, candidate := range candidates {
+			if !picked[i] {
+				redundancy[i] = math.Max(redundancy[i], cosineSimilarity(candidate, candidates[best]))
+			}
+		}
+	}
+	return selected
+}
+
+// cosineSimilarity returns the cosine similarity of two vectors, or 0 if one
+// of them is zero.
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := 0; i < len(a) && i < len(b); i++ {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,23 @@
+package qdrant
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaximalMarginalRelevance(t *testing.T) {
+	t.Parallel()
+
+	query := []float32{1, 0}
+	candidates := [][]float32{
+		{1, 0},
+		{1, 0.01},
+		{0.6, 0.8},
+	}
+
+	assert.Equal(t, []int{0, 1}, maximalMarginalRelevance(query, candidates, 2, 1))
+	assert.Equal(t, []int{0, 2}, maximalMarginalRelevance(query, candidates, 2, 0.3))
+	assert.Equal(t, []int{0, 2, 1}, maximalMarginalRelevance(query, candidates, 5, 0.3))
+	assert.Empty(t, maximalMarginalRelevance(query, nil, 2, 0.5))
+}
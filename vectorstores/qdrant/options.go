@@ -3,13 +3,74 @@ package qdrant
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
+	"os"
+	"strconv"
+	"sync/atomic"
 
 	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/vectorstores"
+	"google.golang.org/grpc"
 )
 
+// The environment variables read by New when the options don't set the URL
+// or the API key.
 const (
-	defaultContentKey = "content"
+	URLEnvVarName    = "QDRANT_URL"
+	APIKeyEnvVarName = "QDRANT_API_KEY" // #nosec G101
+)
+
+// DefaultIDKey is the metadata field of the point IDs set by WithIDKey with
+// an empty key.
+const DefaultIDKey = vectorstores.IDMetadataKey
+
+const (
+	defaultContentKey      = "content"
+	defaultUpsertBatchSize = 100
+	defaultMaxIdleConns    = 100
+
+	// metadataKey is the payload field of the document metadata in the
+	// nested payload layout.
+	metadataKey = "metadata"
+)
+
+// defaultContentKeyFallback is the content fields of the payloads tried by
+// WithContentKeyFallback without keys, used by other tools.
+var defaultContentKeyFallback = []string{"content", "text", "page_content"} //nolint:gochecknoglobals
+
+// Distance metrics supported when creating a collection.
+// Reference: https://qdrant.tech/documentation/concepts/search/#metrics
+const (
+	DistanceCosine = "Cosine"
+	DistanceDot    = "Dot"
+	DistanceEuclid = "Euclid"
+)
+
+// Payload schema types supported by CreatePayloadIndex.
+// Reference: https://qdrant.tech/documentation/concepts/indexing/#payload-index
+const (
+	PayloadSchemaKeyword = "keyword"
+	PayloadSchemaInteger = "integer"
+	PayloadSchemaFloat   = "float"
+	PayloadSchemaBool    = "bool"
+	PayloadSchemaText    = "text"
+)
+
+// Read consistency levels of WithReadConsistency, besides a number of replicas.
+// Reference: https://qdrant.tech/documentation/guides/distributed_deployment/#read-consistency
+const (
+	ReadConsistencyAll      = "all"
+	ReadConsistencyMajority = "majority"
+	ReadConsistencyQuorum   = "quorum"
+)
+
+// Write ordering guarantees of WithWriteOrdering.
+// Reference: https://qdrant.tech/documentation/guides/distributed_deployment/#write-ordering
+const (
+	WriteOrderingWeak   = "weak"
+	WriteOrderingMedium = "medium"
+	WriteOrderingStrong = "strong"
 )
 
 // ErrInvalidOptions is returned when the options given are invalid.
@@ -26,39 +87,267 @@ func WithCollectionName(name string) Option {
 }
 
 // WithURL returns an Option for setting the Qdrant instance URL.
-// Example: 'http://localhost:63333'. Required.
+// Example: 'http://localhost:63333'. Required, unless set with the
+// QDRANT_URL environment variable or WithGRPC.
 func WithURL(qdrantURL url.URL) Option {
 	return func(p *Store) {
 		p.qdrantURL = qdrantURL
 	}
 }
 
+// WithGRPC returns an Option for talking to Qdrant over its gRPC API at the
+// given address (e.g. 'localhost:6334') instead of the REST API, in which
+// case WithURL isn't required. The connection is insecure unless the dial
+// options set transport credentials. Optional.
+func WithGRPC(addr string, dialOptions ...grpc.DialOption) Option {
+	return func(p *Store) {
+		p.grpcAddr = addr
+		p.grpcDialOptions = dialOptions
+	}
+}
+
 // WithEmbedder returns an Option for setting the embedder to be used when
-// adding documents or doing similarity search. Required.
+// adding documents or doing similarity search. Only a Store adding its
+// documents with AddVectors and searching them with vectors can be created
+// without it.
 func WithEmbedder(embedder embeddings.Embedder) Option {
 	return func(p *Store) {
 		p.embedder = embedder
 	}
 }
 
+// WithHTTPClient returns an Option for setting the HTTP client of the REST
+// API, e.g. with a Transport tuned for the load. The client is shared by the
+// copies of the Store, and isn't closed by Close. Optional. By default, New
+// creates a client keeping up to 100 idle connections to Qdrant.
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Store) {
+		p.httpClient = client
+	}
+}
+
 // WithAPIKey returns an Option for setting the API key to authenticate the connection. Optional.
+// Defaults to the QDRANT_API_KEY environment variable.
 func WithAPIKey(apiKey string) Option {
 	return func(p *Store) {
 		p.apiKey = apiKey
 	}
 }
 
-// WithContent returns an Option for setting field name of the document content
-// in the Qdrant payload. Optional. Defaults to "content".
+// WithBearerToken returns an Option for setting a token, e.g. a JWT of Qdrant
+// Cloud, sent as a bearer token in the Authorization header of the requests,
+// along with the API key if also set. The requests are unauthenticated if
+// neither is set. Optional.
+func WithBearerToken(token string) Option {
+	return func(p *Store) {
+		p.bearerToken = token
+	}
+}
+
+// WithContentKey returns an Option for setting field name of the document
+// content in the Qdrant payload. It must not be empty. Optional. Defaults to
+// "content".
 func WithContentKey(contentKey string) Option {
 	return func(p *Store) {
 		p.contentKey = contentKey
 	}
 }
 
+// WithContentKeyFallback returns an Option for reading the content of the
+// points whose payload lacks the content key from the first of the keys
+// found, e.g. for collections populated by other tools. Without keys,
+// "content", "text" and "page_content" are tried. Optional. By default, a
+// point without the content key is an error.
+func WithContentKeyFallback(keys ...string) Option {
+	return func(p *Store) {
+		if len(keys) == 0 {
+			keys = defaultContentKeyFallback
+		}
+		p.contentKeyFallback = keys
+	}
+}
+
+// WithKeepContentInMetadata returns an Option for keeping the content of the
+// returned documents in their metadata too, under the content key it was read
+// from. Optional. By default, the content is only the PageContent of the
+// documents.
+func WithKeepContentInMetadata() Option {
+	return func(p *Store) {
+		p.keepContentInMetadata = true
+	}
+}
+
+// WithReadConsistency returns an Option for setting the read consistency of
+// the searches in a distributed deployment: ReadConsistencyAll,
+// ReadConsistencyMajority, ReadConsistencyQuorum, or the number of replicas
+// to query, e.g. "2". Optional. By default, Qdrant queries a single replica,
+// which may not have the latest upserts yet.
+func WithReadConsistency(level string) Option {
+	return func(p *Store) {
+		p.readConsistency = level
+	}
+}
+
+// WithWriteWait returns an Option for setting whether the upserts wait for the
+// points to be applied before returning. Without waiting, the upserts are
+// faster but the points may not be found by the searches right after.
+// Optional. Defaults to true.
+func WithWriteWait(wait bool) Option {
+	return func(p *Store) {
+		p.writeWait = wait
+	}
+}
+
+// WithWriteOrdering returns an Option for setting the ordering guarantee of
+// the upserts in a distributed deployment: WriteOrderingWeak,
+// WriteOrderingMedium or WriteOrderingStrong. Optional. Defaults to the weak
+// ordering of Qdrant.
+func WithWriteOrdering(ordering string) Option {
+	return func(p *Store) {
+		p.writeOrdering = ordering
+	}
+}
+
+// WithQuantizationSearch returns an Option for setting how the searches use
+// the quantized vectors of a collection with scalar, product or binary
+// quantization: if rescore is set, Qdrant rescores the candidates found with
+// the quantized vectors with the original ones, and oversampling, at least 1,
+// is the factor of the number of candidates preselected. A zero oversampling
+// leaves it to Qdrant. Optional. By default, Qdrant decides.
+func WithQuantizationSearch(rescore bool, oversampling float64) Option {
+	return func(p *Store) {
+		p.quantizationSearch = &quantizationParams{Rescore: rescore, Oversampling: oversampling}
+	}
+}
+
+// WithNestedPayload returns an Option for storing the document metadata in
+// the payload nested under a "metadata" field, next to the content field,
+// rather than as top-level fields, so that metadata never collides with the
+// content key. Filters on metadata then refer to "metadata.<field>".
+// Optional. Defaults to the flat layout.
+func WithNestedPayload(nested bool) Option {
+	return func(p *Store) {
+		p.nestedPayload = nested
+	}
+}
+
+// WithUpsertBatchSize returns an Option for setting the maximum number of
+// points sent per upsert request by AddDocuments. Optional. Defaults to 100.
+func WithUpsertBatchSize(batchSize int) Option {
+	return func(p *Store) {
+		p.upsertBatchSize = batchSize
+	}
+}
+
+// WithIDKey returns an Option for setting the metadata field holding the ID
+// of a document, DefaultIDKey if empty. When the field of an added document
+// holds a UUID or an unsigned integer, it is used as the Qdrant point ID, so
+// adding the document again overwrites its point; otherwise an ID is
+// generated. The documents returned by the searches and GetDocuments hold
+// the ID of their point in the field, overriding a payload field of the same
+// name, e.g. to pass to DeleteDocuments. Optional.
+func WithIDKey(idKey string) Option {
+	return func(p *Store) {
+		if idKey == "" {
+			idKey = DefaultIDKey
+		}
+		p.idKey = idKey
+	}
+}
+
+// WithScoreKey returns an Option for setting the metadata field the
+// similarity score of a point is stored in for the documents returned by
+// SimilaritySearch, overriding a payload field of the same name. Optional.
+// By default, the score is only set in the Score field of the documents.
+func WithScoreKey(scoreKey string) Option {
+	return func(p *Store) {
+		p.scoreKey = scoreKey
+	}
+}
+
+// WithNormalizedScores returns an Option for normalizing the scores of the
+// dense searches, not of SparseSearch, into a similarity between 0 and 1,
+// higher meaning more similar, whatever the distance metric of the
+// collection, so that vectorstores.WithScoreThreshold means the same for all
+// collections. See NormalizeScore for the conversion. Optional. By default,
+// the scores are the raw scores of Qdrant.
+func WithNormalizedScores() Option {
+	return func(p *Store) {
+		p.normalizeScores = true
+	}
+}
+
+// WithClampScoreThreshold returns an Option for clamping the score thresholds
+// of vectorstores.WithScoreThreshold outside of [0, 1] into that range, e.g.
+// computed thresholds overshooting it. Optional. By default, the searches
+// with such a threshold fail.
+func WithClampScoreThreshold() Option {
+	return func(p *Store) {
+		p.clampScoreThreshold = true
+	}
+}
+
+// WithVectorName returns an Option for setting the name of the vector used
+// when adding documents and doing similarity search, for collections with
+// multiple named vectors. Optional. Defaults to the unnamed default vector.
+func WithVectorName(name string) Option {
+	return func(p *Store) {
+		p.vectorName = name
+	}
+}
+
+// WithCreateCollectionIfNotExists returns an Option that makes the first
+// AddDocuments call create the collection if it doesn't exist yet, with the
+// given vector size and distance metric (DistanceCosine, DistanceDot or
+// DistanceEuclid). A vector size of 0 uses the size of the first embedding.
+// Optional.
+func WithCreateCollectionIfNotExists(vectorSize uint64, distance string) Option {
+	return func(p *Store) {
+		p.createCollection = &collectionConfig{
+			vectorSize: vectorSize,
+			distance:   distance,
+		}
+	}
+}
+
+// WithSparseEmbedder returns an Option for embedding the documents into a
+// sparse vector too, e.g. with SPLADE or BM25, stored as the named sparse
+// vector of the points alongside their dense vector, for SparseSearch and
+// SparseHybridSearch. The collection must have a sparse vector of this name;
+// WithCreateCollectionIfNotExists creates it. Optional.
+func WithSparseEmbedder(vectorName string, embedder SparseEmbedder) Option {
+	return func(p *Store) {
+		p.sparseVectorName = vectorName
+		p.sparseEmbedder = embedder
+	}
+}
+
+// WithPayloadInclude returns an Option for only fetching the given payload
+// fields of the points found by the searches, and the content field, e.g. to
+// leave out large metadata. In the nested payload layout, the metadata fields
+// are under "metadata.". Optional. By default, the whole payload is fetched.
+func WithPayloadInclude(fields ...string) Option {
+	return func(p *Store) {
+		p.payloadInclude = append(p.payloadInclude, fields...)
+	}
+}
+
+// WithPayloadExclude returns an Option for leaving out the given payload
+// fields of the points found by the searches, except the content field.
+// Optional. It can't be combined with WithPayloadInclude.
+func WithPayloadExclude(fields ...string) Option {
+	return func(p *Store) {
+		p.payloadExclude = append(p.payloadExclude, fields...)
+	}
+}
+
 func applyClientOptions(opts ...Option) (Store, error) {
 	o := &Store{
-		contentKey: defaultContentKey,
+		contentKey:      defaultContentKey,
+		upsertBatchSize: defaultUpsertBatchSize,
+		writeWait:       true,
+		collection:      &collectionState{},
+		closed:          &atomic.Bool{},
 	}
 
 	for _, opt := range opts {
@@ -69,12 +358,73 @@ func applyClientOptions(opts ...Option) (Store, error) {
 		return Store{}, fmt.Errorf("%w: missing collection name", ErrInvalidOptions)
 	}
 
-	if o.qdrantURL == (url.URL{}) {
-		return Store{}, fmt.Errorf("%w: missing Qdrant URL", ErrInvalidOptions)
+	if o.qdrantURL == (url.URL{}) && o.grpcAddr == "" {
+		envURL := os.Getenv(URLEnvVarName)
+		if envURL == "" {
+			return Store{}, fmt.Errorf(
+				"%w: missing Qdrant URL. Pass it as an option or set the %s environment variable",
+				ErrInvalidOptions, URLEnvVarName)
+		}
+		qdrantURL, err := url.Parse(envURL)
+		if err != nil {
+			return Store{}, fmt.Errorf("%w: invalid %s: %w", ErrInvalidOptions, URLEnvVarName, err)
+		}
+		o.qdrantURL = *qdrantURL
+	}
+
+	if o.apiKey == "" {
+		o.apiKey = os.Getenv(APIKeyEnvVarName)
+	}
+
+	if o.httpClient == nil {
+		o.httpClient = newHTTPClient()
+		o.ownsHTTPClient = true
+	}
+
+	if o.contentKey == "" {
+		return Store{}, fmt.Errorf("%w: empty content key", ErrInvalidOptions)
+	}
+	if o.nestedPayload && o.contentKey == metadataKey {
+		return Store{}, fmt.Errorf("%w: content key %q is the metadata field of the nested payload",
+			ErrInvalidOptions, metadataKey)
+	}
+
+	if o.sparseEmbedder != nil && o.sparseVectorName == "" {
+		return Store{}, fmt.Errorf("%w: missing sparse vector name", ErrInvalidOptions)
+	}
+
+	if len(o.payloadInclude) > 0 && len(o.payloadExclude) > 0 {
+		return Store{}, fmt.Errorf("%w: payload include and exclude are exclusive", ErrInvalidOptions)
+	}
+
+	if o.upsertBatchSize < 1 {
+		return Store{}, fmt.Errorf("%w: upsert batch size must be positive", ErrInvalidOptions)
+	}
+
+	switch o.readConsistency {
+	case "", ReadConsistencyAll, ReadConsistencyMajority, ReadConsistencyQuorum:
+	default:
+		if n, err := strconv.ParseUint(o.readConsistency, 10, 64); err != nil || n == 0 {
+			return Store{}, fmt.Errorf("%w: unsupported read consistency %q", ErrInvalidOptions, o.readConsistency)
+		}
+	}
+
+	switch o.writeOrdering {
+	case "", WriteOrderingWeak, WriteOrderingMedium, WriteOrderingStrong:
+	default:
+		return Store{}, fmt.Errorf("%w: unsupported write ordering %q", ErrInvalidOptions, o.writeOrdering)
+	}
+
+	if q := o.quantizationSearch; q != nil && q.Oversampling != 0 && q.Oversampling < 1 {
+		return Store{}, fmt.Errorf("%w: oversampling must be at least 1", ErrInvalidOptions)
 	}
 
-	if o.embedder == nil {
-		return Store{}, fmt.Errorf("%w: missing embedder", ErrInvalidOptions)
+	if o.createCollection != nil {
+		switch o.createCollection.distance {
+		case DistanceCosine, DistanceDot, DistanceEuclid:
+		default:
+			return Store{}, fmt.Errorf("%w: unsupported distance metric %q", ErrInvalidOptions, o.createCollection.distance)
+		}
 	}
 
 	return *o, nil
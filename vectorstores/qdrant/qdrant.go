@@ -3,11 +3,19 @@ package qdrant
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
 	"net/url"
+	"sync"
+	"sync/atomic"
 
+	"github.com/google/uuid"
 	"github.com/tmc/langchaingo/embeddings"
 	"github.com/tmc/langchaingo/schema"
 	"github.com/tmc/langchaingo/vectorstores"
+	"google.golang.org/grpc"
 )
 
 type Store struct {
@@ -15,24 +23,177 @@ type Store struct {
 	collectionName string
 	qdrantURL      url.URL
 	apiKey         string
+	bearerToken    string
+	httpClient     *http.Client
+	// ownsHTTPClient is set when the HTTP client was created by New rather
+	// than given with WithHTTPClient, so that Close releases it.
+	ownsHTTPClient bool
 	contentKey     string
+	vectorName     string
+
+	contentKeyFallback []string
+	// keepContentInMetadata is set with WithKeepContentInMetadata.
+	keepContentInMetadata bool
+
+	idKey         string
+	scoreKey      string
+	nestedPayload bool
+
+	normalizeScores bool
+	// clampScoreThreshold is set with WithClampScoreThreshold.
+	clampScoreThreshold bool
+
+	payloadInclude []string
+	payloadExclude []string
+
+	upsertBatchSize int
+
+	readConsistency string
+	writeWait       bool
+	writeOrdering   string
+
+	// quantizationSearch is set with WithQuantizationSearch.
+	quantizationSearch *quantizationParams
+
+	sparseVectorName string
+	sparseEmbedder   SparseEmbedder
+
+	grpcAddr        string
+	grpcDialOptions []grpc.DialOption
+	grpc            *grpcClient
+
+	createCollection *collectionConfig
+	collection       *collectionState
+
+	// closed is set by Close, and shared by the copies of the Store.
+	closed *atomic.Bool
+}
+
+// collectionConfig is the configuration the collection of a Store is created
+// with if it doesn't exist.
+type collectionConfig struct {
+	vectorSize uint64
+	distance   string
+}
+
+// collectionState caches what is known about the collection of a Store. It is
+// shared by the copies of the Store.
+type collectionState struct {
+	mu      sync.Mutex
+	checked bool
+	// vectorSize is the size of the vectors of the collection, 0 if unknown.
+	vectorSize uint64
+	// distance is the distance metric of the vectors of the collection,
+	// empty if unknown.
+	distance string
 }
 
-var _ vectorstores.VectorStore = Store{}
+var (
+	_ vectorstores.VectorStore = Store{}
+	_ io.Closer                = Store{}
+)
 
 func New(opts ...Option) (Store, error) {
 	s, err := applyClientOptions(opts...)
 	if err != nil {
 		return Store{}, err
 	}
+	if s.grpcAddr != "" {
+		s.grpc, err = newGRPCClient(s.grpcAddr, s.grpcDialOptions...)
+		if err != nil {
+			return Store{}, err
+		}
+	}
 	return s, nil
 }
 
+// Close releases the gRPC connection of a Store created with WithGRPC, and
+// the idle connections of the HTTP client of the REST API unless it was given
+// with WithHTTPClient. The methods of the Store, and of its copies, return
+// vectorstores.ErrClosed once it is closed. Closing a closed Store is a no-op.
+func (s Store) Close() error {
+	if s.closed != nil && !s.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	if s.ownsHTTPClient {
+		s.httpClient.CloseIdleConnections()
+	}
+	if s.grpc == nil {
+		return nil
+	}
+	return s.grpc.conn.Close()
+}
+
+// authHeaders returns the headers authenticating the requests of the Store,
+// the api-key header and the Authorization header of the bearer token, for
+// the credentials set. The requests are unauthenticated without credentials.
+func (s Store) authHeaders() map[string]string {
+	headers := make(map[string]string, 2) //nolint:gomnd
+	if s.apiKey != "" {
+		headers["api-key"] = s.apiKey
+	}
+	if s.bearerToken != "" {
+		headers["authorization"] = "Bearer " + s.bearerToken
+	}
+	return headers
+}
+
+// ErrMissingEmbedder is returned when a Store created without WithEmbedder
+// needs to embed texts, and no embedder was given with
+// vectorstores.WithEmbedder.
+var ErrMissingEmbedder = errors.New("missing embedder")
+
+// ErrCollectionNotFound is returned by Ping when the collection of the Store
+// doesn't exist, and wrapped by the errors of the REST API requests failing
+// with HTTP 404.
+var ErrCollectionNotFound = errors.New("collection not found")
+
+// Errors of the Qdrant API responses, wrapped by the errors of the REST API
+// with the error message of Qdrant. With WithGRPC, the errors wrap the gRPC
+// status instead.
+var (
+	// ErrUnauthorized is returned when Qdrant rejects the API key or the
+	// bearer token of the Store (HTTP 401 or 403).
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrBadRequest is returned when Qdrant rejects a request as invalid
+	// (HTTP 400 or 422), e.g. a filter on a field without payload index.
+	ErrBadRequest = errors.New("bad request")
+)
+
+// Ping checks that Qdrant is reachable and that the collection of the Store
+// exists, returning ErrCollectionNotFound if it doesn't.
+func (s Store) Ping(ctx context.Context) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
+	info, err := s.getCollection(ctx, &s.qdrantURL)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		return fmt.Errorf("%w: %s", ErrCollectionNotFound, s.collectionName)
+	}
+	return nil
+}
+
+// checkOpen returns vectorstores.ErrClosed if the Store was closed.
+func (s Store) checkOpen() error {
+	if s.closed != nil && s.closed.Load() {
+		return vectorstores.ErrClosed
+	}
+	return nil
+}
+
 func (s Store) AddDocuments(ctx context.Context,
 	docs []schema.Document,
 	options ...vectorstores.Option,
 ) ([]string, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
 	opts := s.getOptions(options...)
+	ctx, cancel := vectorstores.TimeoutContext(ctx, opts)
+	defer cancel()
 
 	docs = s.deduplicate(ctx, opts, docs)
 
@@ -47,8 +208,12 @@ func (s Store) AddDocuments(ctx context.Context,
 		texts = append(texts, doc.PageContent)
 	}
 
+	embedder, err := s.getEmbedder(opts)
+	if err != nil {
+		return nil, err
+	}
 	vectors,
-		err := s.embedder.EmbedDocuments(ctx, texts)
+		err := embedder.EmbedDocuments(ctx, texts)
 	if err != nil {
 		return nil, err
 	}
@@ -57,27 +222,186 @@ func (s Store) AddDocuments(ctx context.Context,
 		return nil, errors.New("number of vectors from embedder does not match number of documents")
 	}
 
+	return s.addPoints(ctx, opts, docs, vectors)
+}
+
+// AddVectors adds the documents with their precomputed vectors, e.g. embedded
+// by another service, without embedding them: vectors[i] is the vector of
+// docs[i]. The options are those of AddDocuments; documents dropped by the
+// vectorstores.WithDeduplicater function are dropped with their vectors. The
+// sparse vectors of WithSparseEmbedder are still computed from the contents.
+func (s Store) AddVectors(ctx context.Context,
+	vectors [][]float32, docs []schema.Document,
+	options ...vectorstores.Option,
+) ([]string, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(docs) {
+		return nil, fmt.Errorf("%d vectors for %d documents", len(vectors), len(docs))
+	}
+	for i, vector := range vectors {
+		if len(vector) == 0 || len(vector) != len(vectors[0]) {
+			return nil, fmt.Errorf("%w: vector %d has %d dimensions, vector 0 has %d",
+				ErrVectorDimensionMismatch, i, len(vector), len(vectors[0]))
+		}
+	}
+	opts := s.getOptions(options...)
+	ctx, cancel := vectorstores.TimeoutContext(ctx, opts)
+	defer cancel()
+
+	if opts.Deduplicater != nil {
+		var keptDocs []schema.Document
+		var keptVectors [][]float32
+		for i, doc := range docs {
+			if !opts.Deduplicater(ctx, doc) {
+				keptDocs = append(keptDocs, doc)
+				keptVectors = append(keptVectors, vectors[i])
+			}
+		}
+		docs, vectors = keptDocs, keptVectors
+	}
+
+	if len(docs) == 0 {
+		return nil, nil
+	}
+	return s.addPoints(ctx, opts, docs, vectors)
+}
+
+// addPoints upserts the documents with their dense vectors, computing their
+// sparse vectors if the Store has a sparse embedder.
+func (s Store) addPoints(ctx context.Context,
+	opts vectorstores.Options,
+	docs []schema.Document,
+	vectors [][]float32,
+) ([]string, error) {
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = doc.PageContent
+	}
+
+	var (
+		sparseVectors []SparseVector
+		err           error
+	)
+	if s.sparseEmbedder != nil {
+		sparseVectors, err = s.sparseEmbedder.EmbedSparseDocuments(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+		if len(sparseVectors) != len(docs) {
+			return nil, errors.New("number of sparse vectors from embedder does not match number of documents")
+		}
+	}
+
+	if err := s.prepareCollection(ctx, len(vectors[0])); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(docs))
 	metadatas := make([]map[string]interface{}, 0, len(docs))
 	for i := 0; i < len(docs); i++ {
+		ids = append(ids, s.documentID(opts, docs[i]))
+
 		metadata := make(map[string]interface{}, len(docs[i].Metadata))
 		for key, value := range docs[i].Metadata {
 			metadata[key] = value
 		}
+		if s.nestedPayload {
+			metadata = map[string]interface{}{metadataKey: metadata}
+		}
 		metadata[s.contentKey] = texts[i]
 
 		metadatas = append(metadatas, metadata)
 	}
 
-	return s.upsertPoints(ctx, &s.qdrantURL, vectors, metadatas)
+	return s.upsertPoints(ctx, &s.qdrantURL, ids, vectors, sparseVectors, metadatas)
+}
+
+// documentID returns the point ID of a document: a UUID derived from its
+// content hash with vectorstores.WithContentHashID, or the value of its ID key
+// if it is a valid Qdrant point ID, or else a new UUID.
+func (s Store) documentID(opts vectorstores.Options, doc schema.Document) string {
+	if opts.ContentHashID {
+		hash := vectorstores.ContentHash(doc, opts.ContentHashKeys...)
+		return uuid.NewSHA1(uuid.Nil, []byte(hash)).String()
+	}
+	if s.idKey != "" {
+		switch id := doc.Metadata[s.idKey].(type) {
+		case string:
+			if parsed, err := uuid.Parse(id); err == nil {
+				return parsed.String()
+			}
+			if isIntegerID(id) {
+				return id
+			}
+		case uuid.UUID:
+			return id.String()
+		case int, int32, int64, uint, uint32, uint64:
+			if n := fmt.Sprint(id); isIntegerID(n) {
+				return n
+			}
+		case float64:
+			if id >= 0 && id <= math.MaxInt64 && id == math.Trunc(id) {
+				return fmt.Sprint(int64(id))
+			}
+		}
+	}
+	return uuid.NewString()
+}
+
+// isIntegerID reports whether id is an unsigned integer point ID.
+func isIntegerID(id string) bool {
+	_, ok := pointID(id).(uint64)
+	return ok
 }
 
 func (s Store) SimilaritySearch(ctx context.Context,
 	query string, numDocuments int,
 	options ...vectorstores.Option,
 ) ([]schema.Document, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+	opts := s.getOptions(options...)
+	ctx, cancel := vectorstores.TimeoutContext(ctx, opts)
+	defer cancel()
+
+	vector, err := s.embedQuery(ctx, opts, query)
+	if err != nil {
+		return nil, err
+	}
+	return s.searchByVector(ctx, opts, vector, numDocuments)
+}
+
+// SimilaritySearchByVector returns the numDocuments documents most similar to
+// the query vector, e.g. a cached embedding of the query or the average of
+// several vectors, without embedding a query. It takes the options of
+// SimilaritySearch, except vectorstores.WithEmbedder which is unused.
+func (s Store) SimilaritySearchByVector(ctx context.Context,
+	vector []float32, numDocuments int,
+	options ...vectorstores.Option,
+) ([]schema.Document, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
 	opts := s.getOptions(options...)
+	ctx, cancel := vectorstores.TimeoutContext(ctx, opts)
+	defer cancel()
 
-	filters := s.getFilters(opts)
+	return s.searchByVector(ctx, opts, vector, numDocuments)
+}
+
+// searchByVector is the search of SimilaritySearch and
+// SimilaritySearchByVector.
+func (s Store) searchByVector(ctx context.Context,
+	opts vectorstores.Options,
+	vector []float32, numDocuments int,
+) ([]schema.Document, error) {
+	filters, err := s.getFilters(opts)
+	if err != nil {
+		return nil, err
+	}
 
 	scoreThreshold,
 		err := s.getScoreThreshold(opts)
@@ -85,13 +409,209 @@ func (s Store) SimilaritySearch(ctx context.Context,
 		return nil, err
 	}
 
-	vector,
-		err := s.embedder.EmbedQuery(ctx, query)
+	_, docs, vectors, err := s.searchDense(ctx, vector, numDocuments, scoreThreshold, filters, opts.IncludeVectors)
 	if err != nil {
 		return nil, err
 	}
+	if opts.IncludeVectors {
+		for i := range docs {
+			setVector(&docs[i], vectors[i])
+		}
+	}
+	return docs, nil
+}
 
-	return s.searchPoints(ctx, &s.qdrantURL, vector, numDocuments, scoreThreshold, filters)
+// SimilaritySearchWithScore is SimilaritySearch returning the documents paired
+// with their similarity score. The scores are normalized with
+// WithNormalizedScores.
+func (s Store) SimilaritySearchWithScore(ctx context.Context,
+	query string, numDocuments int,
+	options ...vectorstores.Option,
+) ([]vectorstores.ScoredDocument, error) {
+	docs, err := s.SimilaritySearch(ctx, query, numDocuments, options...)
+	if err != nil {
+		return nil, err
+	}
+	return vectorstores.ScoredDocuments(docs), nil
+}
+
+// setVector sets the stored vector of a document returned by a search.
+func setVector(doc *schema.Document, vector []float32) {
+	if doc.Metadata == nil {
+		doc.Metadata = map[string]any{}
+	}
+	doc.Metadata[vectorstores.VectorMetadataKey] = vector
+}
+
+// HybridSearch returns the numDocuments documents most similar to the query
+// among those whose content matches at least one of the keywords, in
+// addition to the filters given with vectorstores.WithFilters. The keywords
+// are matched as full-text, which requires a text payload index on the
+// content field.
+func (s Store) HybridSearch(ctx context.Context,
+	query string, numDocuments int, keywords []string,
+	options ...vectorstores.Option,
+) ([]schema.Document, error) {
+	if len(keywords) == 0 {
+		return s.SimilaritySearch(ctx, query, numDocuments, options...)
+	}
+
+	should := make([]any, len(keywords))
+	for i, keyword := range keywords {
+		should[i] = map[string]any{
+			"key":   s.contentKey,
+			"match": map[string]any{"text": keyword},
+		}
+	}
+	keywordFilter := map[string]any{"should": should}
+
+	opts := s.getOptions(options...)
+	ctx, cancel := vectorstores.TimeoutContext(ctx, opts)
+	defer cancel()
+	filters, err := s.getFilters(opts)
+	if err != nil {
+		return nil, err
+	}
+	filters, err = andFilters(filters, keywordFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.SimilaritySearch(ctx, query, numDocuments, append(options, vectorstores.WithFilters(filters))...)
+}
+
+// MaxMarginalRelevanceSearch returns numDocuments documents relevant to the
+// query that are also diverse: it fetches the fetchK documents most similar to
+// the query, then picks among them by maximal marginal relevance. lambda, in
+// [0, 1], trades relevance (1) for diversity (0).
+func (s Store) MaxMarginalRelevanceSearch(ctx context.Context,
+	query string, numDocuments, fetchK int, lambda float64,
+	options ...vectorstores.Option,
+) ([]schema.Document, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+	if lambda < 0 || lambda > 1 {
+		return nil, errors.New("lambda must be between 0 and 1")
+	}
+	if fetchK < numDocuments {
+		fetchK = numDocuments
+	}
+
+	opts := s.getOptions(options...)
+	ctx, cancel := vectorstores.TimeoutContext(ctx, opts)
+	defer cancel()
+
+	filters, err := s.getFilters(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	scoreThreshold,
+		err := s.getScoreThreshold(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	vector, err := s.embedQuery(ctx, opts, query)
+	if err != nil {
+		return nil, err
+	}
+
+	_, candidates, vectors, err := s.searchDense(ctx, vector, fetchK, scoreThreshold, filters, true)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := maximalMarginalRelevance(vector, vectors, numDocuments, lambda)
+	docs := make([]schema.Document, len(selected))
+	for i, idx := range selected {
+		docs[i] = candidates[idx]
+		if opts.IncludeVectors {
+			setVector(&docs[i], vectors[idx])
+		}
+	}
+	return docs, nil
+}
+
+// ErrVectorDimensionMismatch is returned by AddDocuments when the embedder
+// produces vectors of another size than the vectors of the collection, and
+// by AddVectors when the given vectors don't all have the same size.
+var ErrVectorDimensionMismatch = errors.New("vector dimension mismatch")
+
+// ErrMissingIDsOrFilter is returned by DeleteDocuments when neither IDs nor a
+// filter select the points to delete.
+var ErrMissingIDsOrFilter = errors.New("missing IDs or filter of the documents to delete")
+
+// DeleteDocuments deletes the points with the given IDs from the collection.
+// If a filter is given with vectorstores.WithFilters, the points matching it
+// are deleted; combined with IDs, only the given points matching it are.
+func (s Store) DeleteDocuments(ctx context.Context, ids []string, options ...vectorstores.Option) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
+	opts := s.getOptions(options...)
+	ctx, cancel := vectorstores.TimeoutContext(ctx, opts)
+	defer cancel()
+
+	filters, err := s.getFilters(opts)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 && filters == nil {
+		return ErrMissingIDsOrFilter
+	}
+
+	return s.deletePoints(ctx, &s.qdrantURL, ids, filters)
+}
+
+// GetDocuments returns the documents of the points with the given IDs, e.g. to
+// fetch again the documents found by a previous search, in the order of the
+// IDs. The IDs of missing points are skipped, so fewer documents than IDs may
+// be returned, and repeated IDs are returned once.
+func (s Store) GetDocuments(ctx context.Context, ids []string) ([]schema.Document, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	found, err := s.retrievePoints(ctx, &s.qdrantURL, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]schema.Document, 0, len(found))
+	for _, id := range ids {
+		id = normalizeID(id)
+		if doc, ok := found[id]; ok {
+			docs = append(docs, doc)
+			delete(found, id)
+		}
+	}
+	return docs, nil
+}
+
+// ErrUnsupportedSchemaType is returned by CreatePayloadIndex for an unknown
+// payload schema type.
+var ErrUnsupportedSchemaType = errors.New("unsupported payload schema type")
+
+// CreatePayloadIndex creates an index of the given payload field, which makes
+// filtering by it fast. schemaType is the type of the field values:
+// PayloadSchemaKeyword, PayloadSchemaInteger, PayloadSchemaFloat,
+// PayloadSchemaBool, or PayloadSchemaText for full-text matching.
+func (s Store) CreatePayloadIndex(ctx context.Context, field string, schemaType string) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
+	switch schemaType {
+	case PayloadSchemaKeyword, PayloadSchemaInteger, PayloadSchemaFloat, PayloadSchemaBool, PayloadSchemaText:
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedSchemaType, schemaType)
+	}
+
+	return s.createFieldIndex(ctx, &s.qdrantURL, field, schemaType)
 }
 
 func (s Store) PayloadSearch(
@@ -99,26 +619,123 @@ func (s Store) PayloadSearch(
 	numDocuments int,
 	options ...vectorstores.Option,
 ) ([]schema.Document, error) {
+	docs, _, err := s.PayloadSearchPage(ctx, numDocuments, "", options...)
+	return docs, err
+}
+
+// PayloadSearchPage returns a page of at most numDocuments documents
+// matching the filters, starting at the cursor, along with the cursor of the
+// next page. An empty cursor starts at the first page; an empty next cursor
+// is returned after the last page.
+func (s Store) PayloadSearchPage(
+	ctx context.Context,
+	numDocuments int,
+	cursor string,
+	options ...vectorstores.Option,
+) ([]schema.Document, string, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, "", err
+	}
 	opts := s.getOptions(options...)
+	ctx, cancel := vectorstores.TimeoutContext(ctx, opts)
+	defer cancel()
 
-	filters := s.getFilters(opts)
+	filters, err := s.getFilters(opts)
+	if err != nil {
+		return nil, "", err
+	}
 
-	return s.scroll(ctx, &s.qdrantURL, numDocuments, filters)
+	return s.scroll(ctx, &s.qdrantURL, numDocuments, filters, cursor)
 }
 
+// prepareCollection checks, until it succeeds once, that the collection
+// exists, creating it if WithCreateCollectionIfNotExists was given, and fetches
+// the size of its vectors. It then checks that the vectors about to be added
+// are of that size.
+func (s Store) prepareCollection(ctx context.Context, dimension int) error {
+	c := s.collection
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.checked {
+		info, err := s.getCollection(ctx, &s.qdrantURL)
+		if err != nil {
+			return err
+		}
+		switch {
+		case info != nil:
+			c.vectorSize = info.vectorSize
+			c.distance = info.distance
+			c.checked = true
+		case s.createCollection != nil:
+			vectorSize := s.createCollection.vectorSize
+			if vectorSize == 0 {
+				vectorSize = uint64(dimension)
+			}
+			err := s.createCollectionRequest(ctx, &s.qdrantURL, vectorSize, s.createCollection.distance)
+			if err != nil {
+				return err
+			}
+			c.vectorSize = vectorSize
+			c.distance = s.createCollection.distance
+			c.checked = true
+		}
+	}
+
+	if c.vectorSize != 0 && uint64(dimension) != c.vectorSize {
+		return fmt.Errorf("%w: embedder produced %d-dim vectors but collection expects %d",
+			ErrVectorDimensionMismatch, dimension, c.vectorSize)
+	}
+	return nil
+}
+
+// getScoreThreshold returns the score threshold of the options, rejecting or
+// with WithClampScoreThreshold clamping the thresholds outside of [0, 1].
 func (s Store) getScoreThreshold(opts vectorstores.Options) (float32, error) {
-	if opts.ScoreThreshold < 0 || opts.ScoreThreshold > 1 {
+	switch {
+	case opts.ScoreThreshold >= 0 && opts.ScoreThreshold <= 1:
+		return opts.ScoreThreshold, nil
+	case !s.clampScoreThreshold:
 		return 0, errors.New("score threshold must be between 0 and 1")
+	case opts.ScoreThreshold < 0:
+		return 0, nil
+	default:
+		return 1, nil
 	}
-	return opts.ScoreThreshold, nil
 }
 
-func (s Store) getFilters(opts vectorstores.Options) any {
-	if opts.Filters != nil {
-		return opts.Filters
+// getFilters returns the Qdrant filter of the options, translated from a
+// vectorstores.Filter if needed.
+func (s Store) getFilters(opts vectorstores.Options) (any, error) {
+	switch f := opts.Filters.(type) {
+	case vectorstores.Filter:
+		return fromFilter(f)
+	case *vectorstores.Filter:
+		return fromFilter(*f)
+	default:
+		return opts.Filters, nil
 	}
+}
 
-	return nil
+// embedQuery embeds the query with the embedder of getEmbedder.
+func (s Store) embedQuery(ctx context.Context, opts vectorstores.Options, query string) ([]float32, error) {
+	embedder, err := s.getEmbedder(opts)
+	if err != nil {
+		return nil, err
+	}
+	return embedder.EmbedQuery(ctx, query)
+}
+
+// getEmbedder returns the embedder given with vectorstores.WithEmbedder, or
+// the embedder of the store, or ErrMissingEmbedder if there is none.
+func (s Store) getEmbedder(opts vectorstores.Options) (embeddings.Embedder, error) {
+	if opts.Embedder != nil {
+		return opts.Embedder, nil
+	}
+	if s.embedder == nil {
+		return nil, ErrMissingEmbedder
+	}
+	return s.embedder, nil
 }
 
 func (s Store) getOptions(options ...vectorstores.Option) vectorstores.Options {
@@ -8,90 +8,346 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/tmc/langchaingo/schema"
+	"golang.org/x/exp/slices"
 )
 
-// upsertPoints updates or inserts points into the Qdrant collection.
+// getCollection returns the information of the Qdrant collection, or nil if
+// it doesn't exist.
+func (s Store) getCollection(ctx context.Context, baseURL *url.URL) (*collectionInfo, error) {
+	if s.grpc != nil {
+		return s.grpcGetCollection(ctx)
+	}
+
+	url := baseURL.JoinPath("collections", s.collectionName)
+	body,
+		status,
+		err := s.doRequest(
+		ctx, *url,
+		http.MethodGet,
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	switch status {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, newAPIError("getting collection", status, body)
+	}
+
+	var response collectionResponse
+	if err := json.NewDecoder(body).Decode(&response); err != nil {
+		return nil, err
+	}
+
+	info := &collectionInfo{}
+	vectors := response.Result.Config.Params.Vectors
+	if s.vectorName == "" {
+		var params vectorParams
+		if json.Unmarshal(vectors, &params) == nil {
+			info.vectorSize = params.Size
+			info.distance = params.Distance
+		}
+	} else {
+		var params map[string]vectorParams
+		if json.Unmarshal(vectors, &params) == nil {
+			info.vectorSize = params[s.vectorName].Size
+			info.distance = params[s.vectorName].Distance
+		}
+	}
+	return info, nil
+}
+
+// createCollectionRequest creates the Qdrant collection with a single vector
+// of the given size and distance metric.
+func (s Store) createCollectionRequest(
+	ctx context.Context,
+	baseURL *url.URL,
+	vectorSize uint64,
+	distance string,
+) error {
+	if s.grpc != nil {
+		return s.grpcCreateCollection(ctx, vectorSize, distance)
+	}
+
+	params := vectorParams{
+		Size:     vectorSize,
+		Distance: distance,
+	}
+	var vectors any = params
+	if s.vectorName != "" {
+		vectors = map[string]vectorParams{s.vectorName: params}
+	}
+	payload := createCollectionBody{
+		Vectors: vectors,
+	}
+	if s.sparseEmbedder != nil {
+		payload.SparseVectors = map[string]struct{}{s.sparseVectorName: {}}
+	}
+
+	url := baseURL.JoinPath("collections", s.collectionName)
+	body,
+		status,
+		err := s.doRequest(
+		ctx, *url,
+		http.MethodPut,
+		payload,
+	)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if status == http.StatusOK {
+		return nil
+	}
+
+	return newAPIError("creating collection", status, body)
+}
+
+// createFieldIndex creates an index of a payload field of the Qdrant
+// collection.
+func (s Store) createFieldIndex(
+	ctx context.Context,
+	baseURL *url.URL,
+	field string,
+	schemaType string,
+) error {
+	if s.grpc != nil {
+		return s.grpcCreateFieldIndex(ctx, field, schemaType)
+	}
+
+	payload := createFieldIndexBody{
+		FieldName:   field,
+		FieldSchema: schemaType,
+	}
+
+	url := baseURL.JoinPath("collections", s.collectionName, "index")
+	body,
+		status,
+		err := s.doRequest(
+		ctx, *url,
+		http.MethodPut,
+		payload,
+	)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if status == http.StatusOK {
+		return nil
+	}
+
+	return newAPIError("creating payload index", status, body)
+}
+
+// upsertPoints updates or inserts points into the Qdrant collection, in
+// batches of at most s.upsertBatchSize points. The sparse vectors are nil
+// without a sparse embedder. If a batch fails, the IDs of the points of the
+// preceding batches are returned along with the error.
 func (s Store) upsertPoints(
 	ctx context.Context,
 	baseURL *url.URL,
+	ids []string,
 	vectors [][]float32,
+	sparseVectors []SparseVector,
 	payloads []map[string]interface{},
 ) ([]string, error) {
-	ids := make([]string, len(vectors))
-	for i := range ids {
-		ids[i] = uuid.NewString()
+	for start := 0; start < len(ids); start += s.upsertBatchSize {
+		end := start + s.upsertBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var sparseBatch []SparseVector
+		if sparseVectors != nil {
+			sparseBatch = sparseVectors[start:end]
+		}
+		err := s.upsertBatch(ctx, baseURL, ids[start:end], vectors[start:end], sparseBatch, payloads[start:end])
+		if err != nil {
+			return ids[:start], err
+		}
 	}
 
+	return ids, nil
+}
+
+// upsertBatch updates or inserts a single batch of points into the Qdrant
+// collection.
+func (s Store) upsertBatch(
+	ctx context.Context,
+	baseURL *url.URL,
+	ids []string,
+	vectors [][]float32,
+	sparseVectors []SparseVector,
+	payloads []map[string]interface{},
+) error {
+	if s.grpc != nil {
+		return s.grpcUpsertBatch(ctx, ids, vectors, sparseVectors, payloads)
+	}
+
+	pointIDs := make([]any, len(ids))
+	for i, id := range ids {
+		pointIDs[i] = pointID(id)
+	}
+
+	var batchVectors any = vectors
+	switch {
+	case sparseVectors != nil:
+		// The default vector is named "" next to the sparse vector.
+		batchVectors = map[string]any{s.vectorName: vectors, s.sparseVectorName: sparseVectors}
+	case s.vectorName != "":
+		batchVectors = map[string][][]float32{s.vectorName: vectors}
+	}
 	payload := upsertBody{
 		Batch: upsertBatch{
-			IDs:      ids,
-			Vectors:  vectors,
+			IDs:      pointIDs,
+			Vectors:  batchVectors,
 			Payloads: payloads,
 		},
 	}
 
 	url := baseURL.JoinPath("collections", s.collectionName, "points")
+	query := url.Query()
+	query.Set("wait", strconv.FormatBool(s.writeWait))
+	if s.writeOrdering != "" {
+		query.Set("ordering", s.writeOrdering)
+	}
+	url.RawQuery = query.Encode()
 	body,
 		status,
-		err := DoRequest(
+		err := s.doRequest(
 		ctx, *url,
-		s.apiKey,
 		http.MethodPut,
 		payload,
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer body.Close()
 
 	if status == http.StatusOK {
-		return ids, nil
+		return nil
 	}
 
-	return nil,
-		newAPIError("upserting vectors", body)
+	return newAPIError("upserting vectors", status, body)
 }
 
-// searchPoints queries the Qdrant collection for points based on the provided parameters.
+// deletePoints deletes the points with the given IDs, or matching the filter,
+// from the Qdrant collection.
+func (s Store) deletePoints(
+	ctx context.Context,
+	baseURL *url.URL,
+	ids []string,
+	filter any,
+) error {
+	if s.grpc != nil {
+		return s.grpcDeletePoints(ctx, ids, filter)
+	}
+
+	pointIDs := make([]any, len(ids))
+	for i, id := range ids {
+		pointIDs[i] = pointID(id)
+	}
+
+	payload := deleteBody{}
+	switch {
+	case filter == nil:
+		payload.Points = pointIDs
+	case len(ids) == 0:
+		payload.Filter = filter
+	default:
+		payload.Filter = map[string]any{
+			"must": []any{
+				map[string]any{"has_id": pointIDs},
+				filter,
+			},
+		}
+	}
+
+	url := baseURL.JoinPath("collections", s.collectionName, "points", "delete")
+	body,
+		status,
+		err := s.doRequest(
+		ctx, *url,
+		http.MethodPost,
+		payload,
+	)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if status == http.StatusOK {
+		return nil
+	}
+
+	return newAPIError("deleting points", status, body)
+}
+
+// searchPoints queries the Qdrant collection for points based on the provided parameters,
+// returning their IDs and documents. The vector is either a dense []float32 or a
+// SparseVector searched against the sparse vector of the Store. If withVector is set,
+// the dense vectors of the points are returned too.
 func (s Store) searchPoints(
 	ctx context.Context,
 	baseURL *url.URL,
-	vector []float32,
+	vector any,
 	numVectors int,
 	scoreThreshold float32,
 	filter any,
-) ([]schema.Document, error) {
+	withVector bool,
+) ([]string, []schema.Document, [][]float32, error) {
+	if s.grpc != nil {
+		return s.grpcSearchPoints(ctx, vector, numVectors, scoreThreshold, filter, withVector)
+	}
+
+	searchVector := vector
+	switch v := vector.(type) {
+	case SparseVector:
+		searchVector = namedSparseVector{Name: s.sparseVectorName, Vector: v}
+	case []float32:
+		if s.vectorName != "" {
+			searchVector = namedVector{Name: s.vectorName, Vector: v}
+		}
+	}
 	payload := searchBody{
-		WithPayload: true,
-		WithVector:  false,
-		Vector:      vector,
+		WithPayload: s.payloadSelector(),
+		WithVector:  withVector,
+		Vector:      searchVector,
 		Limit:       numVectors,
 		Filter:      filter,
+		Params:      s.searchParams(),
 	}
 
 	if scoreThreshold != 0 {
 		payload.ScoreThreshold = scoreThreshold
 	}
 
-	url := baseURL.JoinPath("collections", s.collectionName, "points", "search")
+	url := s.withReadConsistency(baseURL.JoinPath("collections", s.collectionName, "points", "search"))
 	body,
 		statusCode,
-		err := DoRequest(
+		err := s.doRequest(
 		ctx, *url,
-		s.apiKey,
 		http.MethodPost,
 		payload,
 	)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 	defer body.Close()
 
 	if statusCode != http.StatusOK {
-		return nil, newAPIError("querying collection", body)
+		return nil, nil, nil, newAPIError("querying collection", statusCode, body)
 	}
 
 	var response searchResponse
@@ -99,57 +355,94 @@ func (s Store) searchPoints(
 	decoder := json.NewDecoder(body)
 	err = decoder.Decode(&response)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
+	ids := make([]string, len(response.Result))
 	docs := make([]schema.Document, len(response.Result))
+	var vectors [][]float32
+	if withVector {
+		vectors = make([][]float32, len(response.Result))
+	}
 	for i, match := range response.Result {
-		pageContent, ok := match.Payload[s.contentKey].(string)
-		if !ok {
-			return nil, fmt.Errorf("payload does not contain content key '%s'", s.contentKey)
+		ids[i] = normalizeID(strings.Trim(string(match.ID), `"`))
+		doc, err := s.scoredDocument(ids[i], match.Payload, match.Score)
+		if err != nil {
+			return nil, nil, nil, err
 		}
-		delete(match.Payload, s.contentKey)
-
-		doc := schema.Document{
-			PageContent: pageContent,
-			Metadata:    match.Payload,
-			Score:       match.Score,
+		docs[i] = doc
+		if withVector {
+			vectors[i], err = s.resultVector(match.Vector)
+			if err != nil {
+				return nil, nil, nil, err
+			}
 		}
+	}
 
-		docs[i] = doc
+	return ids, docs, vectors, nil
+}
+
+// resultVector returns the vector of a search result: the default vector, or
+// the named one if set.
+func (s Store) resultVector(raw json.RawMessage) ([]float32, error) {
+	var vector []float32
+	if s.vectorName == "" && s.sparseEmbedder == nil {
+		err := json.Unmarshal(raw, &vector)
+		return vector, err
 	}
 
-	return docs, nil
+	// The vectors of the points with a sparse vector are named, the
+	// default one "".
+	var vectors map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &vectors); err != nil {
+		return nil, err
+	}
+	rawVector, ok := vectors[s.vectorName]
+	if !ok {
+		return nil, fmt.Errorf("result does not contain vector '%s'", s.vectorName)
+	}
+	err := json.Unmarshal(rawVector, &vector)
+	return vector, err
 }
 
+// scroll returns the points of the Qdrant collection matching the filter,
+// starting at the given offset point ID if set, along with the ID to start the
+// next page at, empty after the last page.
 func (s Store) scroll(
 	ctx context.Context,
 	baseURL *url.URL,
 	numVectors int,
 	filter any,
-) ([]schema.Document, error) {
+	offset string,
+) ([]schema.Document, string, error) {
+	if s.grpc != nil {
+		return s.grpcScroll(ctx, numVectors, filter, offset)
+	}
+
 	payload := scrollBody{
 		WithPayload: true,
 		WithVector:  false,
 		Limit:       numVectors,
 		Filter:      filter,
 	}
+	if offset != "" {
+		payload.Offset = pointID(offset)
+	}
 
 	url := baseURL.JoinPath("collections", s.collectionName, "points", "scroll")
 	body,
 		statusCode,
-		err := DoRequest(
+		err := s.doRequest(
 		ctx, *url,
-		s.apiKey,
 		http.MethodPost,
 		payload,
 	)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer body.Close()
 
 	if statusCode != http.StatusOK {
-		return nil, newAPIError("querying collection", body)
+		return nil, "", newAPIError("querying collection", statusCode, body)
 	}
 
 	var response scrollResponse
@@ -157,34 +450,246 @@ func (s Store) scroll(
 	decoder := json.NewDecoder(body)
 	err = decoder.Decode(&response)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	docs := make([]schema.Document, len(response.Result.Points))
 	for i, match := range response.Result.Points {
+		doc, err := s.document(normalizeID(strings.Trim(string(match.ID), `"`)), match.Payload)
+		if err != nil {
+			return nil, "", err
+		}
+		docs[i] = doc
+	}
+
+	// The offset is either a UUID string, an integer, or null.
+	next := strings.Trim(string(response.Result.NextPageOffset), `"`)
+	if next == "null" {
+		next = ""
+	}
+
+	return docs, next, nil
+}
+
+// retrievePoints returns the documents of the points with the given IDs, by
+// point ID. The IDs of missing points are left out.
+func (s Store) retrievePoints(ctx context.Context, baseURL *url.URL, ids []string) (map[string]schema.Document, error) {
+	if s.grpc != nil {
+		return s.grpcRetrievePoints(ctx, ids)
+	}
+
+	payload := retrieveBody{
+		IDs:         make([]any, len(ids)),
+		WithPayload: true,
+	}
+	for i, id := range ids {
+		payload.IDs[i] = pointID(id)
+	}
 
-		pageContent, ok := match.Payload[s.contentKey].(string)
-		if !ok {
-			return nil, fmt.Errorf("payload does not contain content key '%s'", s.contentKey)
+	url := baseURL.JoinPath("collections", s.collectionName, "points")
+	body,
+		statusCode,
+		err := s.doRequest(
+		ctx, *url,
+		http.MethodPost,
+		payload,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	if statusCode != http.StatusOK {
+		return nil, newAPIError("retrieving points", statusCode, body)
+	}
+
+	var response retrieveResponse
+	if err := json.NewDecoder(body).Decode(&response); err != nil {
+		return nil, err
+	}
+	docs := make(map[string]schema.Document, len(response.Result))
+	for _, point := range response.Result {
+		id := normalizeID(strings.Trim(string(point.ID), `"`))
+		doc, err := s.document(id, point.Payload)
+		if err != nil {
+			return nil, err
 		}
-		delete(match.Payload, s.contentKey)
+		docs[id] = doc
+	}
+	return docs, nil
+}
+
+// normalizeID returns the canonical form of a point ID, as returned by
+// Qdrant: UUIDs are lowercase and hyphenated.
+func normalizeID(id string) string {
+	if _, ok := pointID(id).(uint64); ok {
+		return id
+	}
+	if u, err := uuid.Parse(id); err == nil {
+		return u.String()
+	}
+	return id
+}
+
+// payloadSelector returns the with_payload of the searches: true, or the
+// fields selected with WithPayloadInclude or WithPayloadExclude, always
+// including the content field.
+func (s Store) payloadSelector() any {
+	include, exclude := s.selectedPayload()
+	if include == nil && exclude == nil {
+		return true
+	}
+	return payloadSelector{Include: include, Exclude: exclude}
+}
+
+// selectedPayload returns the payload fields of the searches to include or to
+// exclude, both nil for the whole payload.
+func (s Store) selectedPayload() ([]string, []string) {
+	contentKeys := s.contentKeys()
+	if len(s.payloadInclude) > 0 {
+		include := append(contentKeys, s.payloadInclude...)
+		return include, nil
+	}
 
-		doc := schema.Document{
-			PageContent: pageContent,
-			Metadata:    match.Payload,
+	var exclude []string
+	for _, field := range s.payloadExclude {
+		if !slices.Contains(contentKeys, field) {
+			exclude = append(exclude, field)
 		}
+	}
+	return nil, exclude
+}
 
-		docs[i] = doc
+// contentKeys returns the payload fields the content of a point may be stored
+// in: the content key, then the fallback keys of WithContentKeyFallback.
+func (s Store) contentKeys() []string {
+	keys := []string{s.contentKey}
+	for _, key := range s.contentKeyFallback {
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
 	}
+	return keys
+}
 
-	return docs, nil
+// document returns the document stored in the payload of a point, holding the
+// ID of the point under the ID key if set.
+func (s Store) document(id string, payload map[string]interface{}) (schema.Document, error) {
+	var pageContent, contentKey string
+	for _, key := range s.contentKeys() {
+		if content, ok := payload[key].(string); ok {
+			pageContent, contentKey = content, key
+			delete(payload, key)
+			break
+		}
+	}
+	if contentKey == "" {
+		return schema.Document{}, fmt.Errorf("payload does not contain content key '%s'", s.contentKey)
+	}
+
+	metadata := payload
+	if s.nestedPayload {
+		metadata, _ = payload[metadataKey].(map[string]interface{})
+	}
+	if s.keepContentInMetadata {
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+		metadata[contentKey] = pageContent
+	}
+	if s.idKey != "" && id != "" {
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+		metadata[s.idKey] = id
+	}
+
+	return schema.Document{
+		PageContent: pageContent,
+		Metadata:    metadata,
+	}, nil
+}
+
+// scoredDocument returns the document stored in the payload of a point found
+// with the given similarity score.
+func (s Store) scoredDocument(id string, payload map[string]interface{}, score float32) (schema.Document, error) {
+	doc, err := s.document(id, payload)
+	if err != nil {
+		return schema.Document{}, err
+	}
+	doc.Score = score
+	if s.scoreKey != "" {
+		if doc.Metadata == nil {
+			doc.Metadata = map[string]interface{}{}
+		}
+		doc.Metadata[s.scoreKey] = score
+	}
+	return doc, nil
 }
 
-// doRequest performs an HTTP request to the Qdrant API.
+// pointID returns the Qdrant point ID of an ID: an unsigned integer if it is
+// one, the UUID string otherwise.
+func pointID(id string) any {
+	if n, err := strconv.ParseUint(id, 10, 64); err == nil {
+		return n
+	}
+	return id
+}
+
+// searchParams returns the search parameters of WithQuantizationSearch, nil
+// if not set.
+func (s Store) searchParams() *searchParams {
+	if s.quantizationSearch == nil {
+		return nil
+	}
+	return &searchParams{Quantization: s.quantizationSearch}
+}
+
+// withReadConsistency returns the URL of a search with the read consistency of
+// WithReadConsistency.
+func (s Store) withReadConsistency(url *url.URL) *url.URL {
+	if s.readConsistency != "" {
+		query := url.Query()
+		query.Set("consistency", s.readConsistency)
+		url.RawQuery = query.Encode()
+	}
+	return url
+}
+
+// DoRequest performs an HTTP request to the Qdrant API, authenticated with the
+// API key if not empty. The request is aborted when ctx is done.
 func DoRequest(ctx context.Context,
 	url url.URL,
 	apiKey,
 	method string,
 	payload interface{},
+) (io.ReadCloser, int, error) {
+	header := http.Header{}
+	if apiKey != "" {
+		header.Set("api-key", apiKey)
+	}
+	return doRequest(ctx, http.DefaultClient, url, header, method, payload)
+}
+
+// doRequest performs an HTTP request to the Qdrant API, authenticated with
+// the credentials of the Store.
+func (s Store) doRequest(ctx context.Context,
+	url url.URL,
+	method string,
+	payload interface{},
+) (io.ReadCloser, int, error) {
+	header := http.Header{}
+	for key, value := range s.authHeaders() {
+		header.Set(key, value)
+	}
+	return doRequest(ctx, s.httpClient, url, header, method, payload)
+}
+
+func doRequest(ctx context.Context,
+	client *http.Client,
+	url url.URL,
+	header http.Header,
+	method string,
+	payload interface{},
 ) (io.ReadCloser, int, error) {
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
@@ -192,23 +697,58 @@ func DoRequest(ctx context.Context,
 	}
 	body := bytes.NewReader(payloadBytes)
 
-	req, err := http.NewRequestWithContext(ctx, method, url.String()+"?wait=true", body)
+	// The write requests wait for their changes to be applied, unless the
+	// URL says otherwise.
+	query := url.Query()
+	if !query.Has("wait") {
+		query.Set("wait", "true")
+	}
+	url.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, method, url.String(), body)
 	if err != nil {
 		return nil, 0, err
 	}
 
+	req.Header = header
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("api-Key", apiKey)
 
-	r, err := http.DefaultClient.Do(req)
+	r, err := client.Do(req)
 	if err != nil {
 		return nil, 0, err
 	}
-	return r.Body, r.StatusCode, err
+	return drainingBody{r.Body}, r.StatusCode, err
+}
+
+// maxDrainedBytes is the size of the unread part of a response body drained
+// on close to reuse the connection, beyond which it is closed instead.
+const maxDrainedBytes = 64 << 10
+
+// drainingBody is a response body reading its unread part on close, e.g. the
+// trailing newline left by a json.Decoder, so that the HTTP client reuses the
+// connection for the next request.
+type drainingBody struct {
+	io.ReadCloser
+}
+
+func (b drainingBody) Close() error {
+	_, _ = io.Copy(io.Discard, io.LimitReader(b.ReadCloser, maxDrainedBytes))
+	return b.ReadCloser.Close()
+}
+
+// newHTTPClient returns the HTTP client of the REST API of a Store created
+// without WithHTTPClient, keeping alive more idle connections to Qdrant than
+// the http.DefaultTransport for the concurrent requests of a Store.
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+	transport.MaxIdleConns = defaultMaxIdleConns
+	transport.MaxIdleConnsPerHost = defaultMaxIdleConns
+	return &http.Client{Transport: transport}
 }
 
-// newAPIError creates an error based on the Qdrant API response.
-func newAPIError(task string, body io.ReadCloser) error {
+// newAPIError creates an error based on the Qdrant API response, wrapping
+// ErrCollectionNotFound, ErrUnauthorized or ErrBadRequest according to its
+// status.
+func newAPIError(task string, status int, body io.ReadCloser) error {
 	buf := new(bytes.Buffer)
 	_,
 		err := io.Copy(buf, body)
@@ -216,5 +756,26 @@ func newAPIError(task string, body io.ReadCloser) error {
 		return fmt.Errorf("failed to read body of error message: %w", err)
 	}
 
-	return fmt.Errorf("%s: %s", task, buf.String())
+	// The message is in the status of the JSON responses, which the
+	// authentication errors lack.
+	message := strings.TrimSpace(buf.String())
+	var response struct {
+		Status struct {
+			Error string `json:"error"`
+		} `json:"status"`
+	}
+	if json.Unmarshal(buf.Bytes(), &response) == nil && response.Status.Error != "" {
+		message = response.Status.Error
+	}
+
+	switch status {
+	case http.StatusNotFound:
+		return fmt.Errorf("%s: %w: %s", task, ErrCollectionNotFound, message)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%s: %w: %s", task, ErrUnauthorized, message)
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return fmt.Errorf("%s: %w: %s", task, ErrBadRequest, message)
+	default:
+		return fmt.Errorf("%s: status %d: %s", task, status, message)
+	}
 }
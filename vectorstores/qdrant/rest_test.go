@@ -0,0 +1,1480 @@
+package qdrant_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+	"github.com/tmc/langchaingo/vectorstores/qdrant"
+)
+
+// fakeEmbedder embeds every text as a vector of its length.
+type fakeEmbedder struct {
+	dimension int
+}
+
+func (e fakeEmbedder) EmbedDocuments(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+func (e fakeEmbedder) EmbedQuery(_ context.Context, text string) ([]float32, error) {
+	return e.embed(text), nil
+}
+
+func (e fakeEmbedder) embed(text string) []float32 {
+	vector := make([]float32, e.dimension)
+	for i := range vector {
+		vector[i] = float32(len(text))
+	}
+	return vector
+}
+
+// fakeRequest is a request received by the fake Qdrant server.
+type fakeRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   map[string]interface{}
+}
+
+// fakeQdrant is a fake Qdrant REST API recording the requests it receives.
+type fakeQdrant struct {
+	mu       sync.Mutex
+	requests []fakeRequest
+
+	// handle returns the status and response of a request; defaults to an
+	// empty successful response.
+	handle func(r fakeRequest) (int, interface{})
+}
+
+func (f *fakeQdrant) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := fakeRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Header: r.Header}
+	_ = json.NewDecoder(r.Body).Decode(&req.Body)
+
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	f.mu.Unlock()
+
+	status, response := http.StatusOK, interface{}(map[string]interface{}{"result": nil})
+	if f.handle != nil {
+		status, response = f.handle(req)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (f *fakeQdrant) received() []fakeRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fakeRequest(nil), f.requests...)
+}
+
+func newFakeStore(t *testing.T, fake *fakeQdrant, opts ...qdrant.Option) qdrant.Store {
+	t.Helper()
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	store, err := qdrant.New(append([]qdrant.Option{
+		qdrant.WithURL(*serverURL),
+		qdrant.WithCollectionName("test"),
+		qdrant.WithEmbedder(fakeEmbedder{dimension: 3}),
+	}, opts...)...)
+	require.NoError(t, err)
+	return store
+}
+
+// newCountingServer serves the fake Qdrant API, counting the connections
+// opened by the clients.
+func newCountingServer(tb testing.TB, fake *fakeQdrant) (url.URL, *atomic.Int64) {
+	tb.Helper()
+
+	conns := &atomic.Int64{}
+	server := httptest.NewUnstartedServer(fake)
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	tb.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(tb, err)
+	return *serverURL, conns
+}
+
+func TestConnectionReuse(t *testing.T) {
+	t.Parallel()
+
+	serverURL, conns := newCountingServer(t, &fakeQdrant{})
+	store, err := qdrant.New(
+		qdrant.WithURL(serverURL),
+		qdrant.WithCollectionName("test"),
+		qdrant.WithEmbedder(fakeEmbedder{dimension: 3}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	for i := 0; i < 10; i++ {
+		_, err := store.SimilaritySearch(context.Background(), "japan", 1)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int64(1), conns.Load())
+
+	var requests atomic.Int64
+	client := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		requests.Add(1)
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+	store, err = qdrant.New(
+		qdrant.WithURL(serverURL),
+		qdrant.WithCollectionName("test"),
+		qdrant.WithEmbedder(fakeEmbedder{dimension: 3}),
+		qdrant.WithHTTPClient(client),
+	)
+	require.NoError(t, err)
+	_, err = store.SimilaritySearch(context.Background(), "japan", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), requests.Load())
+}
+
+// roundTripperFunc is an http.RoundTripper calling the function.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// BenchmarkSimilaritySearch reports the connections opened per search, which
+// the HTTP client of the Store reuses.
+func BenchmarkSimilaritySearch(b *testing.B) {
+	serverURL, conns := newCountingServer(b, &fakeQdrant{})
+	store, err := qdrant.New(
+		qdrant.WithURL(serverURL),
+		qdrant.WithCollectionName("test"),
+		qdrant.WithEmbedder(fakeEmbedder{dimension: 3}),
+	)
+	require.NoError(b, err)
+	b.Cleanup(func() { _ = store.Close() })
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := store.SimilaritySearch(context.Background(), "japan", 1); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	b.ReportMetric(float64(conns.Load())/float64(b.N), "conns/op")
+}
+
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	fake := &fakeQdrant{
+		handle: func(fakeRequest) (int, interface{}) {
+			<-release
+			return http.StatusOK, map[string]interface{}{"result": nil}
+		},
+	}
+	store := newFakeStore(t, fake)
+	// Released before the server is closed, which waits for the handlers.
+	t.Cleanup(func() { close(release) })
+
+	start := time.Now()
+	_, err := store.SimilaritySearch(context.Background(), "tokyo", 1,
+		vectorstores.WithTimeout(20*time.Millisecond))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The earlier deadline of the context applies.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = store.SimilaritySearch(ctx, "tokyo", 1, vectorstores.WithTimeout(time.Hour))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestAuthHeaders(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		opts          []qdrant.Option
+		apiKey        string
+		authorization string
+	}{
+		{"unauthenticated", nil, "", ""},
+		{"api key", []qdrant.Option{qdrant.WithAPIKey("secret")}, "secret", ""},
+		{"bearer token", []qdrant.Option{qdrant.WithBearerToken("jwt")}, "", "Bearer jwt"},
+		{
+			"both",
+			[]qdrant.Option{qdrant.WithAPIKey("secret"), qdrant.WithBearerToken("jwt")},
+			"secret",
+			"Bearer jwt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fake := &fakeQdrant{}
+			store := newFakeStore(t, fake, tt.opts...)
+			ctx := context.Background()
+			_, err := store.AddDocuments(ctx, []schema.Document{{PageContent: "tokyo"}})
+			require.NoError(t, err)
+			_, err = store.SimilaritySearch(ctx, "tokyo", 1)
+			require.NoError(t, err)
+			_, _, err = store.PayloadSearchPage(ctx, 1, "")
+			require.NoError(t, err)
+
+			requests := fake.received()
+			require.Len(t, requests, 4)
+			for _, r := range requests {
+				assert.Equal(t, tt.apiKey, r.Header.Get("api-key"), r.Path)
+				assert.Equal(t, tt.authorization, r.Header.Get("Authorization"), r.Path)
+			}
+		})
+	}
+}
+
+func TestCreateCollectionIfNotExists(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{
+		handle: func(r fakeRequest) (int, interface{}) {
+			if r.Method == http.MethodGet {
+				return http.StatusNotFound, map[string]interface{}{"status": map[string]interface{}{"error": "not found"}}
+			}
+			return http.StatusOK, map[string]interface{}{"result": true}
+		},
+	}
+	store := newFakeStore(t, fake, qdrant.WithCreateCollectionIfNotExists(0, qdrant.DistanceDot))
+
+	for i := 0; i < 2; i++ {
+		_, err := store.AddDocuments(context.Background(), []schema.Document{{PageContent: "tokyo"}})
+		require.NoError(t, err)
+	}
+
+	requests := fake.received()
+	require.Len(t, requests, 4)
+	assert.Equal(t, http.MethodGet, requests[0].Method)
+	assert.Equal(t, "/collections/test", requests[1].Path)
+	assert.Equal(t, http.MethodPut, requests[1].Method)
+	assert.Equal(t, map[string]interface{}{
+		"vectors": map[string]interface{}{"size": float64(3), "distance": "Dot"},
+	}, requests[1].Body)
+	assert.Equal(t, "/collections/test/points", requests[2].Path)
+	assert.Equal(t, "/collections/test/points", requests[3].Path)
+}
+
+func TestEnvVars(t *testing.T) { //nolint:paralleltest
+	var apiKeys []string
+	fake := &fakeQdrant{
+		handle: func(r fakeRequest) (int, interface{}) {
+			apiKeys = append(apiKeys, r.Header.Get("api-key"))
+			return http.StatusOK, map[string]interface{}{"result": []interface{}{}}
+		},
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	opts := []qdrant.Option{qdrant.WithCollectionName("test"), qdrant.WithEmbedder(fakeEmbedder{dimension: 3})}
+	t.Setenv(qdrant.URLEnvVarName, "")
+	_, err := qdrant.New(opts...)
+	require.ErrorIs(t, err, qdrant.ErrInvalidOptions)
+
+	t.Setenv(qdrant.URLEnvVarName, server.URL)
+	t.Setenv(qdrant.APIKeyEnvVarName, "env-key")
+	store, err := qdrant.New(opts...)
+	require.NoError(t, err)
+	_, err = store.SimilaritySearch(context.Background(), "japan", 1)
+	require.NoError(t, err)
+
+	// The options take precedence.
+	store, err = qdrant.New(append(opts,
+		qdrant.WithURL(url.URL{Scheme: "http", Host: "localhost:1"}), qdrant.WithAPIKey("option-key"))...)
+	require.NoError(t, err)
+	_, err = store.SimilaritySearch(context.Background(), "japan", 1)
+	require.Error(t, err)
+	store = newFakeStore(t, fake, qdrant.WithAPIKey("option-key"))
+	_, err = store.SimilaritySearch(context.Background(), "japan", 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"env-key", "option-key"}, apiKeys)
+}
+
+func TestCreateCollectionInvalidDistance(t *testing.T) {
+	t.Parallel()
+
+	_, err := qdrant.New(
+		qdrant.WithURL(url.URL{Scheme: "http", Host: "localhost:6333"}),
+		qdrant.WithCollectionName("test"),
+		qdrant.WithEmbedder(fakeEmbedder{dimension: 3}),
+		qdrant.WithCreateCollectionIfNotExists(3, "Hamming"),
+	)
+	require.ErrorIs(t, err, qdrant.ErrInvalidOptions)
+}
+
+func TestVectorName(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{
+		handle: func(r fakeRequest) (int, interface{}) {
+			if r.Method == http.MethodGet {
+				return http.StatusNotFound, map[string]interface{}{}
+			}
+			return http.StatusOK, map[string]interface{}{"result": []interface{}{}}
+		},
+	}
+	store := newFakeStore(t, fake,
+		qdrant.WithVectorName("title"),
+		qdrant.WithCreateCollectionIfNotExists(0, qdrant.DistanceCosine),
+	)
+
+	_, err := store.AddDocuments(context.Background(), []schema.Document{{PageContent: "tokyo"}})
+	require.NoError(t, err)
+	_, err = store.SimilaritySearch(context.Background(), "japan", 1)
+	require.NoError(t, err)
+
+	requests := fake.received()
+	require.Len(t, requests, 4)
+	assert.Equal(t, map[string]interface{}{
+		"title": map[string]interface{}{"size": float64(3), "distance": "Cosine"},
+	}, requests[1].Body["vectors"])
+	batch, _ := requests[2].Body["batch"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"title": []interface{}{[]interface{}{float64(5), float64(5), float64(5)}},
+	}, batch["vectors"])
+	assert.Equal(t, map[string]interface{}{
+		"name":   "title",
+		"vector": []interface{}{float64(5), float64(5), float64(5)},
+	}, requests[3].Body["vector"])
+}
+
+func TestDeleteDocuments(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{}
+	store := newFakeStore(t, fake)
+	filter := map[string]interface{}{
+		"must": []interface{}{map[string]interface{}{"key": "user", "match": map[string]interface{}{"value": "alice"}}},
+	}
+
+	require.ErrorIs(t, store.DeleteDocuments(context.Background(), nil), qdrant.ErrMissingIDsOrFilter)
+	uuid := "5d6f5b2e-4a8b-4c3d-9e1f-0a1b2c3d4e5f"
+	require.NoError(t, store.DeleteDocuments(context.Background(), []string{uuid}))
+	require.NoError(t, store.DeleteDocuments(context.Background(), nil, vectorstores.WithFilters(filter)))
+
+	requests := fake.received()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "/collections/test/points/delete", requests[0].Path)
+	assert.Equal(t, map[string]interface{}{"points": []interface{}{uuid}}, requests[0].Body)
+	assert.Equal(t, map[string]interface{}{"filter": filter}, requests[1].Body)
+}
+
+func TestDeleteDocumentsIntegerIDs(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{}
+	store := newFakeStore(t, fake)
+	filter := map[string]interface{}{
+		"must": []interface{}{map[string]interface{}{"key": "user", "match": map[string]interface{}{"value": "alice"}}},
+	}
+
+	require.NoError(t, store.DeleteDocuments(context.Background(), []string{"1", "2"}))
+	require.NoError(t, store.DeleteDocuments(context.Background(), []string{"1", "2"}, vectorstores.WithFilters(filter)))
+
+	requests := fake.received()
+	require.Len(t, requests, 2)
+	assert.Equal(t, map[string]interface{}{"points": []interface{}{float64(1), float64(2)}}, requests[0].Body)
+	assert.Equal(t, map[string]interface{}{"filter": map[string]interface{}{
+		"must": []interface{}{
+			map[string]interface{}{"has_id": []interface{}{float64(1), float64(2)}},
+			filter,
+		},
+	}}, requests[1].Body)
+}
+
+func TestAddDocumentsIDKey(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{}
+	store := newFakeStore(t, fake, qdrant.WithIDKey("id"))
+
+	docID := "5D6F5B2E-4A8B-4C3D-9E1F-0A1B2C3D4E5F"
+	ids, err := store.AddDocuments(context.Background(), []schema.Document{
+		{PageContent: "tokyo", Metadata: map[string]any{"id": docID}},
+		{PageContent: "osaka", Metadata: map[string]any{"id": 42}},
+		{PageContent: "kyoto", Metadata: map[string]any{"id": "not an id"}},
+		{PageContent: "nara"},
+	})
+	require.NoError(t, err)
+	require.Len(t, ids, 4)
+	assert.Equal(t, "5d6f5b2e-4a8b-4c3d-9e1f-0a1b2c3d4e5f", ids[0])
+	assert.Equal(t, "42", ids[1])
+	assert.NotEqual(t, "not an id", ids[2])
+	assert.NotEmpty(t, ids[3])
+
+	requests := fake.received()
+	require.Len(t, requests, 2)
+	batch, _ := requests[1].Body["batch"].(map[string]interface{})
+	assert.Equal(t, []interface{}{ids[0], float64(42), ids[2], ids[3]}, batch["ids"])
+}
+
+func TestAddDocumentsContentHashID(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{}
+	store := newFakeStore(t, fake, qdrant.WithIDKey("id"))
+
+	docs := []schema.Document{
+		{PageContent: "tokyo", Metadata: map[string]any{"id": 1, "source": "a"}},
+		{PageContent: "tokyo", Metadata: map[string]any{"id": 2, "source": "b"}},
+	}
+	ids, err := store.AddDocuments(context.Background(), docs, vectorstores.WithContentHashID())
+	require.NoError(t, err)
+	require.Len(t, ids, 2)
+	assert.Equal(t, ids[0], ids[1])
+	_, err = uuid.Parse(ids[0])
+	require.NoError(t, err)
+
+	again, err := store.AddDocuments(context.Background(), docs, vectorstores.WithContentHashID("source"))
+	require.NoError(t, err)
+	assert.NotEqual(t, again[0], again[1])
+	assert.NotEqual(t, ids[0], again[0])
+}
+
+func TestSimilaritySearchScoreKey(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{
+		handle: func(fakeRequest) (int, interface{}) {
+			return http.StatusOK, map[string]interface{}{"result": []interface{}{
+				map[string]interface{}{"score": 0.75, "payload": map[string]interface{}{"content": "tokyo", "score": "A"}},
+			}}
+		},
+	}
+
+	docs, err := newFakeStore(t, fake).SimilaritySearch(context.Background(), "japan", 1)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.InDelta(t, 0.75, docs[0].Score, 1e-6)
+	assert.Equal(t, map[string]any{"score": "A"}, docs[0].Metadata)
+
+	docs, err = newFakeStore(t, fake, qdrant.WithScoreKey("_score")).SimilaritySearch(context.Background(), "japan", 1)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, map[string]any{"score": "A", "_score": float32(0.75)}, docs[0].Metadata)
+}
+
+func TestKeepContentInMetadata(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{
+		handle: func(fakeRequest) (int, interface{}) {
+			return http.StatusOK, map[string]interface{}{"result": []interface{}{
+				map[string]interface{}{"score": 0.75, "payload": map[string]interface{}{"content": "tokyo", "country": "japan"}},
+			}}
+		},
+	}
+
+	docs, err := newFakeStore(t, fake).SimilaritySearch(context.Background(), "japan", 1)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "tokyo", docs[0].PageContent)
+	assert.Equal(t, map[string]any{"country": "japan"}, docs[0].Metadata)
+
+	docs, err = newFakeStore(t, fake, qdrant.WithKeepContentInMetadata()).SimilaritySearch(context.Background(), "japan", 1)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "tokyo", docs[0].PageContent)
+	assert.Equal(t, map[string]any{"content": "tokyo", "country": "japan"}, docs[0].Metadata)
+}
+
+func TestSimilaritySearchWithScore(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{
+		handle: func(fakeRequest) (int, interface{}) {
+			return http.StatusOK, map[string]interface{}{"result": []interface{}{
+				map[string]interface{}{"score": 0.75, "payload": map[string]interface{}{"content": "tokyo"}},
+				map[string]interface{}{"score": 0.5, "payload": map[string]interface{}{"content": "osaka"}},
+			}}
+		},
+	}
+
+	docs, err := newFakeStore(t, fake).SimilaritySearchWithScore(context.Background(), "japan", 2)
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.Equal(t, "tokyo", docs[0].Document.PageContent)
+	assert.InDelta(t, 0.75, docs[0].Score, 1e-6)
+	assert.Equal(t, "osaka", docs[1].Document.PageContent)
+	assert.InDelta(t, 0.5, docs[1].Score, 1e-6)
+	assert.Empty(t, docs[0].Document.Metadata)
+}
+
+func TestSimilaritySearchGrouped(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{
+		handle: func(fakeRequest) (int, interface{}) {
+			hit := func(id int, score float64, content, source string) map[string]interface{} {
+				return map[string]interface{}{
+					"id":      id,
+					"score":   score,
+					"payload": map[string]interface{}{"content": content, "metadata": map[string]interface{}{"source": source}},
+				}
+			}
+			return http.StatusOK, map[string]interface{}{"result": map[string]interface{}{"groups": []interface{}{
+				map[string]interface{}{"id": "a.md", "hits": []interface{}{hit(1, 0.9, "tokyo", "a.md"), hit(2, 0.8, "osaka", "a.md")}},
+				map[string]interface{}{"id": "b.md", "hits": []interface{}{hit(3, 0.7, "kyoto", "b.md")}},
+			}}}
+		},
+	}
+	store := newFakeStore(t, fake, qdrant.WithNestedPayload(true))
+
+	docs, err := store.SimilaritySearchGrouped(context.Background(), "japan", "source", 2, 3)
+	require.NoError(t, err)
+	require.Len(t, docs, 3)
+	for i, content := range []string{"tokyo", "osaka", "kyoto"} {
+		assert.Equal(t, content, docs[i].PageContent)
+	}
+	assert.Equal(t, "b.md", docs[2].Metadata["source"])
+	assert.InDelta(t, 0.7, docs[2].Score, 1e-6)
+
+	requests := fake.received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "/collections/test/points/search/groups", requests[0].Path)
+	assert.Equal(t, "metadata.source", requests[0].Body["group_by"])
+	assert.EqualValues(t, 2, requests[0].Body["group_size"])
+	assert.EqualValues(t, 3, requests[0].Body["limit"])
+
+	_, err = store.SimilaritySearchGrouped(context.Background(), "japan", "source", 0, 3)
+	require.Error(t, err)
+}
+
+func TestConsistency(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{}
+	docs := []schema.Document{{PageContent: "tokyo"}}
+
+	_, err := newFakeStore(t, fake).AddDocuments(context.Background(), docs)
+	require.NoError(t, err)
+	_, err = newFakeStore(t, fake).SimilaritySearch(context.Background(), "japan", 1)
+	require.NoError(t, err)
+	requests := fake.received()
+	require.Len(t, requests, 3)
+	assert.Equal(t, url.Values{"wait": {"true"}}, requests[1].Query)
+	assert.Equal(t, url.Values{"wait": {"true"}}, requests[2].Query)
+
+	fake = &fakeQdrant{}
+	store := newFakeStore(t, fake,
+		qdrant.WithReadConsistency(qdrant.ReadConsistencyMajority),
+		qdrant.WithWriteWait(false),
+		qdrant.WithWriteOrdering(qdrant.WriteOrderingStrong))
+	_, err = store.AddDocuments(context.Background(), docs)
+	require.NoError(t, err)
+	_, err = store.SimilaritySearch(context.Background(), "japan", 1)
+	require.NoError(t, err)
+	requests = fake.received()
+	require.Len(t, requests, 3)
+	assert.Equal(t, url.Values{"wait": {"false"}, "ordering": {"strong"}}, requests[1].Query)
+	assert.Equal(t, "majority", requests[2].Query.Get("consistency"))
+
+	for _, opt := range []qdrant.Option{
+		qdrant.WithReadConsistency("most"),
+		qdrant.WithReadConsistency("0"),
+		qdrant.WithWriteOrdering("total"),
+	} {
+		_, err := qdrant.New(qdrant.WithURL(url.URL{Scheme: "http", Host: "localhost:6333"}),
+			qdrant.WithCollectionName("test"), opt)
+		require.ErrorIs(t, err, qdrant.ErrInvalidOptions)
+	}
+	_, err = qdrant.New(qdrant.WithURL(url.URL{Scheme: "http", Host: "localhost:6333"}),
+		qdrant.WithCollectionName("test"), qdrant.WithReadConsistency("2"))
+	require.NoError(t, err)
+}
+
+func TestClampScoreThreshold(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{}
+	_, err := newFakeStore(t, fake).SimilaritySearch(context.Background(), "japan", 1,
+		vectorstores.WithScoreThreshold(1.2))
+	require.Error(t, err)
+	assert.Empty(t, fake.received())
+
+	store := newFakeStore(t, fake, qdrant.WithClampScoreThreshold())
+	_, err = store.SimilaritySearch(context.Background(), "japan", 1, vectorstores.WithScoreThreshold(1.2))
+	require.NoError(t, err)
+	_, err = store.SimilaritySearch(context.Background(), "japan", 1, vectorstores.WithScoreThreshold(-0.5))
+	require.NoError(t, err)
+	requests := fake.received()
+	require.Len(t, requests, 2)
+	assert.InDelta(t, 1, requests[0].Body["score_threshold"], 1e-6)
+	assert.InDelta(t, 0, requests[1].Body["score_threshold"], 1e-6)
+}
+
+func TestQuantizationSearch(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{}
+	_, err := newFakeStore(t, fake).SimilaritySearch(context.Background(), "japan", 1)
+	require.NoError(t, err)
+	_, err = newFakeStore(t, fake, qdrant.WithQuantizationSearch(true, 2.5)).
+		SimilaritySearch(context.Background(), "japan", 1)
+	require.NoError(t, err)
+	_, err = newFakeStore(t, fake, qdrant.WithQuantizationSearch(false, 0)).
+		SimilaritySearch(context.Background(), "japan", 1)
+	require.NoError(t, err)
+	requests := fake.received()
+	require.Len(t, requests, 3)
+	assert.NotContains(t, requests[0].Body, "params")
+	assert.Equal(t, map[string]interface{}{
+		"quantization": map[string]interface{}{"rescore": true, "oversampling": 2.5},
+	}, requests[1].Body["params"])
+	assert.Equal(t, map[string]interface{}{
+		"quantization": map[string]interface{}{"rescore": false},
+	}, requests[2].Body["params"])
+
+	for _, oversampling := range []float64{0.5, -1} {
+		_, err := qdrant.New(qdrant.WithURL(url.URL{Scheme: "http", Host: "localhost:6333"}),
+			qdrant.WithCollectionName("test"), qdrant.WithQuantizationSearch(true, oversampling))
+		require.ErrorIs(t, err, qdrant.ErrInvalidOptions)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		status   int
+		response interface{}
+		err      error
+		message  string
+	}{
+		{
+			status:   http.StatusNotFound,
+			response: map[string]interface{}{"status": map[string]interface{}{"error": "Not found: Collection `test` doesn't exist!"}},
+			err:      qdrant.ErrCollectionNotFound,
+			message:  "querying collection: collection not found: Not found: Collection `test` doesn't exist!",
+		},
+		{
+			status:   http.StatusUnauthorized,
+			response: "Must provide an API key or an Authorization bearer token",
+			err:      qdrant.ErrUnauthorized,
+			message:  "querying collection: unauthorized: \"Must provide an API key or an Authorization bearer token\"",
+		},
+		{
+			status:   http.StatusBadRequest,
+			response: map[string]interface{}{"status": map[string]interface{}{"error": "Bad request: Index required"}},
+			err:      qdrant.ErrBadRequest,
+			message:  "querying collection: bad request: Bad request: Index required",
+		},
+		{
+			status:   http.StatusInternalServerError,
+			response: map[string]interface{}{"status": map[string]interface{}{"error": "timeout"}},
+			message:  "querying collection: status 500: timeout",
+		},
+	}
+	for _, tt := range tests {
+		fake := &fakeQdrant{
+			handle: func(fakeRequest) (int, interface{}) { return tt.status, tt.response },
+		}
+		_, err := newFakeStore(t, fake).SimilaritySearch(context.Background(), "japan", 1)
+		require.EqualError(t, err, tt.message)
+		for _, target := range []error{qdrant.ErrCollectionNotFound, qdrant.ErrUnauthorized, qdrant.ErrBadRequest} {
+			assert.Equal(t, target == tt.err, errors.Is(err, target), tt.message)
+		}
+	}
+}
+
+func TestContentKey(t *testing.T) {
+	t.Parallel()
+
+	// The fake returns the payload of the last upserted point.
+	var payload interface{}
+	fake := &fakeQdrant{}
+	fake.handle = func(r fakeRequest) (int, interface{}) {
+		switch r.Method {
+		case http.MethodGet:
+			return http.StatusOK, map[string]interface{}{"result": nil}
+		case http.MethodPut:
+			batch, _ := r.Body["batch"].(map[string]interface{})
+			payloads, _ := batch["payloads"].([]interface{})
+			payload = payloads[len(payloads)-1]
+		}
+		return http.StatusOK, map[string]interface{}{"result": []interface{}{
+			map[string]interface{}{"score": 0.75, "payload": payload},
+		}}
+	}
+	store := newFakeStore(t, fake)
+
+	_, err := store.AddDocuments(context.Background(), []schema.Document{
+		{PageContent: "tokyo", Metadata: map[string]any{"country": "japan"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"content": "tokyo", "country": "japan"}, payload)
+
+	docs, err := store.SimilaritySearch(context.Background(), "japan", 1)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "tokyo", docs[0].PageContent)
+	assert.Equal(t, map[string]any{"country": "japan"}, docs[0].Metadata)
+
+	for _, opts := range [][]qdrant.Option{
+		{qdrant.WithContentKey("")},
+		{qdrant.WithContentKey("metadata"), qdrant.WithNestedPayload(true)},
+	} {
+		_, err := qdrant.New(append([]qdrant.Option{
+			qdrant.WithURL(url.URL{Scheme: "http", Host: "localhost:6333"}),
+			qdrant.WithCollectionName("test"),
+		}, opts...)...)
+		require.ErrorIs(t, err, qdrant.ErrInvalidOptions)
+	}
+}
+
+func TestAddDocumentsUpsertBatches(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{}
+	fake.handle = func(fakeRequest) (int, interface{}) {
+		if len(fake.received()) == 4 {
+			return http.StatusInternalServerError, map[string]interface{}{"status": map[string]interface{}{"error": "timeout"}}
+		}
+		return http.StatusOK, map[string]interface{}{"result": nil}
+	}
+	store := newFakeStore(t, fake, qdrant.WithUpsertBatchSize(2))
+
+	docs := []schema.Document{}
+	for _, city := range []string{"tokyo", "osaka", "kyoto", "nara", "kobe"} {
+		docs = append(docs, schema.Document{PageContent: city})
+	}
+	ids, err := store.AddDocuments(context.Background(), docs)
+	require.ErrorContains(t, err, "timeout")
+	assert.Len(t, ids, 4)
+
+	requests := fake.received()
+	require.Len(t, requests, 4)
+	assert.Equal(t, http.MethodGet, requests[0].Method)
+	for i, size := range []int{2, 2, 1} {
+		batch, _ := requests[i+1].Body["batch"].(map[string]interface{})
+		assert.Len(t, batch["ids"], size)
+	}
+}
+
+func TestAddVectors(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{}
+	store := newFakeStore(t, fake, qdrant.WithEmbedder(nil))
+
+	docs := []schema.Document{{PageContent: "tokyo"}, {PageContent: "osaka"}}
+	ids, err := store.AddVectors(context.Background(), [][]float32{{1, 0}, {0, 1}}, docs)
+	require.NoError(t, err)
+	assert.Len(t, ids, 2)
+
+	requests := fake.received()
+	require.Len(t, requests, 2)
+	batch, _ := requests[1].Body["batch"].(map[string]interface{})
+	assert.Equal(t, []interface{}{[]interface{}{1.0, 0.0}, []interface{}{0.0, 1.0}}, batch["vectors"])
+
+	_, err = store.AddVectors(context.Background(), [][]float32{{1, 0}}, docs)
+	require.Error(t, err)
+	_, err = store.AddVectors(context.Background(), [][]float32{{1, 0}, {1}}, docs)
+	require.ErrorIs(t, err, qdrant.ErrVectorDimensionMismatch)
+
+	_, err = store.AddDocuments(context.Background(), docs)
+	require.ErrorIs(t, err, qdrant.ErrMissingEmbedder)
+	_, err = store.SimilaritySearch(context.Background(), "japan", 1)
+	require.ErrorIs(t, err, qdrant.ErrMissingEmbedder)
+}
+
+func TestSimilaritySearchByVector(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{
+		handle: func(fakeRequest) (int, interface{}) {
+			return http.StatusOK, map[string]interface{}{"result": []interface{}{
+				map[string]interface{}{"score": 0.75, "payload": map[string]interface{}{"content": "tokyo"}},
+			}}
+		},
+	}
+	store := newFakeStore(t, fake, qdrant.WithEmbedder(nil))
+
+	docs, err := store.SimilaritySearchByVector(context.Background(), []float32{0.5, 0.25}, 1)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "tokyo", docs[0].PageContent)
+
+	requests := fake.received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "/collections/test/points/search", requests[0].Path)
+	assert.Equal(t, []interface{}{0.5, 0.25}, requests[0].Body["vector"])
+	assert.EqualValues(t, 1, requests[0].Body["limit"])
+}
+
+func TestMaxMarginalRelevanceSearch(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{
+		handle: func(fakeRequest) (int, interface{}) {
+			point := func(content string, vector ...float64) map[string]interface{} {
+				return map[string]interface{}{"payload": map[string]interface{}{"content": content}, "vector": vector}
+			}
+			return http.StatusOK, map[string]interface{}{"result": []interface{}{
+				point("tokyo", 1, 1),
+				point("tokyo!", 1, 1.01),
+				point("osaka", 1, -0.2),
+			}}
+		},
+	}
+	store := newFakeStore(t, fake, qdrant.WithEmbedder(fakeEmbedder{dimension: 2}))
+
+	docs, err := store.MaxMarginalRelevanceSearch(context.Background(), "japan", 2, 3, 0.3)
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.Equal(t, "tokyo", docs[0].PageContent)
+	assert.Equal(t, "osaka", docs[1].PageContent)
+
+	requests := fake.received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, true, requests[0].Body["with_vector"])
+	assert.Equal(t, float64(3), requests[0].Body["limit"])
+
+	_, err = store.MaxMarginalRelevanceSearch(context.Background(), "japan", 2, 3, 1.5)
+	require.Error(t, err)
+}
+
+func TestSimilaritySearchIncludeVectors(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{
+		handle: func(fakeRequest) (int, interface{}) {
+			return http.StatusOK, map[string]interface{}{"result": []interface{}{
+				map[string]interface{}{"payload": map[string]interface{}{"content": "tokyo"}, "vector": []float64{1, 0.5}},
+			}}
+		},
+	}
+	store := newFakeStore(t, fake, qdrant.WithEmbedder(fakeEmbedder{dimension: 2}))
+
+	docs, err := store.SimilaritySearch(context.Background(), "japan", 1)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.NotContains(t, docs[0].Metadata, vectorstores.VectorMetadataKey)
+
+	docs, err = store.SimilaritySearch(context.Background(), "japan", 1, vectorstores.WithIncludeVectors(true))
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, []float32{1, 0.5}, docs[0].Metadata[vectorstores.VectorMetadataKey])
+
+	docs, err = store.MaxMarginalRelevanceSearch(context.Background(), "japan", 1, 1, 0.5, vectorstores.WithIncludeVectors(true))
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, []float32{1, 0.5}, docs[0].Metadata[vectorstores.VectorMetadataKey])
+
+	requests := fake.received()
+	require.Len(t, requests, 3)
+	assert.Equal(t, false, requests[0].Body["with_vector"])
+	assert.Equal(t, true, requests[1].Body["with_vector"])
+}
+
+func TestEmbedderOverride(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{
+		handle: func(r fakeRequest) (int, interface{}) {
+			if r.Method == http.MethodGet {
+				return http.StatusNotFound, map[string]interface{}{}
+			}
+			return http.StatusOK, map[string]interface{}{"result": []interface{}{}}
+		},
+	}
+	store := newFakeStore(t, fake)
+	override := vectorstores.WithEmbedder(fakeEmbedder{dimension: 2})
+
+	_, err := store.AddDocuments(context.Background(), []schema.Document{{PageContent: "tokyo"}}, override)
+	require.NoError(t, err)
+	_, err = store.SimilaritySearch(context.Background(), "japan", 1, override)
+	require.NoError(t, err)
+	_, err = store.SimilaritySearch(context.Background(), "japan", 1)
+	require.NoError(t, err)
+
+	requests := fake.received()
+	require.Len(t, requests, 4)
+	batch, _ := requests[1].Body["batch"].(map[string]interface{})
+	assert.Equal(t, []interface{}{[]interface{}{float64(5), float64(5)}}, batch["vectors"])
+	assert.Equal(t, []interface{}{float64(5), float64(5)}, requests[2].Body["vector"])
+	assert.Equal(t, []interface{}{float64(5), float64(5), float64(5)}, requests[3].Body["vector"])
+}
+
+func TestAddDocumentsVectorDimensionMismatch(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{
+		handle: func(fakeRequest) (int, interface{}) {
+			return http.StatusOK, map[string]interface{}{"result": map[string]interface{}{
+				"config": map[string]interface{}{"params": map[string]interface{}{
+					"vectors": map[string]interface{}{"size": 1536, "distance": "Cosine"},
+				}},
+			}}
+		},
+	}
+	store := newFakeStore(t, fake, qdrant.WithEmbedder(fakeEmbedder{dimension: 768}))
+
+	for i := 0; i < 2; i++ {
+		_, err := store.AddDocuments(context.Background(), []schema.Document{{PageContent: "tokyo"}})
+		require.ErrorIs(t, err, qdrant.ErrVectorDimensionMismatch)
+		require.ErrorContains(t, err, "embedder produced 768-dim vectors but collection expects 1536")
+	}
+
+	// The collection config is fetched only once.
+	requests := fake.received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, http.MethodGet, requests[0].Method)
+}
+
+func TestHybridSearch(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{}
+	store := newFakeStore(t, fake)
+	filter := map[string]interface{}{
+		"must": []interface{}{map[string]interface{}{"key": "country", "match": map[string]interface{}{"value": "japan"}}},
+	}
+
+	_, err := store.HybridSearch(context.Background(), "capital", 2, []string{"tokyo", "kyoto"},
+		vectorstores.WithFilters(filter))
+	require.NoError(t, err)
+
+	requests := fake.received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "/collections/test/points/search", requests[0].Path)
+	assert.Equal(t, map[string]interface{}{"must": []interface{}{
+		filter,
+		map[string]interface{}{"should": []interface{}{
+			map[string]interface{}{"key": "content", "match": map[string]interface{}{"text": "tokyo"}},
+			map[string]interface{}{"key": "content", "match": map[string]interface{}{"text": "kyoto"}},
+		}},
+	}}, requests[0].Body["filter"])
+}
+
+func TestCreatePayloadIndex(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{}
+	store := newFakeStore(t, fake)
+
+	require.NoError(t, store.CreatePayloadIndex(context.Background(), "tenant_id", qdrant.PayloadSchemaKeyword))
+	require.ErrorIs(t, store.CreatePayloadIndex(context.Background(), "location", "geo"), qdrant.ErrUnsupportedSchemaType)
+
+	requests := fake.received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, http.MethodPut, requests[0].Method)
+	assert.Equal(t, "/collections/test/index", requests[0].Path)
+	assert.Equal(t, map[string]interface{}{"field_name": "tenant_id", "field_schema": "keyword"}, requests[0].Body)
+}
+
+func TestPayloadSearchPage(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{}
+	fake.handle = func(r fakeRequest) (int, interface{}) {
+		point := map[string]interface{}{"id": 1, "payload": map[string]interface{}{"content": "tokyo"}}
+		if r.Body["offset"] == nil {
+			return http.StatusOK, map[string]interface{}{"result": map[string]interface{}{
+				"points": []interface{}{point}, "next_page_offset": 2,
+			}}
+		}
+		return http.StatusOK, map[string]interface{}{"result": map[string]interface{}{
+			"points": []interface{}{point}, "next_page_offset": nil,
+		}}
+	}
+	store := newFakeStore(t, fake)
+
+	docs, next, err := store.PayloadSearchPage(context.Background(), 1, "")
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "2", next)
+
+	docs, next, err = store.PayloadSearchPage(context.Background(), 1, next)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Empty(t, next)
+
+	requests := fake.received()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "/collections/test/points/scroll", requests[1].Path)
+	assert.Equal(t, float64(2), requests[1].Body["offset"])
+}
+
+func TestContextCancellation(t *testing.T) {
+	t.Parallel()
+
+	// The server never answers, until the test ends.
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(done) })
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	store, err := qdrant.New(
+		qdrant.WithURL(*serverURL),
+		qdrant.WithCollectionName("test"),
+		qdrant.WithEmbedder(fakeEmbedder{dimension: 3}),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = store.SimilaritySearch(ctx, "japan", 1)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = store.AddDocuments(ctx, []schema.Document{{PageContent: "tokyo"}})
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = store.PayloadSearch(ctx, 1)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestNestedPayload(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{
+		handle: func(r fakeRequest) (int, interface{}) {
+			if r.Path != "/collections/test/points/search" {
+				return http.StatusOK, map[string]interface{}{"result": nil}
+			}
+			return http.StatusOK, map[string]interface{}{"result": []interface{}{
+				map[string]interface{}{"score": 0.5, "payload": map[string]interface{}{
+					"content":  "tokyo",
+					"metadata": map[string]interface{}{"content": "city", "country": "japan"},
+				}},
+			}}
+		},
+	}
+	store := newFakeStore(t, fake, qdrant.WithNestedPayload(true))
+
+	_, err := store.AddDocuments(context.Background(), []schema.Document{
+		{PageContent: "tokyo", Metadata: map[string]any{"content": "city", "country": "japan"}},
+	})
+	require.NoError(t, err)
+	docs, err := store.SimilaritySearch(context.Background(), "japan", 1)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "tokyo", docs[0].PageContent)
+	assert.Equal(t, map[string]any{"content": "city", "country": "japan"}, docs[0].Metadata)
+
+	requests := fake.received()
+	require.Len(t, requests, 3)
+	batch, _ := requests[1].Body["batch"].(map[string]interface{})
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"content":  "tokyo",
+		"metadata": map[string]interface{}{"content": "city", "country": "japan"},
+	}}, batch["payloads"])
+}
+
+func TestClose(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{}
+	store := newFakeStore(t, fake)
+	cp := store
+
+	require.NoError(t, store.Close())
+	require.NoError(t, store.Close())
+
+	// The copies of the store are closed too.
+	_, err := cp.AddDocuments(context.Background(), []schema.Document{{PageContent: "tokyo"}})
+	require.ErrorIs(t, err, vectorstores.ErrClosed)
+	_, err = store.SimilaritySearch(context.Background(), "tokyo", 1)
+	require.ErrorIs(t, err, vectorstores.ErrClosed)
+	err = store.DeleteDocuments(context.Background(), []string{"1"})
+	require.ErrorIs(t, err, vectorstores.ErrClosed)
+	_, _, err = store.PayloadSearchPage(context.Background(), 1, "")
+	require.ErrorIs(t, err, vectorstores.ErrClosed)
+	assert.Empty(t, fake.received())
+}
+
+func TestGetDocuments(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{
+		handle: func(fakeRequest) (int, interface{}) {
+			// Qdrant returns the points found in any order, and UUIDs in
+			// their canonical form.
+			return http.StatusOK, map[string]interface{}{"result": []interface{}{
+				map[string]interface{}{
+					"id":      "8d4c5e2a-1b3f-4a6e-9c7d-0e1f2a3b4c5d",
+					"payload": map[string]interface{}{"content": "kyoto", "country": "japan"},
+				},
+				map[string]interface{}{
+					"id":      uint64(18446744073709551615),
+					"payload": map[string]interface{}{"content": "tokyo"},
+				},
+			}}
+		},
+	}
+	store := newFakeStore(t, fake)
+
+	docs, err := store.GetDocuments(context.Background(),
+		[]string{"18446744073709551615", "3", "8D4C5E2A-1B3F-4A6E-9C7D-0E1F2A3B4C5D", "18446744073709551615"})
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.Equal(t, "tokyo", docs[0].PageContent)
+	assert.Equal(t, "kyoto", docs[1].PageContent)
+	assert.Equal(t, map[string]any{"country": "japan"}, docs[1].Metadata)
+
+	requests := fake.received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "/collections/test/points", requests[0].Path)
+	assert.Equal(t, true, requests[0].Body["with_payload"])
+	assert.Len(t, requests[0].Body["ids"], 4)
+
+	docs, err = store.GetDocuments(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, docs)
+	assert.Len(t, fake.received(), 1)
+}
+
+// fakeSparseEmbedder embeds every text as a sparse vector of its length at
+// index 1.
+type fakeSparseEmbedder struct{}
+
+func (e fakeSparseEmbedder) EmbedSparseDocuments(ctx context.Context, texts []string) ([]qdrant.SparseVector, error) {
+	vectors := make([]qdrant.SparseVector, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = e.EmbedSparseQuery(ctx, text)
+	}
+	return vectors, nil
+}
+
+func (fakeSparseEmbedder) EmbedSparseQuery(_ context.Context, text string) (qdrant.SparseVector, error) {
+	return qdrant.SparseVector{Indices: []uint32{1}, Values: []float32{float32(len(text))}}, nil
+}
+
+func TestSparseVectors(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{
+		handle: func(r fakeRequest) (int, interface{}) {
+			if r.Method == http.MethodGet {
+				return http.StatusNotFound, map[string]interface{}{}
+			}
+			return http.StatusOK, map[string]interface{}{"result": []interface{}{}}
+		},
+	}
+	store := newFakeStore(t, fake,
+		qdrant.WithSparseEmbedder("keywords", fakeSparseEmbedder{}),
+		qdrant.WithCreateCollectionIfNotExists(0, qdrant.DistanceCosine),
+	)
+
+	_, err := store.AddDocuments(context.Background(), []schema.Document{{PageContent: "tokyo"}})
+	require.NoError(t, err)
+	_, err = store.SparseSearch(context.Background(), "japan", 1)
+	require.NoError(t, err)
+	_, err = store.SimilaritySearch(context.Background(), "japan", 1)
+	require.NoError(t, err)
+
+	requests := fake.received()
+	require.Len(t, requests, 5)
+	assert.Equal(t, map[string]interface{}{
+		"vectors":        map[string]interface{}{"size": float64(3), "distance": "Cosine"},
+		"sparse_vectors": map[string]interface{}{"keywords": map[string]interface{}{}},
+	}, requests[1].Body)
+	batch, _ := requests[2].Body["batch"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"": []interface{}{[]interface{}{float64(5), float64(5), float64(5)}},
+		"keywords": []interface{}{map[string]interface{}{
+			"indices": []interface{}{float64(1)},
+			"values":  []interface{}{float64(5)},
+		}},
+	}, batch["vectors"])
+	assert.Equal(t, map[string]interface{}{
+		"name": "keywords",
+		"vector": map[string]interface{}{
+			"indices": []interface{}{float64(1)},
+			"values":  []interface{}{float64(5)},
+		},
+	}, requests[3].Body["vector"])
+	assert.Equal(t, []interface{}{float64(5), float64(5), float64(5)}, requests[4].Body["vector"])
+}
+
+func TestSparseSearchMissingSparseEmbedder(t *testing.T) {
+	t.Parallel()
+
+	store := newFakeStore(t, &fakeQdrant{})
+
+	_, err := store.SparseSearch(context.Background(), "japan", 1)
+	require.ErrorIs(t, err, qdrant.ErrMissingSparseEmbedder)
+	_, err = store.SparseHybridSearch(context.Background(), "japan", 1)
+	require.ErrorIs(t, err, qdrant.ErrMissingSparseEmbedder)
+}
+
+func TestSparseHybridSearch(t *testing.T) {
+	t.Parallel()
+
+	point := func(id interface{}, content string, score float64) map[string]interface{} {
+		return map[string]interface{}{"id": id, "score": score, "payload": map[string]interface{}{"content": content}}
+	}
+	fake := &fakeQdrant{
+		handle: func(r fakeRequest) (int, interface{}) {
+			if _, sparse := r.Body["vector"].(map[string]interface{}); sparse {
+				return http.StatusOK, map[string]interface{}{"result": []interface{}{
+					point(2, "osaka", 9), point(3, "kyoto", 8),
+				}}
+			}
+			return http.StatusOK, map[string]interface{}{"result": []interface{}{
+				point(1, "tokyo", 0.9), point(2, "osaka", 0.8), point(3, "kyoto", 0.7),
+			}}
+		},
+	}
+	store := newFakeStore(t, fake, qdrant.WithSparseEmbedder("keywords", fakeSparseEmbedder{}))
+
+	docs, err := store.SparseHybridSearch(context.Background(), "japan", 2)
+	require.NoError(t, err)
+
+	require.Len(t, fake.received(), 2)
+	require.Len(t, docs, 2)
+	// osaka and kyoto are returned by both searches, ahead of tokyo.
+	assert.Equal(t, "osaka", docs[0].PageContent)
+	assert.InDelta(t, 1.0/62+1.0/61, docs[0].Score, 1e-6)
+	assert.Equal(t, "kyoto", docs[1].PageContent)
+	assert.InDelta(t, 1.0/63+1.0/62, docs[1].Score, 1e-6)
+}
+
+func TestPing(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{}
+	store := newFakeStore(t, fake)
+	require.NoError(t, store.Ping(context.Background()))
+
+	requests := fake.received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, http.MethodGet, requests[0].Method)
+	assert.Equal(t, "/collections/test", requests[0].Path)
+
+	missing := newFakeStore(t, &fakeQdrant{
+		handle: func(fakeRequest) (int, interface{}) {
+			return http.StatusNotFound, map[string]interface{}{"status": map[string]interface{}{"error": "not found"}}
+		},
+	})
+	require.ErrorIs(t, missing.Ping(context.Background()), qdrant.ErrCollectionNotFound)
+
+	failing := newFakeStore(t, &fakeQdrant{
+		handle: func(fakeRequest) (int, interface{}) {
+			return http.StatusInternalServerError, map[string]interface{}{"status": map[string]interface{}{"error": "boom"}}
+		},
+	})
+	err := failing.Ping(context.Background())
+	require.Error(t, err)
+	require.NotErrorIs(t, err, qdrant.ErrCollectionNotFound)
+
+	require.NoError(t, store.Close())
+	require.ErrorIs(t, store.Ping(context.Background()), vectorstores.ErrClosed)
+}
+
+func TestNormalizedScores(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{
+		handle: func(r fakeRequest) (int, interface{}) {
+			if r.Method == http.MethodGet {
+				return http.StatusOK, map[string]interface{}{"result": map[string]interface{}{
+					"config": map[string]interface{}{"params": map[string]interface{}{
+						"vectors": map[string]interface{}{"size": 3, "distance": "Euclid"},
+					}},
+				}}
+			}
+			return http.StatusOK, map[string]interface{}{"result": []interface{}{
+				map[string]interface{}{"id": 1, "score": 0, "payload": map[string]interface{}{"content": "tokyo"}},
+				map[string]interface{}{"id": 2, "score": 1, "payload": map[string]interface{}{"content": "kyoto"}},
+				map[string]interface{}{"id": 3, "score": 3, "payload": map[string]interface{}{"content": "osaka"}},
+			}}
+		},
+	}
+	store := newFakeStore(t, fake, qdrant.WithNormalizedScores(), qdrant.WithScoreKey("score"))
+
+	for i := 0; i < 2; i++ {
+		docs, err := store.SimilaritySearch(context.Background(), "japan", 3, vectorstores.WithScoreThreshold(0.4))
+		require.NoError(t, err)
+		require.Len(t, docs, 2)
+		assert.Equal(t, "tokyo", docs[0].PageContent)
+		assert.InDelta(t, 1, docs[0].Score, 1e-6)
+		assert.Equal(t, "kyoto", docs[1].PageContent)
+		assert.InDelta(t, 0.5, docs[1].Score, 1e-6)
+		assert.Equal(t, docs[1].Score, docs[1].Metadata["score"])
+	}
+
+	// The distance of the collection is only fetched once, and the
+	// threshold is applied to the normalized scores.
+	requests := fake.received()
+	require.Len(t, requests, 3)
+	assert.Equal(t, http.MethodGet, requests[0].Method)
+	assert.Equal(t, float64(0), requests[1].Body["score_threshold"])
+}
+
+func TestIDKeyResults(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{
+		handle: func(r fakeRequest) (int, interface{}) {
+			points := []interface{}{
+				map[string]interface{}{
+					"id":      "8d4c5e2a-1b3f-4a6e-9c7d-0e1f2a3b4c5d",
+					"score":   0.9,
+					"payload": map[string]interface{}{"content": "kyoto", "_id": "stale"},
+				},
+				map[string]interface{}{
+					"id":      uint64(18446744073709551615),
+					"score":   0.8,
+					"payload": map[string]interface{}{"content": "tokyo"},
+				},
+			}
+			if r.Path == "/collections/test/points/scroll" {
+				return http.StatusOK, map[string]interface{}{"result": map[string]interface{}{"points": points}}
+			}
+			return http.StatusOK, map[string]interface{}{"result": points}
+		},
+	}
+	store := newFakeStore(t, fake, qdrant.WithIDKey(""))
+
+	docs, err := store.SimilaritySearch(context.Background(), "japan", 2)
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.Equal(t, map[string]any{qdrant.DefaultIDKey: "8d4c5e2a-1b3f-4a6e-9c7d-0e1f2a3b4c5d"}, docs[0].Metadata)
+	assert.Equal(t, map[string]any{"_id": "18446744073709551615"}, docs[1].Metadata)
+
+	docs, err = store.PayloadSearch(context.Background(), 2)
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.Equal(t, "18446744073709551615", docs[1].Metadata["_id"])
+
+	docs, err = newFakeStore(t, fake).SimilaritySearch(context.Background(), "japan", 2)
+	require.NoError(t, err)
+	assert.Equal(t, "stale", docs[0].Metadata["_id"])
+	assert.Nil(t, docs[1].Metadata["_id"])
+}
+
+func TestContentKeyFallback(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeQdrant{
+		handle: func(fakeRequest) (int, interface{}) {
+			return http.StatusOK, map[string]interface{}{"result": []interface{}{
+				map[string]interface{}{
+					"id":      1,
+					"score":   0.9,
+					"payload": map[string]interface{}{"page_content": "kyoto page", "text": "kyoto"},
+				},
+				map[string]interface{}{
+					"id":      2,
+					"score":   0.8,
+					"payload": map[string]interface{}{"text": "tokyo", "country": "japan"},
+				},
+			}}
+		},
+	}
+
+	_, err := newFakeStore(t, fake).SimilaritySearch(context.Background(), "japan", 2)
+	require.ErrorContains(t, err, "payload does not contain content key 'content'")
+
+	docs, err := newFakeStore(t, fake, qdrant.WithContentKeyFallback()).SimilaritySearch(context.Background(), "japan", 2)
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.Equal(t, "kyoto", docs[0].PageContent)
+	assert.Equal(t, map[string]any{"page_content": "kyoto page"}, docs[0].Metadata)
+	assert.Equal(t, "tokyo", docs[1].PageContent)
+	assert.Equal(t, map[string]any{"country": "japan"}, docs[1].Metadata)
+
+	store := newFakeStore(t, fake,
+		qdrant.WithContentKeyFallback("page_content", "text"), qdrant.WithPayloadInclude("country"))
+	docs, err = store.SimilaritySearch(context.Background(), "japan", 2)
+	require.NoError(t, err)
+	assert.Equal(t, "kyoto page", docs[0].PageContent)
+	assert.Equal(t, "tokyo", docs[1].PageContent)
+
+	requests := fake.received()
+	assert.Equal(t, map[string]interface{}{"include": []interface{}{"content", "page_content", "text", "country"}},
+		requests[len(requests)-1].Body["with_payload"])
+}
+
+func TestPayloadSelector(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []qdrant.Option
+		want interface{}
+	}{
+		{"default", nil, true},
+		{
+			"include",
+			[]qdrant.Option{qdrant.WithPayloadInclude("country"), qdrant.WithContentKey("text")},
+			map[string]interface{}{"include": []interface{}{"text", "country"}},
+		},
+		{
+			"exclude",
+			[]qdrant.Option{qdrant.WithPayloadExclude("content", "embedding_source")},
+			map[string]interface{}{"exclude": []interface{}{"embedding_source"}},
+		},
+		{"exclude content only", []qdrant.Option{qdrant.WithPayloadExclude("content")}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fake := &fakeQdrant{}
+			store := newFakeStore(t, fake, tt.opts...)
+			_, err := store.SimilaritySearch(context.Background(), "japan", 1)
+			require.NoError(t, err)
+
+			requests := fake.received()
+			require.Len(t, requests, 1)
+			assert.Equal(t, tt.want, requests[0].Body["with_payload"])
+		})
+	}
+
+	_, err := qdrant.New(
+		qdrant.WithURL(url.URL{Scheme: "http", Host: "localhost:6333"}),
+		qdrant.WithCollectionName("test"),
+		qdrant.WithEmbedder(fakeEmbedder{dimension: 3}),
+		qdrant.WithPayloadInclude("country"),
+		qdrant.WithPayloadExclude("city"),
+	)
+	require.ErrorIs(t, err, qdrant.ErrInvalidOptions)
+}
@@ -5,19 +5,38 @@
 
 package qdrant
 
+import "encoding/json"
+
 type upsertBatch struct {
-	IDs      []string                 `json:"ids"`
+	IDs      []any                    `json:"ids"`
 	Payloads []map[string]interface{} `json:"payloads"`
-	Vectors  [][]float32              `json:"vectors"`
+	// Vectors holds either the [][]float32 of the default vector or, for a
+	// named vector or with a sparse vector, a map from their names to them.
+	Vectors any `json:"vectors"`
 }
 
 type upsertBody struct {
 	Batch upsertBatch `json:"batch"`
 }
 
+type createFieldIndexBody struct {
+	FieldName   string `json:"field_name"`
+	FieldSchema string `json:"field_schema"`
+}
+
+type deleteBody struct {
+	Points []any `json:"points,omitempty"`
+	Filter any   `json:"filter,omitempty"`
+}
+
 type result struct {
+	// ID is kept raw, so integer IDs don't lose precision as float64.
+	ID      json.RawMessage        `json:"id"`
 	Score   float32                `json:"score"`
 	Payload map[string]interface{} `json:"payload"`
+	// Vector holds the vector of the point, if requested: either a list of
+	// floats or, for named vectors, a map from their names to them.
+	Vector json.RawMessage `json:"vector"`
 }
 
 type searchResponse struct {
@@ -25,30 +44,146 @@ type searchResponse struct {
 }
 
 type scrollPoint struct {
-	ID      string                 `json:"id"`
+	ID      json.RawMessage        `json:"id"`
 	Payload map[string]interface{} `json:"payload"`
 }
 
 type scrollResult struct {
-	Points []scrollPoint `json:"points"`
+	Points         []scrollPoint   `json:"points"`
+	NextPageOffset json.RawMessage `json:"next_page_offset"`
 }
 
 type scrollResponse struct {
 	Result scrollResult `json:"result"`
 }
 
+// namedVector is the vector of a search request against a named vector.
+type namedVector struct {
+	Name   string    `json:"name"`
+	Vector []float32 `json:"vector"`
+}
+
+// namedSparseVector is the vector of a search request against a named sparse
+// vector.
+type namedSparseVector struct {
+	Name   string       `json:"name"`
+	Vector SparseVector `json:"vector"`
+}
+
 type searchBody struct {
-	Vector         []float32 `json:"vector"`
-	Filter         any       `json:"filter"`
-	Limit          int       `json:"limit"`
-	ScoreThreshold float32   `json:"score_threshold"`
-	WithVector     bool      `json:"with_vector"`
-	WithPayload    bool      `json:"with_payload"`
+	// Vector holds either the []float32 of the default vector, a
+	// namedVector or a namedSparseVector.
+	Vector         any     `json:"vector"`
+	Filter         any     `json:"filter"`
+	Limit          int     `json:"limit"`
+	ScoreThreshold float32 `json:"score_threshold"`
+	WithVector     bool    `json:"with_vector"`
+	// WithPayload holds either true or a payloadSelector.
+	WithPayload any           `json:"with_payload"`
+	Params      *searchParams `json:"params,omitempty"`
+}
+
+// searchParams are the parameters of a search.
+type searchParams struct {
+	Quantization *quantizationParams `json:"quantization"`
+}
+
+// quantizationParams are the parameters of a search using quantized vectors.
+type quantizationParams struct {
+	Rescore      bool    `json:"rescore"`
+	Oversampling float64 `json:"oversampling,omitempty"`
+}
+
+// searchGroupsBody is the body of a search request grouping the points by a
+// payload field.
+type searchGroupsBody struct {
+	// Vector holds either the []float32 of the default vector or a
+	// namedVector.
+	Vector         any     `json:"vector"`
+	Filter         any     `json:"filter"`
+	Limit          int     `json:"limit"`
+	GroupBy        string  `json:"group_by"`
+	GroupSize      int     `json:"group_size"`
+	ScoreThreshold float32 `json:"score_threshold,omitempty"`
+	// WithPayload holds either true or a payloadSelector.
+	WithPayload any           `json:"with_payload"`
+	Params      *searchParams `json:"params,omitempty"`
+}
+
+type pointGroup struct {
+	ID   json.RawMessage `json:"id"`
+	Hits []result        `json:"hits"`
+}
+
+type searchGroupsResponse struct {
+	Result struct {
+		Groups []pointGroup `json:"groups"`
+	} `json:"result"`
+}
+
+// payloadSelector selects the payload fields of the points returned by a
+// search.
+type payloadSelector struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// retrieveBody is the body of a request retrieving points by ID.
+type retrieveBody struct {
+	IDs         []any `json:"ids"`
+	WithVector  bool  `json:"with_vector"`
+	WithPayload bool  `json:"with_payload"`
+}
+
+// retrievedPoint is a point retrieved by ID. Its ID is kept raw, so integer
+// IDs don't lose precision as float64.
+type retrievedPoint struct {
+	ID      json.RawMessage        `json:"id"`
+	Payload map[string]interface{} `json:"payload"`
+}
+
+type retrieveResponse struct {
+	Result []retrievedPoint `json:"result"`
 }
 
 type scrollBody struct {
 	Filter      any  `json:"filter"`
+	Offset      any  `json:"offset,omitempty"`
 	Limit       int  `json:"limit"`
 	WithVector  bool `json:"with_vector"`
 	WithPayload bool `json:"with_payload"`
 }
+
+type vectorParams struct {
+	Size     uint64 `json:"size"`
+	Distance string `json:"distance"`
+}
+
+type createCollectionBody struct {
+	// Vectors holds either the vectorParams of the default vector or, for a
+	// named vector, a map from its name to them.
+	Vectors any `json:"vectors"`
+	// SparseVectors maps the names of the sparse vectors to their (default)
+	// parameters.
+	SparseVectors map[string]struct{} `json:"sparse_vectors,omitempty"`
+}
+
+// collectionInfo is what the Store uses of the information of a collection.
+type collectionInfo struct {
+	// vectorSize is the size of the vectors used by the Store, 0 if unknown.
+	vectorSize uint64
+	// distance is the distance metric of the vectors used by the Store.
+	distance string
+}
+
+type collectionResponse struct {
+	Result struct {
+		Config struct {
+			Params struct {
+				// Vectors holds either the vectorParams of the default vector
+				// or a map from the names of the named vectors to them.
+				Vectors json.RawMessage `json:"vectors"`
+			} `json:"params"`
+		} `json:"config"`
+	} `json:"result"`
+}
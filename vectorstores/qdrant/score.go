@@ -0,0 +1,114 @@
+package qdrant
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tmc/langchaingo/schema"
+)
+
+// DistanceManhattan is the Manhattan distance metric, which collections
+// created outside of the Store may use.
+const DistanceManhattan = "Manhattan"
+
+// NormalizeScore converts a Qdrant score of the given distance metric into a
+// similarity between 0 and 1, higher meaning more similar:
+//
+//   - DistanceCosine: (1 + score) / 2, the cosine being between -1 and 1.
+//   - DistanceDot: (1 + score) / 2, clamped to [0, 1]. It is exact for unit
+//     vectors, whose dot product is their cosine, as produced by most
+//     embedders.
+//   - DistanceEuclid and DistanceManhattan: 1 / (1 + score), the score being
+//     a distance, lower meaning more similar.
+func NormalizeScore(distance string, score float32) (float32, error) {
+	switch distance {
+	case DistanceCosine, DistanceDot:
+		similarity := (1 + score) / 2 //nolint:gomnd
+		if similarity < 0 {
+			return 0, nil
+		}
+		if similarity > 1 {
+			return 1, nil
+		}
+		return similarity, nil
+	case DistanceEuclid, DistanceManhattan:
+		return 1 / (1 + score), nil
+	default:
+		return 0, fmt.Errorf("unsupported distance metric %q", distance)
+	}
+}
+
+// searchDense is searchPoints with a dense vector, normalizing the scores with
+// NormalizeScore if WithNormalizedScores was given. The score threshold then
+// applies to the normalized scores, which are in the same order as the raw
+// ones: filtering the results on it returns the same points as Qdrant would
+// with the matching raw threshold.
+func (s Store) searchDense(
+	ctx context.Context,
+	vector []float32,
+	numVectors int,
+	scoreThreshold float32,
+	filter any,
+	withVector bool,
+) ([]string, []schema.Document, [][]float32, error) {
+	if !s.normalizeScores {
+		return s.searchPoints(ctx, &s.qdrantURL, vector, numVectors, scoreThreshold, filter, withVector)
+	}
+
+	distance, err := s.collectionDistance(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ids, docs, vectors, err := s.searchPoints(ctx, &s.qdrantURL, vector, numVectors, 0, filter, withVector)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	n := 0
+	for i, doc := range docs {
+		score, err := NormalizeScore(distance, doc.Score)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if score < scoreThreshold {
+			continue
+		}
+		doc.Score = score
+		if s.scoreKey != "" {
+			// The metadata was set by scoredDocument.
+			doc.Metadata[s.scoreKey] = score
+		}
+		ids[n], docs[n] = ids[i], doc
+		if withVector {
+			vectors[n] = vectors[i]
+		}
+		n++
+	}
+	if withVector {
+		vectors = vectors[:n]
+	}
+	return ids[:n], docs[:n], vectors, nil
+}
+
+// collectionDistance returns the distance metric of the vectors of the
+// collection, fetched once and shared by the copies of the Store.
+func (s Store) collectionDistance(ctx context.Context) (string, error) {
+	c := s.collection
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.distance != "" {
+		return c.distance, nil
+	}
+	info, err := s.getCollection(ctx, &s.qdrantURL)
+	if err != nil {
+		return "", err
+	}
+	if info == nil {
+		return "", fmt.Errorf("%w: %s", ErrCollectionNotFound, s.collectionName)
+	}
+	c.vectorSize = info.vectorSize
+	c.distance = info.distance
+	c.checked = true
+	return c.distance, nil
+}
@@ -0,0 +1,36 @@
+package qdrant
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeScore(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		distance string
+		score    float32
+		want     float32
+	}{
+		{DistanceCosine, 1, 1},
+		{DistanceCosine, 0, 0.5},
+		{DistanceCosine, -1, 0},
+		{DistanceDot, 0.6, 0.8},
+		{DistanceDot, 3, 1},
+		{DistanceDot, -3, 0},
+		{DistanceEuclid, 0, 1},
+		{DistanceEuclid, 1, 0.5},
+		{DistanceManhattan, 3, 0.25},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeScore(tt.distance, tt.score)
+		require.NoError(t, err)
+		assert.InDelta(t, tt.want, got, 1e-6, "%s %v", tt.distance, tt.score)
+	}
+
+	_, err := NormalizeScore("Hamming", 1)
+	require.Error(t, err)
+}
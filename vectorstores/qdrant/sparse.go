@@ -0,0 +1,155 @@
+package qdrant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// SparseVector is a sparse vector, e.g. of SPLADE or BM25 term weights: the
+// values of its non-zero dimensions, at the given indices.
+type SparseVector struct {
+	Indices []uint32  `json:"indices"`
+	Values  []float32 `json:"values"`
+}
+
+// SparseEmbedder is the interface for creating sparse vector embeddings from
+// texts, stored next to the dense ones with WithSparseEmbedder.
+type SparseEmbedder interface {
+	// EmbedSparseDocuments returns a sparse vector for each text.
+	EmbedSparseDocuments(ctx context.Context, texts []string) ([]SparseVector, error)
+	// EmbedSparseQuery embeds a single text.
+	EmbedSparseQuery(ctx context.Context, text string) (SparseVector, error)
+}
+
+// ErrMissingSparseEmbedder is returned by the sparse searches of a Store
+// created without WithSparseEmbedder.
+var ErrMissingSparseEmbedder = errors.New("missing sparse embedder")
+
+// rrfK is the constant of reciprocal rank fusion, damping the weight of the
+// top ranks.
+const rrfK = 60
+
+// SparseSearch returns the numDocuments documents most similar to the query
+// by their sparse vectors.
+func (s Store) SparseSearch(ctx context.Context,
+	query string, numDocuments int,
+	options ...vectorstores.Option,
+) ([]schema.Document, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+	if s.sparseEmbedder == nil {
+		return nil, ErrMissingSparseEmbedder
+	}
+	opts := s.getOptions(options...)
+	ctx, cancel := vectorstores.TimeoutContext(ctx, opts)
+	defer cancel()
+
+	filters, err := s.getFilters(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	scoreThreshold, err := s.getScoreThreshold(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	vector, err := s.sparseEmbedder.EmbedSparseQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	_, docs, _, err := s.searchPoints(ctx, &s.qdrantURL, vector, numDocuments, scoreThreshold, filters, false)
+	return docs, err
+}
+
+// SparseHybridSearch returns the numDocuments documents most similar to the
+// query by both their dense and sparse vectors. Each search fetches
+// numDocuments documents, fused by reciprocal rank fusion: the score of a
+// document is the sum of 1/(60+rank) over the searches returning it. The
+// score threshold of vectorstores.WithScoreThreshold only applies to the
+// dense search, and WithNormalizedScores only to its scores.
+func (s Store) SparseHybridSearch(ctx context.Context,
+	query string, numDocuments int,
+	options ...vectorstores.Option,
+) ([]schema.Document, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+	if s.sparseEmbedder == nil {
+		return nil, ErrMissingSparseEmbedder
+	}
+	opts := s.getOptions(options...)
+	ctx, cancel := vectorstores.TimeoutContext(ctx, opts)
+	defer cancel()
+
+	filters, err := s.getFilters(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	scoreThreshold, err := s.getScoreThreshold(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	dense, err := s.embedQuery(ctx, opts, query)
+	if err != nil {
+		return nil, err
+	}
+	sparse, err := s.sparseEmbedder.EmbedSparseQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	denseIDs, denseDocs, _, err := s.searchDense(ctx, dense, numDocuments, scoreThreshold, filters, false)
+	if err != nil {
+		return nil, fmt.Errorf("dense search: %w", err)
+	}
+	sparseIDs, sparseDocs, _, err := s.searchPoints(ctx, &s.qdrantURL, sparse, numDocuments, 0, filters, false)
+	if err != nil {
+		return nil, fmt.Errorf("sparse search: %w", err)
+	}
+
+	return s.fuseRanks(numDocuments, [][]string{denseIDs, sparseIDs}, [][]schema.Document{denseDocs, sparseDocs}), nil
+}
+
+// fuseRanks merges the ranked results of several searches by reciprocal rank
+// fusion, returning the numDocuments best documents with their fused score.
+func (s Store) fuseRanks(numDocuments int, ids [][]string, docs [][]schema.Document) []schema.Document {
+	scores := map[string]float32{}
+	fused := map[string]schema.Document{}
+	var order []string
+	for i := range ids {
+		for rank, id := range ids[i] {
+			if _, ok := fused[id]; !ok {
+				fused[id] = docs[i][rank]
+				order = append(order, id)
+			}
+			scores[id] += 1 / float32(rrfK+rank+1)
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+	if len(order) > numDocuments {
+		order = order[:numDocuments]
+	}
+
+	result := make([]schema.Document, len(order))
+	for i, id := range order {
+		doc := fused[id]
+		doc.Score = scores[id]
+		if s.scoreKey != "" {
+			// The metadata was set by scoredDocument.
+			doc.Metadata[s.scoreKey] = doc.Score
+		}
+		result[i] = doc
+	}
+	return result
+}
@@ -0,0 +1,186 @@
+package redisvector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// fieldType is the type of an indexed metadata field, deciding the RediSearch
+// syntax of the filters on it.
+type fieldType int
+
+const (
+	textFieldType fieldType = iota
+	tagFieldType
+	numericFieldType
+)
+
+// tagSpecialChars are the characters escaped in tag values.
+const tagSpecialChars = ",.<>{}[]\"':;!@#$%^&*()-+=~|/\\ "
+
+// filterQuery translates a backend-agnostic filter into a RediSearch query.
+// The type of the fields is looked up in the index schema if any, and else
+// inferred from the values: numbers are numeric fields, others text fields.
+func (s Store) filterQuery(f vectorstores.Filter) (string, error) {
+	if f.Combinator != "" {
+		queries := make([]string, len(f.Filters))
+		for i, child := range f.Filters {
+			query, err := s.filterQuery(child)
+			if err != nil {
+				return "", err
+			}
+			queries[i] = query
+		}
+		switch f.Combinator {
+		case vectorstores.CombinatorAnd:
+			return "(" + strings.Join(queries, " ") + ")", nil
+		case vectorstores.CombinatorOr:
+			return "(" + strings.Join(queries, " | ") + ")", nil
+		case vectorstores.CombinatorNot:
+			return "-(" + strings.Join(queries, " | ") + ")", nil
+		default:
+			return "", fmt.Errorf("%w: combinator %q", vectorstores.ErrUnsupportedFilter, f.Combinator)
+		}
+	}
+
+	typ := s.fieldType(f)
+	switch f.Operator {
+	case vectorstores.OperatorEq:
+		return eqQuery(f.Field, typ, f.Value)
+	case vectorstores.OperatorNe:
+		query, err := eqQuery(f.Field, typ, f.Value)
+		if err != nil {
+			return "", err
+		}
+		return "-" + query, nil
+	case vectorstores.OperatorGt, vectorstores.OperatorGte, vectorstores.OperatorLt, vectorstores.OperatorLte:
+		n, ok := f.Number()
+		if !ok || typ != numericFieldType {
+			return "", fmt.Errorf("%w: %s of %q with non-numeric value %v",
+				vectorstores.ErrUnsupportedFilter, f.Operator, f.Field, f.Value)
+		}
+		return rangeQuery(f.Field, f.Operator, n), nil
+	case vectorstores.OperatorIn:
+		return inQuery(f.Field, typ, f.Values())
+	default:
+		return "", fmt.Errorf("%w: operator %q", vectorstores.ErrUnsupportedFilter, f.Operator)
+	}
+}
+
+// fieldType returns the type of the field of a leaf filter.
+func (s Store) fieldType(f vectorstores.Filter) fieldType {
+	if s.indexSchema != nil {
+		for _, field := range s.indexSchema.Tag {
+			if field.Name == f.Field {
+				return tagFieldType
+			}
+		}
+		for _, field := range s.indexSchema.Numeric {
+			if field.Name == f.Field {
+				return numericFieldType
+			}
+		}
+		for _, field := range s.indexSchema.Text {
+			if field.Name == f.Field {
+				return textFieldType
+			}
+		}
+	}
+	value := f
+	if values := f.Values(); len(values) > 0 {
+		value.Value = values[0]
+	}
+	if _, ok := value.Number(); ok {
+		return numericFieldType
+	}
+	return textFieldType
+}
+
+// eqQuery returns the query matching the documents whose field equals value.
+func eqQuery(field string, typ fieldType, value any) (string, error) {
+	switch typ {
+	case tagFieldType:
+		return fmt.Sprintf("@%s:{%s}", field, escapeTag(fmt.Sprint(value))), nil
+	case numericFieldType:
+		n, ok := vectorstores.Filter{Value: value}.Number()
+		if !ok {
+			return "", fmt.Errorf("%w: numeric field %q with non-numeric value %v",
+				vectorstores.ErrUnsupportedFilter, field, value)
+		}
+		v := formatNumber(n)
+		return fmt.Sprintf("@%s:[%s %s]", field, v, v), nil
+	default:
+		return fmt.Sprintf("@%s:%s", field, quoteText(fmt.Sprint(value))), nil
+	}
+}
+
+// inQuery returns the query matching the documents whose field equals one of
+// the values.
+func inQuery(field string, typ fieldType, values []any) (string, error) {
+	if len(values) == 0 {
+		return "", fmt.Errorf("%w: %s of %q without values", vectorstores.ErrUnsupportedFilter,
+			vectorstores.OperatorIn, field)
+	}
+	terms := make([]string, len(values))
+	for i, value := range values {
+		switch typ {
+		case tagFieldType:
+			terms[i] = escapeTag(fmt.Sprint(value))
+		case textFieldType:
+			terms[i] = quoteText(fmt.Sprint(value))
+		case numericFieldType:
+			query, err := eqQuery(field, typ, value)
+			if err != nil {
+				return "", err
+			}
+			terms[i] = query
+		}
+	}
+	switch typ {
+	case tagFieldType:
+		return fmt.Sprintf("@%s:{%s}", field, strings.Join(terms, " | ")), nil
+	case textFieldType:
+		return fmt.Sprintf("@%s:(%s)", field, strings.Join(terms, " | ")), nil
+	default:
+		return "(" + strings.Join(terms, " | ") + ")", nil
+	}
+}
+
+// rangeQuery returns the query comparing a numeric field with n.
+func rangeQuery(field string, op vectorstores.Operator, n float64) string {
+	v := formatNumber(n)
+	switch op { //nolint:exhaustive
+	case vectorstores.OperatorGt:
+		return fmt.Sprintf("@%s:[(%s +inf]", field, v)
+	case vectorstores.OperatorGte:
+		return fmt.Sprintf("@%s:[%s +inf]", field, v)
+	case vectorstores.OperatorLt:
+		return fmt.Sprintf("@%s:[-inf (%s]", field, v)
+	default:
+		return fmt.Sprintf("@%s:[-inf %s]", field, v)
+	}
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// escapeTag escapes the special characters of a tag value.
+func escapeTag(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if strings.ContainsRune(tagSpecialChars, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// quoteText returns value as an exact phrase of a text field.
+func quoteText(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
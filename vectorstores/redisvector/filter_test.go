@@ -0,0 +1,78 @@
+package redisvector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+func TestFilterQuery(t *testing.T) {
+	t.Parallel()
+
+	withSchema := Store{indexSchema: &IndexSchema{
+		Tag:     []TagField{{Name: "tags"}},
+		Text:    []TextField{{Name: "title"}},
+		Numeric: []NumericField{{Name: "year"}},
+	}}
+
+	tests := []struct {
+		name   string
+		store  Store
+		filter vectorstores.Filter
+		want   string
+	}{
+		{"tag eq", withSchema, vectorstores.Eq("tags", "sci-fi"), `@tags:{sci\-fi}`},
+		{"text eq", withSchema, vectorstores.Eq("title", `a "b"`), `@title:"a \"b\""`},
+		{"numeric eq", withSchema, vectorstores.Eq("year", 1999), "@year:[1999 1999]"},
+		{"ne", withSchema, vectorstores.Ne("tags", "drama"), "-@tags:{drama}"},
+		{"gt", withSchema, vectorstores.Gt("year", 2000), "@year:[(2000 +inf]"},
+		{"gte", withSchema, vectorstores.Gte("year", 2000.5), "@year:[2000.5 +inf]"},
+		{"lt", withSchema, vectorstores.Lt("year", 2000), "@year:[-inf (2000]"},
+		{"lte", withSchema, vectorstores.Lte("year", 2000), "@year:[-inf 2000]"},
+		{"tag in", withSchema, vectorstores.In("tags", "a b", "c"), `@tags:{a\ b | c}`},
+		{"text in", withSchema, vectorstores.In("title", "a", "b"), `@title:("a" | "b")`},
+		{"numeric in", withSchema, vectorstores.In("year", 1, 2), "(@year:[1 1] | @year:[2 2])"},
+		{
+			"combinators",
+			withSchema,
+			vectorstores.And(
+				vectorstores.Eq("tags", "drama"),
+				vectorstores.Or(vectorstores.Lt("year", 1990), vectorstores.Not(vectorstores.Eq("title", "x"))),
+			),
+			`(@tags:{drama} (@year:[-inf (1990] | -(@title:"x")))`,
+		},
+		{"inferred numeric", Store{}, vectorstores.Eq("year", 1999), "@year:[1999 1999]"},
+		{"inferred text", Store{}, vectorstores.In("title", "a"), `@title:("a")`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := tt.store.getFilters(vectorstores.Options{Filters: tt.filter})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFilterQueryUnsupported(t *testing.T) {
+	t.Parallel()
+
+	for _, filter := range []vectorstores.Filter{
+		vectorstores.Gt("title", "a"),
+		vectorstores.Eq("year", "a"),
+		vectorstores.In("tags"),
+		{Field: "title", Operator: "like", Value: "a%"},
+	} {
+		_, err := Store{indexSchema: &IndexSchema{
+			Tag:     []TagField{{Name: "tags"}},
+			Numeric: []NumericField{{Name: "year"}},
+		}}.filterQuery(filter)
+		require.ErrorIs(t, err, vectorstores.ErrUnsupportedFilter, filter)
+	}
+
+	_, err := Store{}.getFilters(vectorstores.Options{Filters: 1})
+	require.ErrorIs(t, err, ErrInvalidFilters)
+}
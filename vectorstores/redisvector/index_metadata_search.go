@@ -1,9 +1,6 @@
 package redisvector
 
-import (
-	"errors"
-	"strconv"
-)
+import "errors"
 
 func NewIndexMetadataSearch(index string, opts ...SearchOption) (*IndexVectorSearch, error) {
 	if index == "" {
@@ -12,40 +9,55 @@ func NewIndexMetadataSearch(index string, opts ...SearchOption) (*IndexVectorSea
 	s := &IndexVectorSearch{
 		index:   index,
 		returns: []string{},
+		dialect: defaultDialect,
 	}
 	for _, opt := range opts {
 		opt(s)
 	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
 	return s, nil
 }
 
+// AsMetadataSearchCommand returns the FT.SEARCH command of a metadata search,
+// querying the pre-filters. If a query vector is set, the pre-filters are
+// combined with a KNN clause as in AsCommand.
 func (s IndexVectorSearch) AsMetadataSearchCommand() []string {
+	if len(s.vector) > 0 {
+		return s.AsCommand()
+	}
 
 	// "FT.SEARCH" "users"
 	// "@job:("engineer")"
 	// "RETURN" "4" "content" "user" "age"
+	// "SUMMARIZE" "FIELDS" "1" "content" "FRAGS" "2" "LEN" "10"
+	// "HIGHLIGHT" "FIELDS" "1" "content"
 	// "SORTBY" "distance" "ASC"
 	// "DIALECT" "2"
 	// "LIMIT" "0" "3"
 	cmd := []string{"FT.SEARCH", s.index}
 
-	if s.limit == 0 {
-		s.limit = 1
-	}
-
 	filter := "*"
 	if len(s.preFilters) > 0 {
 		filter = s.preFilters
 	}
 	cmd = append(cmd, filter)
 
-	if l := len(s.returns); l > 0 {
-		cmd = append(cmd, "RETURN", strconv.Itoa(len(s.returns)))
-		cmd = append(cmd, s.returns...)
+	if !s.countOnly && (len(s.returns) > 0 || len(s.returnAliases) > 0) {
+		cmd = append(cmd, s.returnArgs()...)
+	}
+	if !s.countOnly {
+		cmd = append(cmd, s.snippetArgs()...)
+	}
+
+	if len(s.sortBy) > 0 {
+		cmd = append(cmd, "SORTBY")
+		cmd = append(cmd, s.sortBy...)
 	}
 
-	cmd = append(cmd, "DIALECT", "2")
-	cmd = append(cmd, "LIMIT", strconv.Itoa(s.offset), strconv.Itoa(s.limit))
+	cmd = append(cmd, "DIALECT", s.dialectString())
+	cmd = append(cmd, s.limitArgs()...)
 
 	return cmd
 }
@@ -9,6 +9,7 @@ import (
 	"os"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -262,6 +263,30 @@ func (s *IndexSchema) MetadataKeys() map[string]any {
 	return keys
 }
 
+// contentVectorDistanceMetric returns the distance metric of the content
+// vector field, or "" if the schema has none.
+func (s *IndexSchema) contentVectorDistanceMetric() DistanceMetric {
+	for _, field := range s.Vector {
+		if field.Name == defaultContentVectorFieldKey {
+			return field.DistanceMetric
+		}
+	}
+	return ""
+}
+
+// setContentVectorDistanceMetric sets the distance metric of the content
+// vector field, if the metric isn't empty.
+func (s *IndexSchema) setContentVectorDistanceMetric(metric DistanceMetric) {
+	if metric == "" {
+		return
+	}
+	for i := range s.Vector {
+		if s.Vector[i].Name == defaultContentVectorFieldKey {
+			s.Vector[i].DistanceMetric = metric
+		}
+	}
+}
+
 func (s *IndexSchema) AsCommand() []string {
 	argsOut := []string{}
 	for _, tag := range s.Tag {
@@ -406,6 +431,58 @@ func (i *RedisIndex) AsCommand() ([]string, error) {
 		cmd = append(cmd, i.prefix...)
 	}
 	cmd = append(cmd, "SCORE", "1.0", "SCHEMA")
-	cmd = append(cmd, i.schema.AsCommand()...)
+	schema := i.schema
+	if i.indexType == JSONIndexType {
+		schema = schema.jsonSchema()
+	}
+	cmd = append(cmd, schema.AsCommand()...)
 	return cmd, nil
 }
+
+// jsonSchema returns the schema of an index of JSON documents: the fields are
+// indexed from the top-level JSON paths of their names, aliased to their names
+// unless they already have an alias. Tag fields index arrays of strings.
+func (s IndexSchema) jsonSchema() IndexSchema {
+	out := IndexSchema{
+		Tag:     make([]TagField, 0, len(s.Tag)),
+		Text:    make([]TextField, 0, len(s.Text)),
+		Numeric: make([]NumericField, 0, len(s.Numeric)),
+		Vector:  make([]VectorField, 0, len(s.Vector)),
+	}
+	for _, f := range s.Tag {
+		if !strings.HasPrefix(f.Name, "$") {
+			f.Name, f.As = jsonPath(f.Name)+"[*]", jsonAlias(f.Name, f.As)
+		}
+		out.Tag = append(out.Tag, f)
+	}
+	for _, f := range s.Text {
+		f.Name, f.As = jsonField(f.Name, f.As)
+		out.Text = append(out.Text, f)
+	}
+	for _, f := range s.Numeric {
+		f.Name, f.As = jsonField(f.Name, f.As)
+		out.Numeric = append(out.Numeric, f)
+	}
+	for _, f := range s.Vector {
+		f.Name, f.As = jsonField(f.Name, f.As)
+		out.Vector = append(out.Vector, f)
+	}
+	return out
+}
+
+// jsonField returns the JSON path and alias of a field of a JSON index.
+func jsonField(name, as string) (string, string) {
+	if strings.HasPrefix(name, "$") {
+		return name, as
+	}
+	return jsonPath(name), jsonAlias(name, as)
+}
+
+// jsonAlias returns the alias of a field of a JSON index, defaulting to its
+// name.
+func jsonAlias(name, as string) string {
+	if as != "" {
+		return as
+	}
+	return name
+}
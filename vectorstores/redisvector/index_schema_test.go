@@ -1,12 +1,16 @@
 package redisvector
 
 import (
+	"context"
 	"strings"
 	"testing"
 
+	"github.com/redis/rueidis"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/embeddings"
 	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
 )
 
 func TestGenerateSchema(t *testing.T) {
@@ -67,6 +71,36 @@ func TestGenerateSchema(t *testing.T) {
 	assert.Len(t, schema.Numeric, 2)
 }
 
+func TestIndexDistanceMetric(t *testing.T) {
+	t.Parallel()
+
+	indexSchema, err := generateSchemaWithMetadata(map[string]any{defaultContentVectorFieldKey: []float32{1, 0}})
+	require.NoError(t, err)
+	indexSchema.setContentVectorDistanceMetric(IPDistanceMetric)
+	assert.Contains(t, strings.Join(indexSchema.AsCommand(), " "), "DISTANCE_METRIC IP")
+
+	assert.Equal(t, IPDistanceMetric, Store{distanceMetric: IPDistanceMetric}.contentDistanceMetric())
+	assert.Equal(t, CosineDistanceMetric, Store{
+		distanceMetric: IPDistanceMetric,
+		indexSchema:    &IndexSchema{Vector: []VectorField{{Name: defaultContentVectorFieldKey, DistanceMetric: CosineDistanceMetric}}},
+	}.contentDistanceMetric())
+
+	embedder, err := embeddings.NewEmbedder(embeddings.EmbedderClientFunc(
+		func(context.Context, []string) ([][]float32, error) { return nil, nil }))
+	require.NoError(t, err)
+	_, err = applyClientOptions(WithEmbedder(embedder), WithIndexName("test", true),
+		WithIndexDistanceMetric("HAMMING"))
+	require.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = applyClientOptions(WithEmbedder(embedder), WithIndexName("test", true),
+		WithIndexDistanceMetric(L2DistanceMetric),
+		WithIndexSchema(YAMLSchemaFormat, "./testdata/schema.yml", nil))
+	require.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = applyClientOptions(WithEmbedder(embedder), WithIndexName("test", true),
+		WithIndexDistanceMetric(CosineDistanceMetric),
+		WithIndexSchema(YAMLSchemaFormat, "./testdata/schema.yml", nil))
+	require.NoError(t, err)
+}
+
 func TestSchemaAsCommand(t *testing.T) {
 	t.Parallel()
 
@@ -169,6 +203,11 @@ func TestIndexSearchAsCommand(t *testing.T) {
 			Args{"demo", []float32{0.111}, []SearchOption{WithScoreThreshold(0.5)}},
 			"FT.SEARCH demo @content_vector:[VECTOR_RANGE $distance_threshold $vector]=>{$yield_distance_as: distance} SORTBY distance ASC DIALECT 2 LIMIT 0 1 PARAMS 4 vector \xf8S\xe3= distance_threshold 0.5",
 		},
+		{
+			"search with cosine score threshold above 1",
+			Args{"demo", []float32{0.111}, []SearchOption{WithScoreThreshold(1.5)}},
+			"FT.SEARCH demo @content_vector:[VECTOR_RANGE $distance_threshold $vector]=>{$yield_distance_as: distance} SORTBY distance ASC DIALECT 2 LIMIT 0 1 PARAMS 4 vector \xf8S\xe3= distance_threshold 1.5",
+		},
 		{
 			"search with filter",
 			Args{"demo", []float32{0.111}, []SearchOption{WithPreFilters("@job{engineer}")}},
@@ -179,6 +218,36 @@ func TestIndexSearchAsCommand(t *testing.T) {
 			Args{"demo", []float32{0.111}, []SearchOption{WithScoreThreshold(0.5), WithPreFilters("@job{engineer}")}},
 			"FT.SEARCH demo (@job{engineer}) @content_vector:[VECTOR_RANGE $distance_threshold $vector]=>{$yield_distance_as: distance} SORTBY distance ASC DIALECT 2 LIMIT 0 1 PARAMS 4 vector \xf8S\xe3= distance_threshold 0.5",
 		},
+		{
+			"JSON search with returns",
+			Args{"demo", []float32{0.111}, []SearchOption{WithReturns([]string{"content"}), WithStorageType(JSONIndexType)}},
+			"FT.SEARCH demo (*)=>[KNN 1 @content_vector $vector AS distance] RETURN 4 $.content AS content distance SORTBY distance ASC DIALECT 2 LIMIT 0 1 PARAMS 2 vector \xf8S\xe3=",
+		},
+		{
+			"search with k and vector field",
+			Args{"demo", []float32{0.111}, []SearchOption{WithKNN(3), WithVectorField("embedding"), WithOffsetLimit(0, 10)}},
+			"FT.SEARCH demo (*)=>[KNN 3 @embedding $vector AS distance] SORTBY distance ASC DIALECT 2 LIMIT 0 10 PARAMS 2 vector \xf8S\xe3=",
+		},
+		{
+			"search with sort by",
+			Args{"demo", []float32{0.111}, []SearchOption{WithSortBy("distance", false)}},
+			"FT.SEARCH demo (*)=>[KNN 1 @content_vector $vector AS distance] SORTBY distance DESC DIALECT 2 LIMIT 0 1 PARAMS 2 vector \xf8S\xe3=",
+		},
+		{
+			"search with returns and alias",
+			Args{"demo", []float32{0.111}, []SearchOption{WithReturns([]string{"content"}), WithReturnAlias("$.metadata.author", "author")}},
+			"FT.SEARCH demo (*)=>[KNN 1 @content_vector $vector AS distance] RETURN 5 content distance $.metadata.author AS author SORTBY distance ASC DIALECT 2 LIMIT 0 1 PARAMS 2 vector \xf8S\xe3=",
+		},
+		{
+			"count only search",
+			Args{"demo", []float32{0.111}, []SearchOption{WithReturns([]string{"content"}), WithOffsetLimit(0, 5), WithCountOnly()}},
+			"FT.SEARCH demo (*)=>[KNN 5 @content_vector $vector AS distance] SORTBY distance ASC DIALECT 2 LIMIT 0 0 PARAMS 2 vector \xf8S\xe3=",
+		},
+		{
+			"search with returns and vectors",
+			Args{"demo", []float32{0.111}, []SearchOption{WithReturns([]string{"content"}), WithIncludeVectors()}},
+			"FT.SEARCH demo (*)=>[KNN 1 @content_vector $vector AS distance] RETURN 3 content distance content_vector SORTBY distance ASC DIALECT 2 LIMIT 0 1 PARAMS 2 vector \xf8S\xe3=",
+		},
 	}
 
 	for _, tt := range tests {
@@ -190,3 +259,283 @@ func TestIndexSearchAsCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestIndexMetadataSearchAsCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []SearchOption
+		want string
+	}{
+		{
+			"basic search",
+			[]SearchOption{},
+			"FT.SEARCH demo * DIALECT 2 LIMIT 0 1",
+		},
+		{
+			"search with filter and returns",
+			[]SearchOption{WithPreFilters("@category:{news}"), WithReturns([]string{"content", "category"}), WithOffsetLimit(0, 5)},
+			"FT.SEARCH demo @category:{news} RETURN 2 content category DIALECT 2 LIMIT 0 5",
+		},
+		{
+			"hybrid search with filter and vector",
+			[]SearchOption{WithPreFilters("@category:{news}"), WithVector([]float32{0.111}), WithVectorField("embedding"), WithKNN(5)},
+			"FT.SEARCH demo (@category:{news})=>[KNN 5 @embedding $vector AS distance] SORTBY distance ASC DIALECT 2 LIMIT 0 1 PARAMS 2 vector \xf8S\xe3=",
+		},
+		{
+			"search with dialect",
+			[]SearchOption{WithDialect(4)},
+			"FT.SEARCH demo * DIALECT 4 LIMIT 0 1",
+		},
+		{
+			"search with sort by",
+			[]SearchOption{WithPreFilters("@category:{news}"), WithSortBy("published", true)},
+			"FT.SEARCH demo @category:{news} SORTBY published ASC DIALECT 2 LIMIT 0 1",
+		},
+		{
+			"search with alias",
+			[]SearchOption{WithReturnAlias("$.metadata.author", "author")},
+			"FT.SEARCH demo * RETURN 3 $.metadata.author AS author DIALECT 2 LIMIT 0 1",
+		},
+		{
+			"search with returns and aliases",
+			[]SearchOption{WithReturns([]string{"content"}), WithReturnAlias("$.metadata.author", "author"), WithReturnAlias("$.metadata.year", "year")},
+			"FT.SEARCH demo * RETURN 7 content $.metadata.author AS author $.metadata.year AS year DIALECT 2 LIMIT 0 1",
+		},
+		{
+			"JSON search with returns and vectors",
+			[]SearchOption{WithReturns([]string{"content"}), WithReturnAlias("$.meta.year", "year"), WithIncludeVectors(), WithStorageType(JSONIndexType)},
+			"FT.SEARCH demo * RETURN 9 $.content AS content $.content_vector AS content_vector $.meta.year AS year DIALECT 2 LIMIT 0 1",
+		},
+		{
+			"count only search",
+			[]SearchOption{WithPreFilters("@category:{news}"), WithReturns([]string{"content"}), WithOffsetLimit(10, 5), WithCountOnly()},
+			"FT.SEARCH demo @category:{news} DIALECT 2 LIMIT 0 0",
+		},
+		{
+			"search with highlight",
+			[]SearchOption{WithPreFilters("@content:tokyo"), WithReturns([]string{"content", "title"}), WithHighlight()},
+			"FT.SEARCH demo @content:tokyo RETURN 2 content title HIGHLIGHT FIELDS 1 content DIALECT 2 LIMIT 0 1",
+		},
+		{
+			"search with summarize and highlight",
+			[]SearchOption{WithPreFilters("tokyo"), WithSummarize("", 2, 10), WithHighlight("content", "title")},
+			"FT.SEARCH demo tokyo SUMMARIZE FIELDS 1 content FRAGS 2 LEN 10 HIGHLIGHT FIELDS 2 content title DIALECT 2 LIMIT 0 1",
+		},
+		{
+			"search with default summarize",
+			[]SearchOption{WithPreFilters("tokyo"), WithSummarize("title", 0, 0)},
+			"FT.SEARCH demo tokyo SUMMARIZE FIELDS 1 title DIALECT 2 LIMIT 0 1",
+		},
+		{
+			"hybrid search ignores highlight",
+			[]SearchOption{WithPreFilters("tokyo"), WithVector([]float32{0.111}), WithHighlight()},
+			"FT.SEARCH demo (tokyo)=>[KNN 1 @content_vector $vector AS distance] SORTBY distance ASC DIALECT 2 LIMIT 0 1 PARAMS 2 vector \xf8S\xe3=",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			search, err := NewIndexMetadataSearch("demo", tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, strings.Join(search.AsMetadataSearchCommand(), " "))
+		})
+	}
+}
+
+func TestIndexSearchInvalidDialect(t *testing.T) {
+	t.Parallel()
+
+	for _, dialect := range []int{-1, 0, 5} {
+		_, err := NewIndexMetadataSearch("demo", WithDialect(dialect))
+		require.ErrorIs(t, err, ErrInvalidDialect)
+		_, err = NewIndexVectorSearch("demo", []float32{0.111}, WithDialect(dialect))
+		require.ErrorIs(t, err, ErrInvalidDialect)
+	}
+}
+
+func TestIndexSearchInvalidOffsetLimit(t *testing.T) {
+	t.Parallel()
+
+	for _, opt := range []SearchOption{WithOffsetLimit(-1, 5), WithOffsetLimit(0, -5)} {
+		_, err := NewIndexMetadataSearch("demo", opt)
+		require.ErrorIs(t, err, ErrInvalidOffsetLimit)
+		_, err = NewIndexVectorSearch("demo", []float32{0.111}, opt)
+		require.ErrorIs(t, err, ErrInvalidOffsetLimit)
+	}
+}
+
+func TestIndexSearchInvalidScoreThreshold(t *testing.T) {
+	t.Parallel()
+
+	for _, opts := range [][]SearchOption{
+		{WithScoreThreshold(-0.1)},
+		{WithScoreThreshold(2.5)},
+		{WithScoreThreshold(2.5), WithDistanceMetric(CosineDistanceMetric)},
+		{WithScoreThreshold(-1), WithDistanceMetric(L2DistanceMetric)},
+	} {
+		_, err := NewIndexVectorSearch("demo", []float32{0.111}, opts...)
+		require.ErrorIs(t, err, ErrInvalidDistanceThreshold)
+	}
+
+	// L2 distances aren't bounded above.
+	search, err := NewIndexVectorSearch("demo", []float32{0.111},
+		WithScoreThreshold(2.5), WithDistanceMetric(L2DistanceMetric))
+	require.NoError(t, err)
+	assert.Contains(t, search.AsCommand(), "2.5")
+}
+
+func TestIndexSearchInvalidStorageType(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewIndexMetadataSearch("demo", WithStorageType("XML"))
+	require.ErrorIs(t, err, ErrInvalidStorageType)
+	_, err = NewIndexVectorSearch("demo", []float32{0.111}, WithStorageType("XML"))
+	require.ErrorIs(t, err, ErrInvalidStorageType)
+}
+
+func TestJSONIndexAsCommand(t *testing.T) {
+	t.Parallel()
+
+	indexSchema := IndexSchema{
+		Tag:     []TagField{{Name: "tags"}},
+		Text:    []TextField{{Name: "content"}, {Name: "$.meta.title", As: "title"}},
+		Numeric: []NumericField{{Name: "year", As: "published"}},
+		Vector:  []VectorField{{Name: "content_vector", Dims: 2}},
+	}
+
+	cmd, err := NewIndex("demo", []string{"doc:demo"}, JSONIndexType, indexSchema).AsCommand()
+	require.NoError(t, err)
+	assert.Equal(t, "FT.CREATE demo ON JSON PREFIX 1 doc:demo SCORE 1.0 SCHEMA "+
+		"$.tags[*] AS tags TAG , $.content AS content TEXT $.meta.title AS title TEXT $.year AS published NUMERIC "+
+		"$.content_vector AS content_vector VECTOR FLAT 6 TYPE FLOAT32 DIM 2 DISTANCE_METRIC COSINE",
+		strings.Join(cmd, " "))
+
+	cmd, err = NewIndex("demo", []string{"doc:demo"}, HASHIndexType, indexSchema).AsCommand()
+	require.NoError(t, err)
+	assert.Contains(t, strings.Join(cmd, " "), "ON HASH PREFIX 1 doc:demo SCORE 1.0 SCHEMA tags TAG , content TEXT")
+}
+
+func TestConvertFTSearchResJSON(t *testing.T) {
+	t.Parallel()
+
+	search, err := NewIndexVectorSearch("demo", []float32{0.111}, WithStorageType(JSONIndexType), WithIncludeVectors())
+	require.NoError(t, err)
+
+	// Fields returned with a RETURN clause.
+	res := convertFTSearchResIntoDocSchema([]rueidis.FtSearchDoc{{
+		Key: "doc:demo:1",
+		Doc: map[string]string{"content": "tokyo", "content_vector": "[0.5,-1]", "distance": "0.25"},
+	}}, *search)
+	assert.Equal(t, "tokyo", res[0].PageContent)
+	assert.InDelta(t, 0.25, res[0].Score, 1e-6)
+	assert.Equal(t, []float32{0.5, -1}, res[0].Metadata[vectorstores.VectorMetadataKey])
+
+	// Whole documents returned without a RETURN clause.
+	res = convertFTSearchResIntoDocSchema([]rueidis.FtSearchDoc{{
+		Key: "doc:demo:2",
+		Doc: map[string]string{"$": `{"content":"kyoto","content_vector":[1,2],"year":2024}`, "distance": "0.5"},
+	}}, *search)
+	assert.Equal(t, "kyoto", res[0].PageContent)
+	assert.Equal(t, map[string]any{
+		"id":                           "doc:demo:2",
+		"year":                         float64(2024),
+		vectorstores.VectorMetadataKey: []float32{1, 2},
+	}, res[0].Metadata)
+}
+
+func TestIndexSearchDefaultLimit(t *testing.T) {
+	t.Parallel()
+
+	metadataSearch, err := NewIndexMetadataSearch("demo")
+	require.NoError(t, err)
+	vectorSearch, err := NewIndexVectorSearch("demo", []float32{0.111})
+	require.NoError(t, err)
+
+	// The default limit doesn't depend on the receiver being mutated, so
+	// building the command twice, or from a bare search, emits the same LIMIT.
+	for i := 0; i < 2; i++ {
+		assert.Contains(t, strings.Join(metadataSearch.AsMetadataSearchCommand(), " "), "LIMIT 0 1")
+		assert.Contains(t, strings.Join(vectorSearch.AsCommand(), " "), "[KNN 1 @content_vector $vector AS distance]")
+		assert.Contains(t, strings.Join(vectorSearch.AsCommand(), " "), "LIMIT 0 1")
+	}
+	assert.Contains(t, strings.Join(IndexVectorSearch{index: "demo"}.AsMetadataSearchCommand(), " "), "LIMIT 0 1")
+	assert.Zero(t, metadataSearch.limit)
+	assert.Zero(t, vectorSearch.limit)
+}
+
+func TestConvertFTSearchResIncludeVectors(t *testing.T) {
+	t.Parallel()
+
+	docs := []rueidis.FtSearchDoc{{
+		Key: "doc:demo:1",
+		Doc: map[string]string{
+			"content":        "tokyo",
+			"content_vector": VectorString32([]float32{0.5, -1}),
+		},
+	}}
+
+	search, err := NewIndexVectorSearch("demo", []float32{0.111})
+	require.NoError(t, err)
+	res := convertFTSearchResIntoDocSchema(docs, *search)
+	assert.Equal(t, map[string]any{"id": "doc:demo:1"}, res[0].Metadata)
+
+	search, err = NewIndexVectorSearch("demo", []float32{0.111}, WithIncludeVectors())
+	require.NoError(t, err)
+	res = convertFTSearchResIntoDocSchema(docs, *search)
+	assert.Equal(t, "tokyo", res[0].PageContent)
+	assert.Equal(t, []float32{0.5, -1}, res[0].Metadata[vectorstores.VectorMetadataKey])
+}
+
+func TestConvertFTSearchResHighlight(t *testing.T) {
+	t.Parallel()
+
+	docs := []rueidis.FtSearchDoc{{
+		Key: "doc:demo:1",
+		Doc: map[string]string{"content": "the <b>tokyo</b> tower", "title": "towers"},
+	}}
+
+	search, err := NewIndexMetadataSearch("demo", WithHighlight())
+	require.NoError(t, err)
+	res := convertFTSearchResIntoDocSchema(docs, *search)
+	assert.Equal(t, "the <b>tokyo</b> tower", res[0].PageContent)
+	assert.Equal(t, map[string]string{"content": "the <b>tokyo</b> tower"}, res[0].Metadata[HighlightMetadataKey])
+
+	search, err = NewIndexMetadataSearch("demo")
+	require.NoError(t, err)
+	res = convertFTSearchResIntoDocSchema(docs, *search)
+	assert.NotContains(t, res[0].Metadata, HighlightMetadataKey)
+}
+
+// fakeSearchClient is a RedisClient recording the vector searches.
+type fakeSearchClient struct {
+	RedisClient
+	searches []IndexVectorSearch
+}
+
+func (c *fakeSearchClient) Search(_ context.Context, search IndexVectorSearch) (int64, []schema.Document, error) {
+	c.searches = append(c.searches, search)
+	return 0, nil, nil
+}
+
+func TestStoreDistanceThreshold(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeSearchClient{}
+	store := &Store{client: client, indexName: "test", distanceMetric: L2DistanceMetric}
+	_, err := store.SimilaritySearchByVector(context.Background(), []float32{1, 0}, 2, vectorstores.WithScoreThreshold(3))
+	require.NoError(t, err)
+	require.Len(t, client.searches, 1)
+	assert.Contains(t, strings.Join(client.searches[0].AsCommand(), " "), "distance_threshold 3")
+
+	store = &Store{client: client, indexName: "test"}
+	_, err = store.SimilaritySearchByVector(context.Background(), []float32{1, 0}, 2, vectorstores.WithScoreThreshold(1.5))
+	require.NoError(t, err)
+	_, err = store.SimilaritySearchByVector(context.Background(), []float32{1, 0}, 2, vectorstores.WithScoreThreshold(3))
+	require.ErrorIs(t, err, ErrInvalidDistanceThreshold)
+	_, err = store.SimilaritySearchByVector(context.Background(), []float32{1, 0}, 2, vectorstores.WithScoreThreshold(-1))
+	require.ErrorIs(t, err, ErrInvalidDistanceThreshold)
+	assert.Len(t, client.searches, 2)
+}
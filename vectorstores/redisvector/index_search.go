@@ -2,10 +2,12 @@ package redisvector
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
 	"strconv"
+	"strings"
 	"unsafe"
 )
 
@@ -13,15 +15,61 @@ type IndexVectorSearch struct {
 	index          string
 	vector         []float32
 	scoreThreshold float32
+	distanceMetric DistanceMetric
 	preFilters     string
 	returns        []string
+	returnAliases  []returnAlias
 	offset         int
 	limit          int
 	sortBy         []string
+	vectorField    string
+	k              int
+	dialect        int
+	countOnly      bool
+	includeVectors bool
+	storageType    IndexType
+	highlight      []string
+	summarize      *summarizeClause
 }
 
 type SearchOption func(s *IndexVectorSearch)
 
+// returnAlias is a returned field renamed in the search results.
+type returnAlias struct {
+	field string
+	alias string
+}
+
+// summarizeClause is the SUMMARIZE clause of a metadata search.
+type summarizeClause struct {
+	field string
+	frags int
+	len   int
+}
+
+// HighlightMetadataKey is the metadata key of the snippets of the fields
+// highlighted or summarized by a metadata search, a map[string]string of the
+// field names to their snippets, see WithHighlight and WithSummarize.
+const HighlightMetadataKey = "highlights"
+
+const (
+	defaultDialect = 2
+	// maxCosineDistance is the largest cosine distance, between opposite vectors.
+	maxCosineDistance = 2
+)
+
+var (
+	// ErrInvalidDialect is returned when the search dialect isn't between 1 and 4.
+	ErrInvalidDialect = errors.New("invalid dialect, must be between 1 and 4")
+	// ErrInvalidOffsetLimit is returned when the search offset or limit is negative.
+	ErrInvalidOffsetLimit = errors.New("invalid offset or limit, must not be negative")
+	// ErrInvalidDistanceThreshold is returned when the score threshold isn't
+	// a distance of the distance metric.
+	ErrInvalidDistanceThreshold = errors.New("invalid score threshold, must be a distance of the distance metric")
+	// ErrInvalidStorageType is returned when the storage type isn't HASH or JSON.
+	ErrInvalidStorageType = errors.New("invalid storage type, must be HASH or JSON")
+)
+
 func NewIndexVectorSearch(index string, vector []float32, opts ...SearchOption) (*IndexVectorSearch, error) {
 	if index == "" {
 		return nil, errors.New("invalid index")
@@ -33,18 +81,37 @@ func NewIndexVectorSearch(index string, vector []float32, opts ...SearchOption)
 		index:   index,
 		vector:  vector,
 		returns: []string{},
+		dialect: defaultDialect,
 	}
 	for _, opt := range opts {
 		opt(s)
 	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
 	return s, nil
 }
 
+// WithScoreThreshold only keeps the results within the given distance of the
+// query vector, turning the KNN query into a range query on the distance alias.
+// The threshold is a distance of the metric of the vector field, see
+// WithDistanceMetric: a COSINE distance is 1 minus the cosine similarity,
+// between 0 and 2, so a threshold t keeps the results of similarity 1-t or
+// more; L2 and IP distances depend on the scale of the embeddings and are only
+// bounded below by 0. A zero threshold disables the range query, and
+// thresholds outside the range of the metric are rejected with
+// ErrInvalidDistanceThreshold.
 func WithScoreThreshold(scoreThreshold float32) SearchOption {
 	return func(s *IndexVectorSearch) {
-		if scoreThreshold > 0 && scoreThreshold < 1 {
-			s.scoreThreshold = scoreThreshold
-		}
+		s.scoreThreshold = scoreThreshold
+	}
+}
+
+// WithDistanceMetric sets the distance metric of the vector field searched,
+// used to validate the score threshold, defaults to COSINE.
+func WithDistanceMetric(metric DistanceMetric) SearchOption {
+	return func(s *IndexVectorSearch) {
+		s.distanceMetric = metric
 	}
 }
 
@@ -74,6 +141,123 @@ func WithOffsetLimit(offset, limit int) SearchOption {
 	}
 }
 
+// WithSortBy sorts the results by the given field, in ascending or descending
+// order. Vector searches are sorted by ascending distance by default.
+func WithSortBy(field string, ascending bool) SearchOption {
+	return func(s *IndexVectorSearch) {
+		if field == "" {
+			return
+		}
+		order := "DESC"
+		if ascending {
+			order = "ASC"
+		}
+		s.sortBy = []string{field, order}
+	}
+}
+
+// WithIncludeVectors returns the vector field of the matching documents.
+func WithIncludeVectors() SearchOption {
+	return func(s *IndexVectorSearch) {
+		s.includeVectors = true
+	}
+}
+
+// WithStorageType sets how the documents of the index are stored, HASH (the
+// default) or JSON. The returned fields of a JSON search are emitted as their
+// JSON paths, e.g. "$.author AS author", so the results keep the field names.
+func WithStorageType(storageType IndexType) SearchOption {
+	return func(s *IndexVectorSearch) {
+		s.storageType = storageType
+	}
+}
+
+// WithCountOnly only counts the matching documents, emitting "LIMIT 0 0"
+// without a RETURN clause. Callers read the count from the first element of
+// the reply, i.e. the total returned by RedisClient.Search and MetadataSearch.
+func WithCountOnly() SearchOption {
+	return func(s *IndexVectorSearch) {
+		s.countOnly = true
+	}
+}
+
+// WithDialect sets the query dialect of the search, defaults to 2.
+func WithDialect(dialect int) SearchOption {
+	return func(s *IndexVectorSearch) {
+		s.dialect = dialect
+	}
+}
+
+// WithVector sets the query vector, turning a metadata search into a hybrid
+// search: the pre-filters are applied before the KNN clause.
+func WithVector(vector []float32) SearchOption {
+	return func(s *IndexVectorSearch) {
+		if len(vector) != 0 {
+			s.vector = vector
+		}
+	}
+}
+
+// WithReturnAlias returns the given field renamed as alias, e.g. to shorten
+// JSON paths like "$.metadata.author". It can be combined with WithReturns.
+func WithReturnAlias(field, alias string) SearchOption {
+	return func(s *IndexVectorSearch) {
+		if field != "" && alias != "" {
+			s.returnAliases = append(s.returnAliases, returnAlias{field: field, alias: alias})
+		}
+	}
+}
+
+// WithVectorField sets the name of the vector field searched, defaults to
+// "content_vector".
+func WithVectorField(field string) SearchOption {
+	return func(s *IndexVectorSearch) {
+		if field != "" {
+			s.vectorField = field
+		}
+	}
+}
+
+// WithKNN sets the number of nearest neighbors of a KNN search, defaults to
+// the limit.
+func WithKNN(k int) SearchOption {
+	return func(s *IndexVectorSearch) {
+		if k > 0 {
+			s.k = k
+		}
+	}
+}
+
+// WithHighlight wraps the terms of a metadata search matched in the given
+// fields, defaulting to the content field, in <b> tags. The snippets are also
+// returned under HighlightMetadataKey, Redis returning them in place of the
+// field values. Vector searches ignore it, their KNN clause matching no terms.
+func WithHighlight(fields ...string) SearchOption {
+	return func(s *IndexVectorSearch) {
+		if len(fields) == 0 {
+			fields = []string{defaultContentFieldKey}
+		}
+		s.highlight = fields
+	}
+}
+
+// WithSummarize returns fragments of the given field, defaulting to the content
+// field, around the terms of a metadata search: frags fragments of fragLen words,
+// Redis defaulting to 3 fragments of 20 words if not positive. Like
+// WithHighlight, the summary is returned under HighlightMetadataKey and
+// vector searches ignore it.
+func WithSummarize(field string, frags, fragLen int) SearchOption {
+	return func(s *IndexVectorSearch) {
+		if field == "" {
+			field = defaultContentFieldKey
+		}
+		s.summarize = &summarizeClause{field: field, frags: frags, len: fragLen}
+	}
+}
+
+// AsCommand returns the FT.SEARCH command of a vector search: a KNN query, or
+// a range query if a score threshold is set, with the query vector encoded as
+// a little-endian float32 blob in PARAMS.
 func (s IndexVectorSearch) AsCommand() []string {
 	// "FT.SEARCH" "users"
 	// "({prefilters})=>[KNN 5 @content_vector $vector AS distance]"
@@ -92,17 +276,23 @@ func (s IndexVectorSearch) AsCommand() []string {
 	// "params" "n" "vector" "xxx"  "distance_threshold" "0.1"
 	cmd := []string{"FT.SEARCH", s.index}
 
-	if s.limit == 0 {
-		s.limit = 1
+	// The KNN clause needs the number of neighbors even when only counting.
+	limit := s.limit
+	if limit == 0 {
+		limit = 1
 	}
 
 	const vectorField = "vector"
 	const vectorFieldAs = defaultDistanceFieldKey
 	const disThresholdFiled = "distance_threshold"
-	const vectorKey = defaultContentVectorFieldKey
+	vectorKey := s.vectorFieldKey()
+	k := limit
+	if s.k > 0 {
+		k = s.k
+	}
 	params := []string{vectorField, VectorString32(s.vector)}
 
-	if s.scoreThreshold > 0 && s.scoreThreshold < 1 {
+	if s.scoreThreshold > 0 {
 		// Range search
 		// "@content_vector:[VECTOR_RANGE $distance_threshold $vector]=>{$yield_distance_as: distance}"
 		filter := fmt.Sprintf("@%s:[VECTOR_RANGE $%s $%s]=>{$yield_distance_as: %s}", vectorKey, disThresholdFiled, vectorField, vectorFieldAs)
@@ -118,23 +308,22 @@ func (s IndexVectorSearch) AsCommand() []string {
 		if len(s.preFilters) > 0 {
 			filter = s.preFilters
 		}
-		cmd = append(cmd, fmt.Sprintf("(%s)=>[KNN %d @%s $%s AS %s]", filter, s.limit, vectorKey, vectorField, vectorFieldAs))
+		cmd = append(cmd, fmt.Sprintf("(%s)=>[KNN %d @%s $%s AS %s]", filter, k, vectorKey, vectorField, vectorFieldAs))
 	}
 
-	if l := len(s.returns); l > 0 {
-		s.returns = append(s.returns, defaultDistanceFieldKey)
-		cmd = append(cmd, "RETURN", strconv.Itoa(len(s.returns)))
-		cmd = append(cmd, s.returns...)
+	if !s.countOnly && (len(s.returns) > 0 || len(s.returnAliases) > 0) {
+		cmd = append(cmd, s.returnArgs(defaultDistanceFieldKey)...)
 	}
 
 	cmd = append(cmd, "SORTBY")
-	if len(s.sortBy) == 0 {
-		s.sortBy = []string{vectorFieldAs, "ASC"}
+	if len(s.sortBy) > 0 {
+		cmd = append(cmd, s.sortBy...)
+	} else {
+		cmd = append(cmd, vectorFieldAs, "ASC")
 	}
-	cmd = append(cmd, s.sortBy...)
 
-	cmd = append(cmd, "DIALECT", "2")
-	cmd = append(cmd, "LIMIT", strconv.Itoa(s.offset), strconv.Itoa(s.limit))
+	cmd = append(cmd, "DIALECT", s.dialectString())
+	cmd = append(cmd, s.limitArgs()...)
 
 	cmd = append(cmd, "PARAMS", strconv.Itoa(len(params)))
 	cmd = append(cmd, params...)
@@ -142,6 +331,128 @@ func (s IndexVectorSearch) AsCommand() []string {
 	return cmd
 }
 
+// validate checks the options of the search.
+func (s IndexVectorSearch) validate() error {
+	if s.dialect < 1 || s.dialect > 4 {
+		return ErrInvalidDialect
+	}
+	if s.offset < 0 || s.limit < 0 {
+		return ErrInvalidOffsetLimit
+	}
+	if s.storageType != "" && s.storageType != HASHIndexType && s.storageType != JSONIndexType {
+		return ErrInvalidStorageType
+	}
+	if s.scoreThreshold < 0 {
+		return ErrInvalidDistanceThreshold
+	}
+	metric := s.distanceMetric
+	if metric == "" {
+		metric = CosineDistanceMetric
+	}
+	if metric == CosineDistanceMetric && s.scoreThreshold > maxCosineDistance {
+		return ErrInvalidDistanceThreshold
+	}
+	return nil
+}
+
+// returnArgs returns the RETURN clause of the search, listing the plain
+// returned fields followed by the extra fields computed by the query, the
+// vector field if included and the aliased fields.
+func (s IndexVectorSearch) returnArgs(extra ...string) []string {
+	fields := make([]string, 0, (len(s.returns)+len(s.returnAliases)+1)*3+len(extra))
+	for _, field := range s.returns {
+		fields = append(fields, s.returnField(field)...)
+	}
+	fields = append(fields, extra...)
+	if s.includeVectors {
+		fields = append(fields, s.returnField(s.vectorFieldKey())...)
+	}
+	for _, r := range s.returnAliases {
+		fields = append(fields, r.field, "AS", r.alias)
+	}
+	return append([]string{"RETURN", strconv.Itoa(len(fields))}, fields...)
+}
+
+// returnField returns the RETURN arguments of a document field: its name, or
+// its JSON path aliased to its name if the documents are stored as JSON.
+func (s IndexVectorSearch) returnField(field string) []string {
+	if s.storageType != JSONIndexType || strings.HasPrefix(field, "$") {
+		return []string{field}
+	}
+	return []string{jsonPath(field), "AS", field}
+}
+
+// snippetArgs returns the SUMMARIZE and HIGHLIGHT clauses of a metadata
+// search.
+func (s IndexVectorSearch) snippetArgs() []string {
+	var args []string
+	if s.summarize != nil {
+		args = append(args, "SUMMARIZE", "FIELDS", "1", s.summarize.field)
+		if s.summarize.frags > 0 {
+			args = append(args, "FRAGS", strconv.Itoa(s.summarize.frags))
+		}
+		if s.summarize.len > 0 {
+			args = append(args, "LEN", strconv.Itoa(s.summarize.len))
+		}
+	}
+	if len(s.highlight) > 0 {
+		args = append(args, "HIGHLIGHT", "FIELDS", strconv.Itoa(len(s.highlight)))
+		args = append(args, s.highlight...)
+	}
+	return args
+}
+
+// snippetFields returns the set of the fields highlighted or summarized by a
+// metadata search.
+func (s IndexVectorSearch) snippetFields() map[string]bool {
+	if len(s.vector) > 0 || s.countOnly || (len(s.highlight) == 0 && s.summarize == nil) {
+		return nil
+	}
+	fields := make(map[string]bool, len(s.highlight)+1)
+	for _, field := range s.highlight {
+		fields[field] = true
+	}
+	if s.summarize != nil {
+		fields[s.summarize.field] = true
+	}
+	return fields
+}
+
+// jsonPath returns the JSON path of a top-level field of a JSON document.
+func jsonPath(field string) string {
+	return "$." + field
+}
+
+// vectorFieldKey returns the name of the vector field searched.
+func (s IndexVectorSearch) vectorFieldKey() string {
+	if s.vectorField != "" {
+		return s.vectorField
+	}
+	return defaultContentVectorFieldKey
+}
+
+// limitArgs returns the LIMIT clause of the search, defaulting to a single
+// result, or no results if only counting.
+func (s IndexVectorSearch) limitArgs() []string {
+	if s.countOnly {
+		return []string{"LIMIT", "0", "0"}
+	}
+	limit := s.limit
+	if limit == 0 {
+		limit = 1
+	}
+	return []string{"LIMIT", strconv.Itoa(s.offset), strconv.Itoa(limit)}
+}
+
+// dialectString returns the dialect of the search, defaulting to 2 for
+// searches built without a constructor.
+func (s IndexVectorSearch) dialectString() string {
+	if s.dialect == 0 {
+		return strconv.Itoa(defaultDialect)
+	}
+	return strconv.Itoa(s.dialect)
+}
+
 // convert []float32 into string.
 func VectorString32(v []float32) string {
 	b := make([]byte, len(v)*4)
@@ -152,6 +463,28 @@ func VectorString32(v []float32) string {
 	return unsafe.String(unsafe.SliceData(b), len(b))
 }
 
+// parseVector converts a returned vector field, a JSON array if the documents
+// are stored as JSON, a VectorString32 otherwise.
+func (s IndexVectorSearch) parseVector(v string) []float32 {
+	if s.storageType != JSONIndexType {
+		return parseVectorString32(v)
+	}
+	var vector []float32
+	if err := json.Unmarshal([]byte(v), &vector); err != nil {
+		return nil
+	}
+	return vector
+}
+
+// parseVectorString32 converts a string of VectorString32 back into []float32.
+func parseVectorString32(s string) []float32 {
+	v := make([]float32, len(s)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32([]byte(s[i*4 : i*4+4])))
+	}
+	return v
+}
+
 // convert []float64 into string.
 func VectorString64(v []float64) string {
 	b := make([]byte, len(v)*8)
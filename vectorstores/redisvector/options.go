@@ -3,6 +3,8 @@ package redisvector
 import (
 	"errors"
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/tmc/langchaingo/embeddings"
 )
@@ -80,8 +82,42 @@ func WithIndexSchema(format SchemaFormat, schemaFilePath string, schemaBytes []b
 	}
 }
 
+// WithTTL is an option for specifying the time to live of the documents added
+// with `AddDocuments`: their keys expire after ttl (with PEXPIRE), and expired
+// documents drop out of the search results once Redis evicts them.
+// A TTL of zero, the default, means documents never expire.
+func WithTTL(ttl time.Duration) Option {
+	return func(s *Store) {
+		s.ttl = ttl
+	}
+}
+
+// WithIndexType is an option for specifying how the documents are stored,
+// HASHIndexType (the default) or JSONIndexType. The index is created on the
+// given type, documents are added with HSET or JSON.SET, and searches return
+// the JSON paths of the fields of JSON documents (see `WithStorageType`).
+func WithIndexType(indexType IndexType) Option {
+	return func(s *Store) {
+		s.indexType = indexType
+	}
+}
+
+// WithIndexDistanceMetric is an option for specifying the distance metric of
+// the content vector field of the index created from the document metadata
+// by `AddDocuments`: CosineDistanceMetric (the default), L2DistanceMetric or
+// IPDistanceMetric. It must match how the embeddings are meant to be
+// compared, e.g. IP for embeddings trained for the dot product. The searches
+// interpret their score threshold and return their distances in this metric.
+// With `WithIndexSchema`, the metric of the schema's content vector field is
+// used instead, and a different metric is rejected.
+func WithIndexDistanceMetric(metric DistanceMetric) Option {
+	return func(s *Store) {
+		s.distanceMetric = metric
+	}
+}
+
 func applyClientOptions(opts ...Option) (*Store, error) {
-	s := &Store{}
+	s := &Store{closed: &atomic.Bool{}}
 
 	for _, opt := range opts {
 		opt(s)
@@ -95,6 +131,23 @@ func applyClientOptions(opts ...Option) (*Store, error) {
 		return nil, fmt.Errorf("%w: missing index name", ErrInvalidOptions)
 	}
 
+	if s.ttl < 0 {
+		return nil, fmt.Errorf("%w: negative ttl", ErrInvalidOptions)
+	}
+
+	if s.indexType == "" {
+		s.indexType = HASHIndexType
+	}
+	if s.indexType != HASHIndexType && s.indexType != JSONIndexType {
+		return nil, fmt.Errorf("%w: invalid index type %q", ErrInvalidOptions, s.indexType)
+	}
+
+	switch s.distanceMetric {
+	case "", CosineDistanceMetric, L2DistanceMetric, IPDistanceMetric:
+	default:
+		return nil, fmt.Errorf("%w: invalid distance metric %q", ErrInvalidOptions, s.distanceMetric)
+	}
+
 	if s.schemaGenerator != nil {
 		schema, err := s.schemaGenerator.generate()
 		if err != nil {
@@ -103,6 +156,11 @@ func applyClientOptions(opts ...Option) (*Store, error) {
 		s.indexSchema = schema
 		// clear generator buf
 		s.schemaGenerator = nil
+
+		if metric := schema.contentVectorDistanceMetric(); s.distanceMetric != "" && metric != "" && metric != s.distanceMetric {
+			return nil, fmt.Errorf("%w: distance metric %q differs from the %q metric of the index schema",
+				ErrInvalidOptions, s.distanceMetric, metric)
+		}
 	}
 
 	return s, nil
@@ -2,15 +2,20 @@ package redisvector
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/redis/rueidis"
+	"github.com/tmc/langchaingo/embeddings"
 	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
 	"golang.org/x/exp/maps"
 	"log/slog"
 	"reflect"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // RedisClient interface of redis client, easy to replace third redis client package
@@ -18,12 +23,22 @@ import (
 type RedisClient interface {
 	DropIndex(ctx context.Context, index string, deleteDocuments bool) error
 	CheckIndexExists(ctx context.Context, index string) bool
+	// PingIndex returns the error of FT.INFO on the index, ErrNotExistedIndex
+	// if the index doesn't exist.
+	PingIndex(ctx context.Context, index string) error
 	CreateIndexIfNotExists(ctx context.Context, index string, schema *IndexSchema) error
+	// CreateJSONIndexIfNotExists creates an index of JSON documents.
+	CreateJSONIndexIfNotExists(ctx context.Context, index string, schema *IndexSchema) error
 	AddDocWithHash(ctx context.Context, prefix string, doc schema.Document) (string, error)
 	AddDocsWithHash(ctx context.Context, prefix string, docs []schema.Document) ([]string, error)
-	// TODO AddDocsWithJSON
+	// AddDocsWithJSON adds the documents as JSON documents.
+	AddDocsWithJSON(ctx context.Context, prefix string, docs []schema.Document) ([]string, error)
+	// ExpireDocs sets the time to live of the documents with the given ids.
+	ExpireDocs(ctx context.Context, docIDs []string, ttl time.Duration) error
 	Search(ctx context.Context, search IndexVectorSearch) (int64, []schema.Document, error)
 	MetadataSearch(ctx context.Context, search IndexVectorSearch) (int64, []schema.Document, error)
+	// Close closes the connections of the client.
+	Close() error
 }
 
 type RueidisClient struct {
@@ -45,6 +60,11 @@ func NewRueidisClient(url string) (*RueidisClient, error) {
 	return &RueidisClient{client}, err
 }
 
+func (c RueidisClient) Close() error {
+	c.client.Close()
+	return nil
+}
+
 func (c RueidisClient) DropIndex(ctx context.Context, index string, deleteDocuments bool) error {
 	if deleteDocuments {
 		return c.client.Do(ctx, c.client.B().FtDropindex().Index(index).Dd().Build()).Error()
@@ -59,7 +79,30 @@ func (c RueidisClient) CheckIndexExists(ctx context.Context, index string) bool
 	return c.client.Do(ctx, c.client.B().FtInfo().Index(index).Build()).Error() == nil
 }
 
+func (c RueidisClient) PingIndex(ctx context.Context, index string) error {
+	if index == "" {
+		return ErrEmptyIndexName
+	}
+	err := c.client.Do(ctx, c.client.B().FtInfo().Index(index).Build()).Error()
+	if redisErr, ok := rueidis.IsRedisErr(err); ok {
+		// The message depends on the version of RediSearch.
+		msg := strings.ToLower(redisErr.Error())
+		if strings.Contains(msg, "unknown index") || strings.Contains(msg, "no such index") {
+			return fmt.Errorf("%w: %s", ErrNotExistedIndex, index)
+		}
+	}
+	return err
+}
+
 func (c RueidisClient) CreateIndexIfNotExists(ctx context.Context, index string, schema *IndexSchema) error {
+	return c.createIndexIfNotExists(ctx, index, HASHIndexType, schema)
+}
+
+func (c RueidisClient) CreateJSONIndexIfNotExists(ctx context.Context, index string, schema *IndexSchema) error {
+	return c.createIndexIfNotExists(ctx, index, JSONIndexType, schema)
+}
+
+func (c RueidisClient) createIndexIfNotExists(ctx context.Context, index string, indexType IndexType, schema *IndexSchema) error {
 	if index == "" {
 		return ErrEmptyIndexName
 	}
@@ -68,7 +111,7 @@ func (c RueidisClient) CreateIndexIfNotExists(ctx context.Context, index string,
 		return nil
 	}
 
-	redisIndex := NewIndex(index, []string{getPrefix(index)}, HASHIndexType, *schema)
+	redisIndex := NewIndex(index, []string{getPrefix(index)}, indexType, *schema)
 	createIndexCmd, err := redisIndex.AsCommand()
 	if err != nil {
 		return err
@@ -99,6 +142,41 @@ func (c RueidisClient) AddDocsWithHash(ctx context.Context, prefix string, docs
 	return docIDs, errors.Join(errs...)
 }
 
+func (c RueidisClient) AddDocsWithJSON(ctx context.Context, prefix string, docs []schema.Document) ([]string, error) {
+	cmds := make([]rueidis.Completed, 0, len(docs))
+	docIDs := make([]string, 0, len(docs))
+	errs := make([]error, 0, len(docs))
+	for _, doc := range docs {
+		docID, cmd, err := c.generateJSONSetCMD(prefix, doc)
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, cmd)
+		docIDs = append(docIDs, docID)
+	}
+	result := c.client.DoMulti(ctx, cmds...)
+	for _, res := range result {
+		if res.Error() != nil {
+			errs = append(errs, res.Error())
+		}
+	}
+	return docIDs, errors.Join(errs...)
+}
+
+func (c RueidisClient) ExpireDocs(ctx context.Context, docIDs []string, ttl time.Duration) error {
+	cmds := make([]rueidis.Completed, 0, len(docIDs))
+	for _, docID := range docIDs {
+		cmds = append(cmds, c.client.B().Pexpire().Key(docID).Milliseconds(ttl.Milliseconds()).Build())
+	}
+	errs := make([]error, 0, len(docIDs))
+	for _, res := range c.client.DoMulti(ctx, cmds...) {
+		if res.Error() != nil {
+			errs = append(errs, res.Error())
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func (c RueidisClient) Search(ctx context.Context, search IndexVectorSearch) (int64, []schema.Document, error) {
 	cmds := search.AsCommand()
 	// fmt.Println(strings.Join(cmds, " "))
@@ -107,7 +185,7 @@ func (c RueidisClient) Search(ctx context.Context, search IndexVectorSearch) (in
 		return 0, nil, err
 	}
 
-	return total, convertFTSearchResIntoDocSchema(docs), nil
+	return total, convertFTSearchResIntoDocSchema(docs, search), nil
 }
 
 func (c RueidisClient) MetadataSearch(ctx context.Context, search IndexVectorSearch) (int64, []schema.Document, error) {
@@ -118,7 +196,7 @@ func (c RueidisClient) MetadataSearch(ctx context.Context, search IndexVectorSea
 		return 0, nil, err
 	}
 
-	return total, convertFTSearchResIntoDocSchema(docs), nil
+	return total, convertFTSearchResIntoDocSchema(docs, search), nil
 }
 
 func (c RueidisClient) generateHSetCMD(prefix string, doc schema.Document) (string, rueidis.Completed) {
@@ -130,7 +208,8 @@ func (c RueidisClient) generateHSetCMD(prefix string, doc schema.Document) (stri
 			if _v, ok := v.([]float32); ok {
 				kvs = append(kvs, VectorString32(_v))
 			} else if _v, ok := v.([]float64); ok {
-				kvs = append(kvs, VectorString64(_v))
+				// The vector fields of the index are FLOAT32.
+				kvs = append(kvs, VectorString32(embeddings.Float64ToFloat32(_v)))
 			} else {
 				slog.Warn("the type of content vector filed is invalid", "type", reflect.TypeOf(v))
 			}
@@ -142,6 +221,17 @@ func (c RueidisClient) generateHSetCMD(prefix string, doc schema.Document) (stri
 	return docID, c.client.B().Arbitrary("Hmset").Keys(docID).Args(kvs...).Build()
 }
 
+// generateJSONSetCMD returns the JSON.SET command storing the metadata of doc,
+// with its content and vector, as a JSON document.
+func (c RueidisClient) generateJSONSetCMD(prefix string, doc schema.Document) (string, rueidis.Completed, error) {
+	data, err := json.Marshal(doc.Metadata)
+	if err != nil {
+		return "", rueidis.Completed{}, err
+	}
+	docID := getDocIDWithMetaData(prefix, doc.Metadata)
+	return docID, c.client.B().JsonSet().Key(docID).Path("$").Value(string(data)).Build(), nil
+}
+
 // getPrefix get prefix with index name.
 func getPrefix(index string) string {
 	return fmt.Sprintf("doc:%s", index)
@@ -162,18 +252,38 @@ func getDocIDWithMetaData(prefix string, meta map[string]any) string {
 	return fmt.Sprintf("%s:%v", prefix, uuid.New().String())
 }
 
-func convertFTSearchResIntoDocSchema(docs []rueidis.FtSearchDoc) []schema.Document {
+// convertFTSearchResIntoDocSchema converts the documents found by a search,
+// with their vector in the metadata if the search includes vectors.
+// The whole JSON documents, returned without a RETURN clause, are decoded.
+func convertFTSearchResIntoDocSchema(docs []rueidis.FtSearchDoc, search IndexVectorSearch) []schema.Document {
+	vectorKey := search.vectorFieldKey()
+	snippetFields := search.snippetFields()
 	res := make([]schema.Document, 0, len(docs))
 	for _, doc := range docs {
 		_doc := schema.Document{}
 		metadata := make(map[string]any, len(doc.Doc))
+		if len(snippetFields) > 0 {
+			snippets := map[string]string{}
+			for k, v := range doc.Doc {
+				if snippetFields[k] {
+					snippets[k] = v
+				}
+			}
+			metadata[HighlightMetadataKey] = snippets
+		}
 		//nolint: gocritic
 		for k, v := range doc.Doc {
-			if k == defaultContentFieldKey {
+			if k == "$" && search.storageType == JSONIndexType {
+				convertJSONDoc(v, &_doc, metadata, search)
+			} else if k == defaultContentFieldKey {
 				_doc.PageContent = v
 			} else if k == defaultDistanceFieldKey {
 				score, _ := strconv.ParseFloat(v, 32)
 				_doc.Score = float32(score)
+			} else if k == vectorKey {
+				if search.includeVectors {
+					metadata[vectorstores.VectorMetadataKey] = search.parseVector(v)
+				}
 			} else if k != defaultContentVectorFieldKey {
 				metadata[k] = v
 			}
@@ -186,3 +296,30 @@ func convertFTSearchResIntoDocSchema(docs []rueidis.FtSearchDoc) []schema.Docume
 	}
 	return res
 }
+
+// convertJSONDoc converts a whole JSON document found by a search.
+func convertJSONDoc(v string, doc *schema.Document, metadata map[string]any, search IndexVectorSearch) {
+	fields := map[string]any{}
+	if err := json.Unmarshal([]byte(v), &fields); err != nil {
+		slog.Warn("ignore invalid JSON document", "error", err)
+		return
+	}
+	vectorKey := search.vectorFieldKey()
+	for k, v := range fields {
+		switch k {
+		case defaultContentFieldKey:
+			doc.PageContent, _ = v.(string)
+		case vectorKey, defaultContentVectorFieldKey:
+			if values, ok := v.([]any); ok && k == vectorKey && search.includeVectors {
+				vector := make([]float32, 0, len(values))
+				for _, value := range values {
+					f, _ := value.(float64)
+					vector = append(vector, float32(f))
+				}
+				metadata[vectorstores.VectorMetadataKey] = vector
+			}
+		default:
+			metadata[k] = v
+		}
+	}
+}
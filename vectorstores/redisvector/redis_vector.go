@@ -3,6 +3,9 @@ package redisvector
 import (
 	"context"
 	"errors"
+	"io"
+	"sync/atomic"
+	"time"
 
 	"github.com/tmc/langchaingo/embeddings"
 	"github.com/tmc/langchaingo/schema"
@@ -21,8 +24,12 @@ var (
 	ErrEmptyIndexName         = errors.New("empty redis index name")
 	ErrNotExistedIndex        = errors.New("redis index name does not exist")
 	ErrInvalidEmbeddingVector = errors.New("embedding vector error")
-	ErrInvalidScoreThreshold  = errors.New("score threshold must be between 0 and 1")
-	ErrInvalidFilters         = errors.New("invalid filters")
+	// ErrInvalidScoreThreshold is no longer returned.
+	//
+	// Deprecated: the score thresholds outside of the range of the distance
+	// metric of the index are rejected with ErrInvalidDistanceThreshold.
+	ErrInvalidScoreThreshold = errors.New("score threshold must be between 0 and 1")
+	ErrInvalidFilters        = errors.New("invalid filters")
 )
 
 // Store is a wrapper around the redis client.
@@ -34,9 +41,17 @@ type Store struct {
 	createIndexIfNotExists bool
 	indexSchema            *IndexSchema
 	schemaGenerator        *schemaGenerator
+	ttl                    time.Duration
+	indexType              IndexType
+	distanceMetric         DistanceMetric
+	// closed is set by Close, and shared by the copies of the Store.
+	closed *atomic.Bool
 }
 
-var _ vectorstores.VectorStore = &Store{}
+var (
+	_ vectorstores.VectorStore = &Store{}
+	_ io.Closer                = &Store{}
+)
 
 // New creates a new Store with options.
 func New(ctx context.Context, opts ...Option) (*Store, error) {
@@ -60,7 +75,7 @@ func New(ctx context.Context, opts ...Option) (*Store, error) {
 			return nil, ErrNotExistedIndex
 		} else if s.indexSchema != nil {
 			// create index with input schema
-			if err := s.client.CreateIndexIfNotExists(ctx, s.indexName, s.indexSchema); err != nil {
+			if err := s.createIndex(ctx, s.indexSchema); err != nil {
 				return nil, err
 			}
 		}
@@ -69,6 +84,27 @@ func New(ctx context.Context, opts ...Option) (*Store, error) {
 	return s, nil
 }
 
+// Close closes the redis client of the Store. The methods of the Store, and
+// of its copies, return vectorstores.ErrClosed once it is closed. Closing a
+// closed Store is a no-op.
+func (s *Store) Close() error {
+	if s.closed != nil && !s.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	if s.client == nil {
+		return nil
+	}
+	return s.client.Close()
+}
+
+// checkOpen returns vectorstores.ErrClosed if the Store was closed.
+func (s Store) checkOpen() error {
+	if s.closed != nil && s.closed.Load() {
+		return vectorstores.ErrClosed
+	}
+	return nil
+}
+
 func (s Store) deduplicate(ctx context.Context,
 	opts vectorstores.Options,
 	docs []schema.Document,
@@ -89,16 +125,29 @@ func (s Store) deduplicate(ctx context.Context,
 
 // AddDocuments adds the text and metadata from the documents to the redis associated with 'Store'.
 // and returns the ids of the added documents.
-// Note: currently save documents with Hset command
+// Note: documents are saved with the Hset command, or with JSON.SET if the index stores
+// JSON documents (see `WithIndexType`)
 // return `docIDs` that prefix with `doc:{index_name}`
 //
 //	if doc.metadata has `keys` or `ids` field, the docId will use `keys` or `ids` value
 //	if not, the docId is uuid string
+//
+// With `vectorstores.WithContentHashID`, the `ids` field is set to the content hash of
+// the document, so adding an identical document overwrites it.
+//
+// If the store has a TTL (see `WithTTL`), the documents expire after it.
+// The documents are embedded with the embedder of `vectorstores.WithEmbedder` if set,
+// otherwise with the store's embedder.
 func (s Store) AddDocuments(ctx context.Context,
 	docs []schema.Document,
 	options ...vectorstores.Option,
 ) ([]string, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
 	opts := s.getOptions(options...)
+	ctx, cancel := vectorstores.TimeoutContext(ctx, opts)
+	defer cancel()
 
 	docs = s.deduplicate(ctx, opts, docs)
 
@@ -108,7 +157,21 @@ func (s Store) AddDocuments(ctx context.Context,
 		return nil, nil
 	}
 
-	err := s.appendDocumentsWithVectors(ctx, docs)
+	if opts.ContentHashID {
+		for i := range docs {
+			hash := vectorstores.ContentHash(docs[i], opts.ContentHashKeys...)
+			if docs[i].Metadata == nil {
+				docs[i].Metadata = map[string]any{}
+			}
+			docs[i].Metadata["ids"] = hash
+		}
+	}
+
+	embedder := s.embedder
+	if opts.Embedder != nil {
+		embedder = opts.Embedder
+	}
+	err := s.appendDocumentsWithVectors(ctx, docs, embedder)
 	if err != nil {
 		return nil, err
 	}
@@ -117,40 +180,55 @@ func (s Store) AddDocuments(ctx context.Context,
 	if err != nil {
 		return nil, err
 	}
+	indexSchema.setContentVectorDistanceMetric(s.distanceMetric)
 
 	if s.indexSchema == nil {
 		s.indexSchema = indexSchema
 	}
 
 	if s.createIndexIfNotExists && !s.client.CheckIndexExists(ctx, s.indexName) {
-		if err := s.client.CreateIndexIfNotExists(ctx, s.indexName, indexSchema); err != nil {
+		if err := s.createIndex(ctx, indexSchema); err != nil {
 			return nil, err
 		}
 	}
 
-	docIDs, err := s.client.AddDocsWithHash(ctx, getPrefix(s.indexName), docs)
+	var docIDs []string
+	if s.indexType == JSONIndexType {
+		docIDs, err = s.client.AddDocsWithJSON(ctx, getPrefix(s.indexName), docs)
+	} else {
+		docIDs, err = s.client.AddDocsWithHash(ctx, getPrefix(s.indexName), docs)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	if s.ttl > 0 {
+		if err := s.client.ExpireDocs(ctx, docIDs, s.ttl); err != nil {
+			return nil, err
+		}
+	}
+
 	return docIDs, nil
 }
 
 // SimilaritySearch similarity search docs with `ScoreThreshold` `Filters` `Embedder`
 // Support options:
 //
-//	WithScoreThreshold:
+//	WithScoreThreshold: only keep the documents within this distance of the query, see the SearchOption of the same name;
+//		thresholds outside of the range of the distance metric of the index are rejected with ErrInvalidDistanceThreshold
 //	WithFilters: filter string should match redis search pre-filter query pattern.(eg: @title:Dune)
 //		ref: https://redis.io/docs/latest/develop/interact/search-and-query/advanced-concepts/vectors/#pre-filter-query-attributes-hybrid-approach
 //	WithEmbedder: if set, it will embed query string with this embedder; otherwise embed with vector's embedder
+//	WithIncludeVectors: if set, the stored vector of each document is returned in its metadata
 //
 // ref: https://redis.io/docs/latest/develop/interact/search-and-query/advanced-concepts/vectors/#pre-filter-query-attributes-hybrid-approach
 func (s *Store) SimilaritySearch(ctx context.Context, query string, numDocuments int, options ...vectorstores.Option) ([]schema.Document, error) {
-	opts := s.getOptions(options...)
-	scoreThreshold, err := s.getScoreThreshold(opts)
-	if err != nil {
+	if err := s.checkOpen(); err != nil {
 		return nil, err
 	}
+	opts := s.getOptions(options...)
+	ctx, cancel := vectorstores.TimeoutContext(ctx, opts)
+	defer cancel()
 	filter, err := s.getFilters(opts)
 	if err != nil {
 		return nil, err
@@ -163,15 +241,43 @@ func (s *Store) SimilaritySearch(ctx context.Context, query string, numDocuments
 	if err != nil {
 		return nil, err
 	}
+	return s.searchByVector(ctx, opts, embedderData, numDocuments, opts.ScoreThreshold, filter)
+}
+
+// SimilaritySearchByVector returns the numDocuments documents most similar to
+// the query vector, e.g. a cached embedding of the query, without embedding a
+// query. It takes the options of SimilaritySearch, except
+// vectorstores.WithEmbedder which is unused.
+func (s *Store) SimilaritySearchByVector(ctx context.Context, vector []float32, numDocuments int, options ...vectorstores.Option) ([]schema.Document, error) { //nolint:lll
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+	opts := s.getOptions(options...)
+	ctx, cancel := vectorstores.TimeoutContext(ctx, opts)
+	defer cancel()
+	filter, err := s.getFilters(opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.searchByVector(ctx, opts, vector, numDocuments, opts.ScoreThreshold, filter)
+}
 
-	searchOpts := []SearchOption{WithScoreThreshold(scoreThreshold), WithOffsetLimit(0, numDocuments), WithPreFilters(filter)}
+// searchByVector runs the KNN query of the vector.
+func (s *Store) searchByVector(ctx context.Context, opts vectorstores.Options, vector []float32, numDocuments int, scoreThreshold float32, filter string) ([]schema.Document, error) { //nolint:lll
+	searchOpts := []SearchOption{
+		WithScoreThreshold(scoreThreshold), WithOffsetLimit(0, numDocuments), WithPreFilters(filter), WithStorageType(s.indexType),
+		WithDistanceMetric(s.contentDistanceMetric()),
+	}
 	if s.indexSchema != nil {
 		searchOpts = append(searchOpts, WithReturns(maps.Keys(s.indexSchema.MetadataKeys())))
 	}
+	if opts.IncludeVectors {
+		searchOpts = append(searchOpts, WithIncludeVectors())
+	}
 
 	search, err := NewIndexVectorSearch(
 		s.indexName,
-		embedderData,
+		vector,
 		searchOpts...,
 	)
 	if err != nil {
@@ -185,22 +291,40 @@ func (s *Store) SimilaritySearch(ctx context.Context, query string, numDocuments
 	return docs, nil
 }
 
+// SimilaritySearchWithScore is SimilaritySearch returning the documents paired
+// with their score, the distance to the query of the distance metric of the
+// index.
+func (s *Store) SimilaritySearchWithScore(ctx context.Context, query string, numDocuments int, options ...vectorstores.Option) ([]vectorstores.ScoredDocument, error) { //nolint:lll
+	docs, err := s.SimilaritySearch(ctx, query, numDocuments, options...)
+	if err != nil {
+		return nil, err
+	}
+	return vectorstores.ScoredDocuments(docs), nil
+}
+
 func (s *Store) MetadataSearch(ctx context.Context, numDocuments int, options ...vectorstores.Option) ([]schema.Document,
 	error) {
-	opts := s.getOptions(options...)
-	scoreThreshold, err := s.getScoreThreshold(opts)
-	if err != nil {
+	if err := s.checkOpen(); err != nil {
 		return nil, err
 	}
+	opts := s.getOptions(options...)
+	ctx, cancel := vectorstores.TimeoutContext(ctx, opts)
+	defer cancel()
 	filter, err := s.getFilters(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	searchOpts := []SearchOption{WithScoreThreshold(scoreThreshold), WithOffsetLimit(0, numDocuments), WithPreFilters(filter)}
+	searchOpts := []SearchOption{
+		WithScoreThreshold(opts.ScoreThreshold), WithOffsetLimit(0, numDocuments), WithPreFilters(filter), WithStorageType(s.indexType),
+		WithDistanceMetric(s.contentDistanceMetric()),
+	}
 	if s.indexSchema != nil {
 		searchOpts = append(searchOpts, WithReturns(maps.Keys(s.indexSchema.MetadataKeys())))
 	}
+	if opts.IncludeVectors {
+		searchOpts = append(searchOpts, WithIncludeVectors())
+	}
 
 	search, err := NewIndexMetadataSearch(
 		s.indexName,
@@ -217,13 +341,46 @@ func (s *Store) MetadataSearch(ctx context.Context, numDocuments int, options ..
 	return docs, nil
 }
 
+// Ping checks that Redis is reachable and that the index of the Store exists,
+// returning ErrNotExistedIndex if it doesn't.
+func (s *Store) Ping(ctx context.Context) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
+	return s.client.PingIndex(ctx, s.indexName)
+}
+
 func (s *Store) DropIndex(ctx context.Context, index string, deleteDocuments bool) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
 	if !s.client.CheckIndexExists(ctx, index) {
 		return ErrNotExistedIndex
 	}
 	return s.client.DropIndex(ctx, index, deleteDocuments)
 }
 
+// contentDistanceMetric returns the distance metric of the content vector
+// field of the index: the one of the index schema if set, otherwise the one
+// of WithIndexDistanceMetric.
+func (s Store) contentDistanceMetric() DistanceMetric {
+	if s.indexSchema != nil {
+		if metric := s.indexSchema.contentVectorDistanceMetric(); metric != "" {
+			return metric
+		}
+	}
+	return s.distanceMetric
+}
+
+// createIndex creates the index of the store with the given schema, if it
+// doesn't exist.
+func (s Store) createIndex(ctx context.Context, schema *IndexSchema) error {
+	if s.indexType == JSONIndexType {
+		return s.client.CreateJSONIndexIfNotExists(ctx, s.indexName, schema)
+	}
+	return s.client.CreateIndexIfNotExists(ctx, s.indexName, schema)
+}
+
 func (s Store) getOptions(options ...vectorstores.Option) vectorstores.Options {
 	opts := vectorstores.Options{}
 	for _, opt := range options {
@@ -232,26 +389,25 @@ func (s Store) getOptions(options ...vectorstores.Option) vectorstores.Options {
 	return opts
 }
 
-func (s Store) getScoreThreshold(opts vectorstores.Options) (float32, error) {
-	if opts.ScoreThreshold < 0 || opts.ScoreThreshold > 1 {
-		return 0, ErrInvalidScoreThreshold
-	}
-	return opts.ScoreThreshold, nil
-}
-
-// getFilters return metadata filters.
+// getFilters return metadata filters, a RediSearch query or a
+// vectorstores.Filter translated into one.
 func (s Store) getFilters(opts vectorstores.Options) (string, error) {
-	if opts.Filters != nil {
-		if filters, ok := opts.Filters.(string); ok {
-			return filters, nil
-		}
+	switch filters := opts.Filters.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return filters, nil
+	case vectorstores.Filter:
+		return s.filterQuery(filters)
+	case *vectorstores.Filter:
+		return s.filterQuery(*filters)
+	default:
 		return "", ErrInvalidFilters
 	}
-	return "", nil
 }
 
 // append content & content_vector into doc.Metadata.
-func (s Store) appendDocumentsWithVectors(ctx context.Context, docs []schema.Document) error {
+func (s Store) appendDocumentsWithVectors(ctx context.Context, docs []schema.Document, embedder embeddings.Embedder) error {
 	if len(docs) == 0 {
 		return nil
 	}
@@ -261,7 +417,7 @@ func (s Store) appendDocumentsWithVectors(ctx context.Context, docs []schema.Doc
 		texts = append(texts, doc.PageContent)
 	}
 
-	vectors, err := s.embedder.EmbedDocuments(ctx, texts)
+	vectors, err := embedder.EmbedDocuments(ctx, texts)
 	if err != nil {
 		return err
 	}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -226,6 +227,20 @@ func TestAddDocuments(t *testing.T) {
 	assert.Equal(t, prefix+index+":id1", docIDs[0])
 	assert.Equal(t, prefix+index+":key1", docIDs[1])
 
+	// skip the documents rejected by the deduplicater, adding nothing if all of
+	// them are duplicates
+	docIDs, err = vector.AddDocuments(ctx, data[:2], vectorstores.WithDeduplicater(
+		func(_ context.Context, doc schema.Document) bool { return doc.PageContent == "Tokyo" },
+	))
+	require.NoError(t, err)
+	assert.Len(t, docIDs, 1)
+
+	docIDs, err = vector.AddDocuments(ctx, data, vectorstores.WithDeduplicater(
+		func(context.Context, schema.Document) bool { return true },
+	))
+	require.NoError(t, err)
+	assert.Empty(t, docIDs)
+
 	// create vector with existed index & index schema, will not create new index
 	_, err = redisvector.New(ctx,
 		redisvector.WithConnectionURL(redisURL),
@@ -252,6 +267,51 @@ func TestAddDocuments(t *testing.T) {
 	})
 }
 
+func TestAddDocumentsWithTTL(t *testing.T) {
+	t.Parallel()
+
+	redisURL, ollamaURL := getValues(t)
+	_, e := getEmbedding(ollamaModel, ollamaURL)
+
+	ctx := context.Background()
+	index := "test_add_document_ttl"
+
+	_, err := redisvector.New(ctx,
+		redisvector.WithConnectionURL(redisURL),
+		redisvector.WithIndexName(index, true),
+		redisvector.WithEmbedder(e),
+		redisvector.WithTTL(-time.Second),
+	)
+	assert.Equal(t, "invalid options: negative ttl", err.Error())
+
+	vector, err := redisvector.New(ctx,
+		redisvector.WithConnectionURL(redisURL),
+		redisvector.WithIndexName(index, true),
+		redisvector.WithEmbedder(e),
+		redisvector.WithTTL(500*time.Millisecond),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, vector.DropIndex(ctx, index, true))
+	})
+
+	docIDs, err := vector.AddDocuments(ctx, []schema.Document{
+		{PageContent: "Tokyo", Metadata: map[string]any{"population": 9.7, "area": 622}},
+		{PageContent: "Kyoto", Metadata: map[string]any{"population": 1.46, "area": 828}},
+	})
+	require.NoError(t, err)
+	assert.Len(t, docIDs, 2)
+
+	docs, err := vector.MetadataSearch(ctx, 10)
+	require.NoError(t, err)
+	assert.Len(t, docs, 2)
+
+	assert.Eventually(t, func() bool {
+		docs, err := vector.MetadataSearch(ctx, 10)
+		return err == nil && len(docs) == 0
+	}, 5*time.Second, 100*time.Millisecond)
+}
+
 func TestSimilaritySearch(t *testing.T) {
 	t.Parallel()
 
@@ -302,6 +362,23 @@ func TestSimilaritySearch(t *testing.T) {
 	assert.Len(t, docs, 5)
 	assert.Len(t, docs[0].Metadata, 3)
 
+	scored, err := store.SimilaritySearchWithScore(ctx, "Tokyo", 5)
+	require.NoError(t, err)
+	require.Len(t, scored, 5)
+	for i, doc := range scored {
+		assert.Equal(t, docs[i].PageContent, doc.Document.PageContent)
+		assert.InDelta(t, docs[i].Score, doc.Score, 1e-6)
+	}
+
+	vector, err := e.EmbedQuery(ctx, "Tokyo")
+	require.NoError(t, err)
+	byVector, err := store.SimilaritySearchByVector(ctx, vector, 5)
+	require.NoError(t, err)
+	require.Len(t, byVector, 5)
+	for i, doc := range byVector {
+		assert.Equal(t, docs[i].PageContent, doc.PageContent)
+	}
+
 	// search with score threshold
 	docs, err = store.SimilaritySearch(ctx, "Tokyo", 2,
 		vectorstores.WithScoreThreshold(0.5),
@@ -511,3 +588,86 @@ func runOllamaTestContainer(model string) (*tcollama.OllamaContainer, string) {
 	return ollamaContainer, connectionStr
 }
 */
+
+func TestClose(t *testing.T) {
+	t.Parallel()
+
+	redisURL, ollamaURL := getValues(t)
+	_, e := getEmbedding(ollamaModel, ollamaURL)
+
+	ctx := context.Background()
+	vector, err := redisvector.New(ctx,
+		redisvector.WithConnectionURL(redisURL),
+		redisvector.WithIndexName("test_close", true),
+		redisvector.WithEmbedder(e),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, vector.Close())
+	require.NoError(t, vector.Close())
+
+	_, err = vector.AddDocuments(ctx, []schema.Document{{PageContent: "Tokyo"}})
+	require.ErrorIs(t, err, vectorstores.ErrClosed)
+	_, err = vector.SimilaritySearch(ctx, "Tokyo", 1)
+	require.ErrorIs(t, err, vectorstores.ErrClosed)
+	_, err = vector.MetadataSearch(ctx, 1)
+	require.ErrorIs(t, err, vectorstores.ErrClosed)
+}
+
+func TestPing(t *testing.T) {
+	t.Parallel()
+
+	redisURL, ollamaURL := getValues(t)
+	_, e := getEmbedding(ollamaModel, ollamaURL)
+
+	ctx := context.Background()
+	vector, err := redisvector.New(ctx,
+		redisvector.WithConnectionURL(redisURL),
+		redisvector.WithIndexName("test_ping", true),
+		redisvector.WithEmbedder(e),
+	)
+	require.NoError(t, err)
+
+	// The index is only created with the first documents.
+	require.ErrorIs(t, vector.Ping(ctx), redisvector.ErrNotExistedIndex)
+
+	_, err = vector.AddDocuments(ctx, []schema.Document{{PageContent: "Tokyo"}})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, vector.DropIndex(ctx, "test_ping", true))
+	}()
+	require.NoError(t, vector.Ping(ctx))
+}
+
+func TestAddDocumentsContentHashID(t *testing.T) {
+	t.Parallel()
+
+	redisURL, ollamaURL := getValues(t)
+	_, e := getEmbedding(ollamaModel, ollamaURL)
+
+	ctx := context.Background()
+	vector, err := redisvector.New(ctx,
+		redisvector.WithConnectionURL(redisURL),
+		redisvector.WithIndexName("test_content_hash_id", true),
+		redisvector.WithEmbedder(e),
+	)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, vector.DropIndex(ctx, "test_content_hash_id", true))
+	}()
+
+	docs := func() []schema.Document {
+		return []schema.Document{
+			{PageContent: "Tokyo", Metadata: map[string]any{"country": "japan"}},
+			{PageContent: "Tokyo", Metadata: map[string]any{"country": "japan"}},
+		}
+	}
+	ids, err := vector.AddDocuments(ctx, docs(), vectorstores.WithContentHashID("country"))
+	require.NoError(t, err)
+	require.Len(t, ids, 2)
+	assert.Equal(t, ids[0], ids[1])
+
+	again, err := vector.AddDocuments(ctx, docs(), vectorstores.WithContentHashID("country"))
+	require.NoError(t, err)
+	assert.Equal(t, ids, again)
+}
@@ -2,18 +2,40 @@ package vectorstores
 
 import (
 	"context"
+	"errors"
 
 	"github.com/tmc/langchaingo/callbacks"
 	"github.com/tmc/langchaingo/schema"
 )
 
+// ErrClosed is returned by the methods of a vector store called after the
+// store was closed.
+var ErrClosed = errors.New("vector store is closed")
+
 // VectorStore is the interface for saving and querying documents in the
-// form of vector embeddings.
+// form of vector embeddings. The stores holding connections also implement
+// io.Closer, releasing them once the store is no longer needed.
 type VectorStore interface {
 	AddDocuments(ctx context.Context, docs []schema.Document, options ...Option) ([]string, error)
 	SimilaritySearch(ctx context.Context, query string, numDocuments int, options ...Option) ([]schema.Document, error) //nolint:lll
 }
 
+// ScoredDocument is a document found by a similarity search, with its score.
+type ScoredDocument struct {
+	Document schema.Document
+	Score    float32
+}
+
+// ScoredDocuments pairs the documents found by a similarity search with their
+// Score.
+func ScoredDocuments(docs []schema.Document) []ScoredDocument {
+	scored := make([]ScoredDocument, len(docs))
+	for i, doc := range docs {
+		scored[i] = ScoredDocument{Document: doc, Score: doc.Score}
+	}
+	return scored
+}
+
 // Retriever is a retriever for vector stores.
 type Retriever struct {
 	CallbacksHandler callbacks.Handler
@@ -43,7 +65,9 @@ func (r Retriever) GetRelevantDocuments(ctx context.Context, query string) ([]sc
 }
 
 // ToRetriever takes a vector store and returns a retriever using the
-// vector store to retrieve documents.
+// vector store to retrieve documents: GetRelevantDocuments returns the
+// numDocuments documents found by SimilaritySearch with the options, e.g.
+// WithScoreThreshold to drop the documents scoring below a threshold.
 func ToRetriever(vectorStore VectorStore, numDocuments int, options ...Option) Retriever {
 	return Retriever{
 		v:       vectorStore,
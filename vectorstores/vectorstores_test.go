@@ -0,0 +1,218 @@
+package vectorstores_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+	"github.com/tmc/langchaingo/vectorstores/inmemory"
+)
+
+// fakeEmbedder embeds every text as the vector of its first two bytes.
+type fakeEmbedder struct{}
+
+func (e fakeEmbedder) EmbedDocuments(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+func (e fakeEmbedder) EmbedQuery(_ context.Context, text string) ([]float32, error) {
+	return e.embed(text), nil
+}
+
+func (fakeEmbedder) embed(text string) []float32 {
+	return []float32{float32(text[0] - '0'), float32(text[1] - '0')}
+}
+
+func TestRetriever(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := inmemory.New(inmemory.WithEmbedder(fakeEmbedder{}))
+	require.NoError(t, err)
+	_, err = store.AddDocuments(ctx, []schema.Document{
+		{PageContent: "10"},
+		{PageContent: "11"},
+		{PageContent: "01"},
+	})
+	require.NoError(t, err)
+
+	var retriever schema.Retriever = vectorstores.ToRetriever(store, 2)
+	docs, err := retriever.GetRelevantDocuments(ctx, "10")
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.Equal(t, "10", docs[0].PageContent)
+	assert.Equal(t, "11", docs[1].PageContent)
+
+	retriever = vectorstores.ToRetriever(store, 2, vectorstores.WithScoreThreshold(0.9))
+	docs, err = retriever.GetRelevantDocuments(ctx, "10")
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "10", docs[0].PageContent)
+}
+
+func TestTimeoutContext(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := vectorstores.TimeoutContext(context.Background(), vectorstores.Options{})
+	defer cancel()
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Minute)
+	defer cancelParent()
+	parentDeadline, _ := parent.Deadline()
+
+	opts := vectorstores.Options{}
+	vectorstores.WithTimeout(time.Hour)(&opts)
+	ctx, cancel = vectorstores.TimeoutContext(parent, opts)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	assert.Equal(t, parentDeadline, deadline)
+
+	vectorstores.WithTimeout(time.Second)(&opts)
+	ctx, cancel = vectorstores.TimeoutContext(parent, opts)
+	defer cancel()
+	deadline, ok = ctx.Deadline()
+	require.True(t, ok)
+	assert.True(t, deadline.Before(parentDeadline))
+}
+
+// searchFunc is a vector store searching with a function.
+type searchFunc func(ctx context.Context, query string) ([]schema.Document, error)
+
+func (f searchFunc) AddDocuments(context.Context, []schema.Document, ...vectorstores.Option) ([]string, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f searchFunc) SimilaritySearch(ctx context.Context, query string, _ int,
+	_ ...vectorstores.Option,
+) ([]schema.Document, error) {
+	return f(ctx, query)
+}
+
+func TestMultiQuerySearch(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := inmemory.New(inmemory.WithEmbedder(fakeEmbedder{}))
+	require.NoError(t, err)
+	_, err = store.AddDocuments(ctx, []schema.Document{
+		{PageContent: "10"},
+		{PageContent: "11"},
+		{PageContent: "01"},
+		{PageContent: "12"},
+	})
+	require.NoError(t, err)
+
+	docs, err := vectorstores.MultiQuerySearch(ctx, store, []string{"10", "01"}, 3)
+	require.NoError(t, err)
+	require.Len(t, docs, 3)
+	assert.Equal(t, "10", docs[0].PageContent)
+	assert.Equal(t, "01", docs[1].PageContent)
+	// 12 is closer to 01 than 11 is to 10.
+	assert.Equal(t, "12", docs[2].PageContent)
+	assert.InDelta(t, 1, docs[1].Score, 1e-6)
+}
+
+func TestMultiQuerySearchIDs(t *testing.T) {
+	t.Parallel()
+
+	doc := func(id string, score float32) schema.Document {
+		return schema.Document{
+			PageContent: "tokyo",
+			Metadata:    map[string]any{vectorstores.IDMetadataKey: id},
+			Score:       score,
+		}
+	}
+	store := searchFunc(func(_ context.Context, query string) ([]schema.Document, error) {
+		if query == "capital" {
+			return []schema.Document{doc("1", 0.5), doc("2", 0.4)}, nil
+		}
+		return []schema.Document{doc("1", 0.9)}, nil
+	})
+
+	docs, err := vectorstores.MultiQuerySearch(context.Background(), store, []string{"capital", "japan"}, 5)
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.Equal(t, doc("1", 0.9), docs[0])
+	assert.Equal(t, doc("2", 0.4), docs[1])
+}
+
+func TestMultiQuerySearchError(t *testing.T) {
+	t.Parallel()
+
+	errSearch := errors.New("search failed")
+	store := searchFunc(func(ctx context.Context, query string) ([]schema.Document, error) {
+		if query == "bad" {
+			return nil, errSearch
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	_, err := vectorstores.MultiQuerySearch(context.Background(), store, []string{"good", "bad"}, 1)
+	require.ErrorIs(t, err, errSearch)
+}
+
+func TestEvaluateRecall(t *testing.T) {
+	t.Parallel()
+
+	doc := func(id string) schema.Document {
+		return schema.Document{Metadata: map[string]any{vectorstores.IDMetadataKey: id}}
+	}
+	store := searchFunc(func(_ context.Context, query string) ([]schema.Document, error) {
+		if query == "japan" {
+			return []schema.Document{doc("tokyo"), doc("paris"), doc("kyoto")}, nil
+		}
+		return []schema.Document{doc("tokyo")}, nil
+	})
+
+	result, err := vectorstores.EvaluateRecall(context.Background(), store, []vectorstores.EvalCase{
+		{Query: "japan", RelevantIDs: []string{"tokyo", "kyoto", "osaka", "nara"}},
+		{Query: "france", RelevantIDs: []string{"paris"}},
+	}, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0.5, 0}, result.Recalls)
+	assert.InDelta(t, 0.25, result.Recall, 1e-9)
+	assert.Positive(t, result.MeanLatency)
+
+	_, err = vectorstores.EvaluateRecall(context.Background(), store, []vectorstores.EvalCase{{Query: "japan"}}, 3)
+	require.Error(t, err)
+}
+
+// wordCounter counts a token per word.
+type wordCounter struct{}
+
+func (wordCounter) GetNumTokens(text string) int {
+	return len(strings.Fields(text))
+}
+
+func TestPackToTokenBudget(t *testing.T) {
+	t.Parallel()
+
+	docs := []schema.Document{
+		{PageContent: "one two three"},
+		{PageContent: "four five six seven"},
+		{PageContent: "eight"},
+		{PageContent: "nine ten"},
+	}
+
+	packed := vectorstores.PackToTokenBudget(wordCounter{}, docs, 6)
+	assert.Equal(t, []schema.Document{docs[0], docs[2], docs[3]}, packed)
+
+	packed = vectorstores.PackToTokenBudget(wordCounter{}, docs, 6, vectorstores.WithReservedTokens(2))
+	assert.Equal(t, []schema.Document{docs[0], docs[2]}, packed)
+
+	assert.Empty(t, vectorstores.PackToTokenBudget(wordCounter{}, docs, 2, vectorstores.WithReservedTokens(3)))
+}